@@ -0,0 +1,736 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/falcosecurity/falcosidekick/logger"
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// outputSection locates an output in the configuration
+type outputSection struct {
+	// path is the path of the section in types.Configuration, the outputs of a first level section share its settings
+	path string
+	// name is the name of the output in outputs.EnabledOutputs
+	name string
+}
+
+// adminOutputs are the outputs managed with the admin API, by destination
+var adminOutputs = map[string]outputSection{
+	"slack":             {"Slack", "Slack"},
+	"cliq":              {"Cliq", "Cliq"},
+	"rocketchat":        {"Rocketchat", "Rocketchat"},
+	"mattermost":        {"Mattermost", "Mattermost"},
+	"teams":             {"Teams", "Teams"},
+	"datadog":           {"Datadog", "Datadog"},
+	"discord":           {"Discord", "Discord"},
+	"alertmanager":      {"Alertmanager", "AlertManager"},
+	"elasticsearch":     {"Elasticsearch", "Elasticsearch"},
+	"influxdb":          {"Influxdb", "Influxdb"},
+	"loki":              {"Loki", "Loki"},
+	"nats":              {"Nats", "NATS"},
+	"stan":              {"Stan", "STAN"},
+	"awslambda":         {"AWS.Lambda", "AWSLambda"},
+	"awssqs":            {"AWS.SQS", "AWSSQS"},
+	"awssns":            {"AWS.SNS", "AWSSNS"},
+	"awscloudwatchlogs": {"AWS.CloudWatchLogs", "AWSCloudWatchLogs"},
+	"awss3":             {"AWS.S3", "AWSS3"},
+	"awssecuritylake":   {"AWS.SecurityLake", "AWSSecurityLake"},
+	"awskinesis":        {"AWS.Kinesis", "AWSKinesis"},
+	"smtp":              {"SMTP", "SMTP"},
+	"opsgenie":          {"Opsgenie", "Opsgenie"},
+	"webhook":           {"Webhook", "Webhook"},
+	"nodered":           {"NodeRed", "NodeRed"},
+	"cloudevents":       {"CloudEvents", "CloudEvents"},
+	"azureeventhub":     {"Azure.EventHub", "EventHub"},
+	"gcppubsub":         {"GCP.PubSub", "GCPPubSub"},
+	"gcpcloudfunctions": {"GCP.CloudFunctions", "GCPCloudFunctions"},
+	"gcpcloudrun":       {"GCP.CloudRun", "GCPCloudRun"},
+	"gcpstorage":        {"GCP.Storage", "GCPStorage"},
+	"googlechat":        {"Googlechat", "GoogleChat"},
+	"kafka":             {"Kafka", "Kafka"},
+	"kafkarest":         {"KafkaRest", "KafkaRest"},
+	"pagerduty":         {"Pagerduty", "Pagerduty"},
+	"kubeless":          {"Kubeless", "Kubeless"},
+	"openfaas":          {"Openfaas", "OpenFaaS"},
+	"tekton":            {"Tekton", "Tekton"},
+	"rabbitmq":          {"Rabbitmq", "RabbitMQ"},
+	"wavefront":         {"Wavefront", "Wavefront"},
+	"grafana":           {"Grafana", "Grafana"},
+	"grafanaoncall":     {"GrafanaOnCall", "GrafanaOnCall"},
+	"webui":             {"WebUI", "WebUI"},
+	"fission":           {"Fission", outputs.Fission},
+	"policyreport":      {"PolicyReport", "PolicyReport"},
+	"yandexs3":          {"Yandex.S3", "YandexS3"},
+	"yandexdatastreams": {"Yandex.DataStreams", "YandexDataStreams"},
+	"syslog":            {"Syslog", "Syslog"},
+	"mqtt":              {"MQTT", "MQTT"},
+	"zincsearch":        {"Zincsearch", "Zincsearch"},
+	"gotify":            {"Gotify", "Gotify"},
+	"spyderbat":         {"Spyderbat", "Spyderbat"},
+	"timescaledb":       {"TimescaleDB", "TimescaleDB"},
+	"redis":             {"Redis", "Redis"},
+	"telegram":          {"Telegram", "Telegram"},
+	"n8n":               {"N8N", "n8n"},
+	"openobserve":       {"OpenObserve", "OpenObserve"},
+	"dynatrace":         {"Dynatrace", "Dynatrace"},
+	"forward":           {"Forward", "Forward"},
+}
+
+// topLevel returns the first level section of the output, like AWS for AWS.Lambda
+func (s outputSection) topLevel() string {
+	section, _, _ := strings.Cut(s.path, ".")
+	return section
+}
+
+// outputsLock guards the configuration and the clients of the outputs, which are changed by the admin API,
+// forwardEvent reads them with the read lock held.
+var outputsLock sync.RWMutex
+
+// Health of the outputs, from the result of their last delivery
+const (
+	healthOK      = "ok"
+	healthFailing = "failing"
+	healthUnknown = "unknown"
+	healthPaused  = "paused"
+)
+
+// outputStatus is the state of an output set with the admin API, with the results of its deliveries
+type outputStatus struct {
+	paused           bool
+	removed          bool
+	ok               int64
+	errors           int64
+	skipped          int64
+	lastSuccess      time.Time
+	lastError        time.Time
+	lastErrorMessage string
+}
+
+// health returns the health of the output, the last delivery tells whether it's failing
+func (s outputStatus) health() string {
+	switch {
+	case s.paused:
+		return healthPaused
+	case s.lastSuccess.IsZero() && s.lastError.IsZero():
+		return healthUnknown
+	case s.lastError.After(s.lastSuccess):
+		return healthFailing
+	default:
+		return healthOK
+	}
+}
+
+// outputRegistry keeps the statuses of the outputs, by destination
+type outputRegistry struct {
+	statuses map[string]*outputStatus
+	sync.Mutex
+}
+
+var outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+
+// status returns the status of the output, the lock must be held
+func (o *outputRegistry) status(destination string) *outputStatus {
+	s, ok := o.statuses[destination]
+	if !ok {
+		s = new(outputStatus)
+		o.statuses[destination] = s
+	}
+	return s
+}
+
+// allow tells whether the events are sent to the output, the events skipped by a paused or a removed output are
+// counted.
+func (o *outputRegistry) allow(destination string) bool {
+	o.Lock()
+	defer o.Unlock()
+	s := o.status(destination)
+	if s.paused || s.removed {
+		s.skipped++
+		return false
+	}
+	return true
+}
+
+// observe counts the result of a post to the output, and its rates
+func (o *outputRegistry) observe(destination string, err error) {
+	rates.mark(outputRate(destination))
+	if err != nil {
+		rates.mark(outputErrorRate(destination))
+	}
+	o.Lock()
+	defer o.Unlock()
+	s := o.status(destination)
+	if err != nil {
+		s.errors++
+		s.lastError = time.Now().UTC()
+		s.lastErrorMessage = err.Error()
+		return
+	}
+	s.ok++
+	s.lastSuccess = time.Now().UTC()
+}
+
+// update changes the status of the output with f
+func (o *outputRegistry) update(destination string, f func(*outputStatus)) {
+	o.Lock()
+	defer o.Unlock()
+	f(o.status(destination))
+}
+
+// get returns a copy of the status of the output
+func (o *outputRegistry) get(destination string) outputStatus {
+	o.Lock()
+	defer o.Unlock()
+	return *o.status(destination)
+}
+
+// isEnabled tells whether the output is created and not removed, outputsLock must be held
+func isEnabled(destination string) bool {
+	section, ok := adminOutputs[destination]
+	if !ok || outputStatuses.get(destination).removed {
+		return false
+	}
+	for _, i := range outputs.EnabledOutputs {
+		if i == section.name {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionValue returns the section of the configuration at path, like AWS.Lambda
+func sectionValue(c *types.Configuration, path string) reflect.Value {
+	v := reflect.ValueOf(c).Elem()
+	for _, i := range strings.Split(path, ".") {
+		v = v.FieldByName(i)
+	}
+	return v
+}
+
+// redactedValue replaces the secrets in the configurations returned by the admin API
+const redactedValue = "******"
+
+// redactedConfig returns the settings of the section with the secrets redacted, the values built from the settings,
+// like the templates and the parsed lists, are left out.
+func redactedConfig(v reflect.Value) map[string]interface{} {
+	settings := make(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		field, f := v.Field(i), v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if _, parsed := v.Type().FieldByName(strings.TrimSuffix(f.Name, "List")); parsed && strings.HasSuffix(f.Name, "List") {
+			continue
+		}
+		key := strings.ToLower(f.Name)
+		switch field.Kind() {
+		case reflect.Struct:
+			settings[key] = redactedConfig(field)
+		case reflect.String:
+			switch value := field.String(); {
+			case value != "" && secretFieldRegex.MatchString(f.Name) && !isSecretReference(value), configSecrets.contains(value):
+				settings[key] = redactedValue
+			case strings.Contains(value, "://"):
+				// the urls may embed credentials
+				if u, err := url.Parse(value); err == nil {
+					value = u.Redacted()
+				}
+				settings[key] = value
+			default:
+				settings[key] = value
+			}
+		case reflect.Map:
+			if _, ok := field.Interface().(map[types.PriorityType]string); ok {
+				// the priorities are named like in the configuration
+				severities := make(map[string]string, field.Len())
+				for _, k := range field.MapKeys() {
+					severities[k.Interface().(types.PriorityType).String()] = field.MapIndex(k).String()
+				}
+				settings[key] = severities
+				continue
+			}
+			if f.Name != "CustomHeaders" || field.Type().Key().Kind() != reflect.String || field.Type().Elem().Kind() != reflect.String {
+				settings[key] = field.Interface()
+				continue
+			}
+			headers := make(map[string]string, field.Len())
+			for _, k := range field.MapKeys() {
+				headers[k.String()] = field.MapIndex(k).String()
+				if value := field.MapIndex(k).String(); secretHeaderRegex.MatchString(k.String()) && !isSecretReference(value) || configSecrets.contains(value) {
+					headers[k.String()] = redactedValue
+				}
+			}
+			settings[key] = headers
+		case reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan:
+			continue
+		default:
+			settings[key] = field.Interface()
+		}
+	}
+	return settings
+}
+
+// outputInfo describes an output in the responses of the admin API
+type outputInfo struct {
+	Name             string                 `json:"name"`
+	Section          string                 `json:"section"`
+	Paused           bool                   `json:"paused"`
+	MinimumPriority  *string                `json:"minimum_priority,omitempty"`
+	Health           string                 `json:"health"`
+	OK               int64                  `json:"ok"`
+	Errors           int64                  `json:"errors"`
+	Skipped          int64                  `json:"skipped"`
+	LastSuccess      *time.Time             `json:"last_success,omitempty"`
+	LastError        *time.Time             `json:"last_error,omitempty"`
+	LastErrorMessage string                 `json:"last_error_message,omitempty"`
+	Config           map[string]interface{} `json:"config"`
+}
+
+// describeOutput returns the description of the output, outputsLock must be held
+func describeOutput(destination string) outputInfo {
+	section := adminOutputs[destination]
+	status := outputStatuses.get(destination)
+	c := sectionConfig(config, section.topLevel())
+	v := sectionValue(&c, section.path)
+	info := outputInfo{
+		Name:             destination,
+		Section:          strings.ToLower(section.path),
+		Paused:           status.paused,
+		Health:           status.health(),
+		OK:               status.ok,
+		Errors:           status.errors,
+		Skipped:          status.skipped,
+		LastErrorMessage: logger.Redact(status.lastErrorMessage),
+		Config:           redactedConfig(v),
+	}
+	if p := v.FieldByName("MinimumPriority"); p.IsValid() && p.Kind() == reflect.String {
+		priority := p.String()
+		info.MinimumPriority = &priority
+	}
+	if !status.lastSuccess.IsZero() {
+		info.LastSuccess = &status.lastSuccess
+	}
+	if !status.lastError.IsZero() {
+		info.LastError = &status.lastError
+	}
+	return info
+}
+
+// errAdmin is an error of a request to the admin API, with the status of the response
+type errAdmin struct {
+	status  int
+	message string
+}
+
+func (e *errAdmin) Error() string {
+	return e.message
+}
+
+// addOutputs creates the outputs of a configuration fragment, in YAML or in JSON, with the settings of the section
+// of an output, like:
+//
+//	slack:
+//	  webhookurl: https://hooks.slack.com/services/XXX
+//
+// The settings are merged with the current ones of the section, none of the outputs of the section may be enabled.
+// It returns the destinations of the created outputs, outputsLock must be held.
+func addOutputs(fragment []byte) ([]string, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(fragment)); err != nil {
+		return nil, &errAdmin{http.StatusBadRequest, fmt.Sprintf("invalid configuration fragment: %v", err)}
+	}
+	keys := v.AllSettings()
+	if len(keys) != 1 {
+		return nil, &errAdmin{http.StatusBadRequest, "the configuration fragment must have the section of one output"}
+	}
+	var key string
+	for i := range keys {
+		key = i
+	}
+
+	var section string
+	var destinations []string
+	for destination, s := range adminOutputs {
+		if strings.ToLower(s.topLevel()) == key {
+			section = s.topLevel()
+			destinations = append(destinations, destination)
+		}
+	}
+	if section == "" {
+		return nil, &errAdmin{http.StatusBadRequest, fmt.Sprintf("unknown output section '%v'", key)}
+	}
+	for _, i := range destinations {
+		if isEnabled(i) {
+			return nil, &errAdmin{http.StatusConflict, fmt.Sprintf("the output '%v' of the section '%v' is enabled, remove it first", i, key)}
+		}
+	}
+
+	c := sectionConfig(config, section)
+	if err := v.Unmarshal(&c); err != nil {
+		return nil, &errAdmin{http.StatusBadRequest, fmt.Sprintf("invalid configuration fragment: %v", err)}
+	}
+	if err := checkOutputsConfig(&c); err != nil {
+		return nil, &errAdmin{http.StatusBadRequest, err.Error()}
+	}
+
+	added := createOutputs(&c, destinations)
+	if len(added) == 0 {
+		return nil, &errAdmin{http.StatusUnprocessableEntity, fmt.Sprintf("no output of the section '%v' could be created, check the logs", key)}
+	}
+
+	sectionValue(config, section).Set(sectionValue(&c, section))
+	for _, i := range added {
+		outputStatuses.update(i, func(s *outputStatus) { s.paused, s.removed = false, false })
+	}
+	return added, nil
+}
+
+// sectionConfig returns a copy of c where the outputs of the other sections are left out, to create only the ones of
+// the section. The section doesn't share its maps and slices with c, its references have their current values.
+func sectionConfig(c *types.Configuration, section string) types.Configuration {
+	copied := *c
+	for _, i := range adminOutputs {
+		if i.topLevel() != section {
+			f := sectionValue(&copied, i.topLevel())
+			f.Set(reflect.Zero(f.Type()))
+		}
+	}
+	f := sectionValue(&copied, section)
+	f.Set(deepCopy(f))
+	configSecrets.apply(&copied, section)
+	return copied
+}
+
+// createOutputs creates the outputs enabled in c and returns the ones created among destinations, outputsLock must be
+// held
+func createOutputs(c *types.Configuration, destinations []string) []string {
+	enabled := len(outputs.EnabledOutputs)
+	previous := outputClients()
+	initOutputs(c)
+	closeUnusedClients(previous)
+	var created []string
+	for _, name := range outputs.EnabledOutputs[enabled:] {
+		for _, i := range destinations {
+			if adminOutputs[i].name == name {
+				created = append(created, i)
+			}
+		}
+	}
+	sort.Strings(created)
+	return created
+}
+
+// removeOutput stops the posts of the events to the output, outputsLock must be held
+func removeOutput(destination string) {
+	previous := outputClients()
+	outputStatuses.update(destination, func(s *outputStatus) { s.removed = true })
+	name := adminOutputs[destination].name
+	enabled := make([]string, 0, len(outputs.EnabledOutputs))
+	for _, i := range outputs.EnabledOutputs {
+		if i != name {
+			enabled = append(enabled, i)
+		}
+	}
+	outputs.EnabledOutputs = enabled
+	closeUnusedClients(previous)
+}
+
+// outputClients returns the clients of the outputs by destination, the outputs of a section may share their client.
+// outputsLock must be held.
+func outputClients() map[string]*outputs.Client {
+	return map[string]*outputs.Client{
+		"slack":             slackClient,
+		"cliq":              cliqClient,
+		"rocketchat":        rocketchatClient,
+		"mattermost":        mattermostClient,
+		"teams":             teamsClient,
+		"datadog":           datadogClient,
+		"discord":           discordClient,
+		"alertmanager":      alertmanagerClient,
+		"elasticsearch":     elasticsearchClient,
+		"influxdb":          influxdbClient,
+		"loki":              lokiClient,
+		"nats":              natsClient,
+		"stan":              stanClient,
+		"awslambda":         awsClient,
+		"awssqs":            awsClient,
+		"awssns":            awsClient,
+		"awscloudwatchlogs": awsClient,
+		"awss3":             awsClient,
+		"awssecuritylake":   awsClient,
+		"awskinesis":        awsClient,
+		"smtp":              smtpClient,
+		"opsgenie":          opsgenieClient,
+		"webhook":           webhookClient,
+		"nodered":           noderedClient,
+		"cloudevents":       cloudeventsClient,
+		"azureeventhub":     azureClient,
+		"gcppubsub":         gcpClient,
+		"gcpcloudfunctions": gcpClient,
+		"gcpcloudrun":       gcpCloudRunClient,
+		"gcpstorage":        gcpClient,
+		"googlechat":        googleChatClient,
+		"kafka":             kafkaClient,
+		"kafkarest":         kafkaRestClient,
+		"pagerduty":         pagerdutyClient,
+		"kubeless":          kubelessClient,
+		"openfaas":          openfaasClient,
+		"tekton":            tektonClient,
+		"rabbitmq":          rabbitmqClient,
+		"wavefront":         wavefrontClient,
+		"grafana":           grafanaClient,
+		"grafanaoncall":     grafanaOnCallClient,
+		"webui":             webUIClient,
+		"fission":           fissionClient,
+		"policyreport":      policyReportClient,
+		"yandexs3":          yandexClient,
+		"yandexdatastreams": yandexClient,
+		"syslog":            syslogClient,
+		"mqtt":              mqttClient,
+		"zincsearch":        zincsearchClient,
+		"gotify":            gotifyClient,
+		"spyderbat":         spyderbatClient,
+		"timescaledb":       timescaleDBClient,
+		"redis":             redisClient,
+		"telegram":          telegramClient,
+		"n8n":               n8nClient,
+		"openobserve":       openObserveClient,
+		"dynatrace":         dynatraceClient,
+		"forward":           forwardClient,
+	}
+}
+
+// closeUnusedClients closes the clients of previous which aren't used by an enabled output anymore, because their
+// outputs are removed or have got new clients. They're closed in the background, once their posts have ended and
+// their spool is sent.
+// outputsLock must be held.
+func closeUnusedClients(previous map[string]*outputs.Client) {
+	used := make(map[*outputs.Client]bool)
+	for destination, client := range outputClients() {
+		if isEnabled(destination) {
+			used[client] = true
+		}
+	}
+	for _, client := range previous {
+		if client != nil && !used[client] {
+			go func(client *outputs.Client) {
+				// like at the shutdown, the output has shutdowntimeout seconds to send its last events
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout)*time.Second)
+				defer cancel()
+				client.Close(ctx)
+			}(client)
+		}
+	}
+}
+
+// auditLogger writes the changes made with the admin API in the logs, and in a JSON lines file if set
+type auditLogger struct {
+	file *os.File
+	sync.Mutex
+}
+
+// auditRecord is a change made with the admin API
+type auditRecord struct {
+	Time          time.Time `json:"time"`
+	Client        string    `json:"client,omitempty"`
+	RemoteAddress string    `json:"remote_address"`
+	Action        string    `json:"action"`
+	Output        string    `json:"output"`
+	Details       string    `json:"details,omitempty"`
+}
+
+var adminAudit = new(auditLogger)
+
+func newAuditLogger(path string) (*auditLogger, error) {
+	if path == "" {
+		return new(auditLogger), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLogger{file: f}, nil
+}
+
+func (a *auditLogger) write(r *http.Request, action, output, details string) {
+	record := auditRecord{
+		Time:          time.Now().UTC(),
+		Client:        getAuthClientName(r.Context()),
+		RemoteAddress: r.RemoteAddr,
+		Action:        action,
+		Output:        output,
+		Details:       details,
+	}
+	log.Printf("[INFO]  : Admin - Client '%v' (%v) - %v '%v' %v\n", record.Client, record.RemoteAddress, action, output, details)
+	if a.file == nil {
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("[ERROR] : Admin - %v\n", err)
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		log.Printf("[ERROR] : Admin - %v\n", err)
+	}
+}
+
+// adminMaxRequestSize is the maximum size of the bodies of the admin API, the configuration fragments are small
+const adminMaxRequestSize = 1 << 20
+
+// Actions of the audit log
+const (
+	auditAdd                = "add"
+	auditRemove             = "remove"
+	auditPause              = "pause"
+	auditResume             = "resume"
+	auditSetMinimumPriority = "set_minimum_priority"
+)
+
+// outputsHandler serves the admin API of the outputs:
+//   - GET /outputs lists the enabled outputs, with their redacted settings, health and counters
+//   - POST /outputs adds the outputs of the configuration fragment of the body
+//   - GET /outputs/{name} describes an output
+//   - DELETE /outputs/{name} removes an output
+//   - POST /outputs/{name}/pause and POST /outputs/{name}/resume pause and resume an output
+//   - PUT /outputs/{name}/minimumpriority sets the minimum priority of an output, from the body {"priority": "..."}
+func outputsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, adminMaxRequestSize)
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/outputs"), "/")
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			outputsLock.RLock()
+			defer outputsLock.RUnlock()
+			list := make([]outputInfo, 0)
+			for destination := range adminOutputs {
+				if isEnabled(destination) {
+					list = append(list, describeOutput(destination))
+				}
+			}
+			sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+			writeAdminResponse(w, http.StatusOK, list)
+		case http.MethodPost:
+			fragment, err := io.ReadAll(r.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					http.Error(w, fmt.Sprintf("Request body exceeds the maximum size of %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "Please send a valid request body", http.StatusBadRequest)
+				return
+			}
+			outputsLock.Lock()
+			defer outputsLock.Unlock()
+			added, err := addOutputs(fragment)
+			if err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			for _, i := range added {
+				adminAudit.write(r, auditAdd, i, "")
+			}
+			list := make([]outputInfo, 0, len(added))
+			for _, i := range added {
+				list = append(list, describeOutput(i))
+			}
+			writeAdminResponse(w, http.StatusCreated, list)
+		default:
+			http.Error(w, "Please send with GET or POST http method", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	destination, action, _ := strings.Cut(path, "/")
+	outputsLock.Lock()
+	defer outputsLock.Unlock()
+	if !isEnabled(destination) {
+		http.Error(w, "Unknown output", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+	case action == "" && r.Method == http.MethodDelete:
+		removeOutput(destination)
+		adminAudit.write(r, auditRemove, destination, "")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case (action == "pause" || action == "resume") && r.Method == http.MethodPost:
+		paused := action == "pause"
+		outputStatuses.update(destination, func(s *outputStatus) { s.paused = paused })
+		if paused {
+			adminAudit.write(r, auditPause, destination, "")
+		} else {
+			adminAudit.write(r, auditResume, destination, "")
+		}
+	case action == "minimumpriority" && r.Method == http.MethodPut:
+		var body struct {
+			Priority string `json:"priority"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Please send a valid request body", http.StatusBadRequest)
+			return
+		}
+		if checkPriority(body.Priority) == "" {
+			http.Error(w, fmt.Sprintf("Unknown priority '%v'", body.Priority), http.StatusBadRequest)
+			return
+		}
+		p := sectionValue(config, adminOutputs[destination].path).FieldByName("MinimumPriority")
+		if !p.IsValid() || p.Kind() != reflect.String {
+			http.Error(w, "The output has no minimum priority", http.StatusBadRequest)
+			return
+		}
+		previous := p.String()
+		p.SetString(body.Priority)
+		adminAudit.write(r, auditSetMinimumPriority, destination, fmt.Sprintf("from '%v' to '%v'", previous, body.Priority))
+	case action == "" || action == "pause" || action == "resume" || action == "minimumpriority":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	writeAdminResponse(w, http.StatusOK, describeOutput(destination))
+}
+
+func writeAdminResponse(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set(outputs.ContentTypeHeaderKey, "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[ERROR] : Admin - %v\n", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	var adminErr *errAdmin
+	if errors.As(err, &adminErr) {
+		http.Error(w, adminErr.message, adminErr.status)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestOutputsHandler(t *testing.T) {
+	config = &types.Configuration{}
+	outputs.EnabledOutputs = nil
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	auditLog := filepath.Join(t.TempDir(), "audit.jsonl")
+	var err error
+	adminAudit, err = newAuditLogger(auditLog)
+	require.Nil(t, err)
+	defer func() {
+		outputs.EnabledOutputs = nil
+		adminAudit = new(auditLogger)
+	}()
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		outputsHandler(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	fragment := "webhook:\n  address: http://localhost:2801\n  minimumpriority: warning\n  customheaders:\n    Authorization: Bearer my-token\n"
+	w := request(http.MethodPost, "/outputs", fragment)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NotNil(t, webhookClient)
+	require.Equal(t, "warning", config.Webhook.MinimumPriority)
+	require.Equal(t, http.StatusConflict, request(http.MethodPost, "/outputs", fragment).Code)
+	require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/outputs", "unknown:\n  address: x\n").Code)
+	require.Equal(t, http.StatusUnprocessableEntity, request(http.MethodPost, "/outputs", "slack:\n  webhookurl: not-an-url\n").Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, request(http.MethodPost, "/outputs", strings.Repeat("#", adminMaxRequestSize+1)).Code)
+
+	w = request(http.MethodGet, "/outputs", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []outputInfo
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	require.Equal(t, "webhook", list[0].Name)
+	require.Equal(t, healthUnknown, list[0].Health)
+	require.Equal(t, map[string]interface{}{"authorization": redactedValue}, list[0].Config["customheaders"])
+
+	outputStatuses.observe("webhook", errors.New("refused"))
+	w = request(http.MethodPost, "/outputs/webhook/pause", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var info outputInfo
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &info))
+	require.True(t, info.Paused)
+	require.Equal(t, int64(1), info.Errors)
+	require.False(t, outputStatuses.allow("webhook"))
+	require.Equal(t, http.StatusOK, request(http.MethodPost, "/outputs/webhook/resume", "").Code)
+	require.True(t, outputStatuses.allow("webhook"))
+	require.Equal(t, healthFailing, outputStatuses.get("webhook").health())
+
+	require.Equal(t, http.StatusBadRequest, request(http.MethodPut, "/outputs/webhook/minimumpriority", `{"priority": "unknown"}`).Code)
+	require.Equal(t, http.StatusOK, request(http.MethodPut, "/outputs/webhook/minimumpriority", `{"priority": "critical"}`).Code)
+	require.Equal(t, "critical", config.Webhook.MinimumPriority)
+
+	require.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/outputs/webhook", "").Code)
+	require.Equal(t, http.StatusNotFound, request(http.MethodGet, "/outputs/webhook", "").Code)
+	require.False(t, outputStatuses.allow("webhook"))
+	require.Empty(t, outputs.EnabledOutputs)
+
+	// a removed output can be added again
+	require.Equal(t, http.StatusCreated, request(http.MethodPost, "/outputs", fragment).Code)
+	require.True(t, outputStatuses.allow("webhook"))
+
+	data, err := os.ReadFile(auditLog)
+	require.Nil(t, err)
+	var actions []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record auditRecord
+		require.Nil(t, json.Unmarshal([]byte(line), &record))
+		actions = append(actions, record.Action)
+	}
+	require.Equal(t, []string{auditAdd, auditPause, auditResume, auditSetMinimumPriority, auditRemove, auditAdd}, actions)
+}
+
+func TestRemoveOutputClosesClient(t *testing.T) {
+	posts, closeHub := newForwardHub(t)
+	defer closeHub()
+	hub := httptest.NewServer(http.HandlerFunc(forwardHandler))
+	defer hub.Close()
+	config.ShutdownTimeout = 5
+	stats.Forward = new(expvar.Map).Init()
+	promStats.OutputQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_queue_depth"}, []string{"destination"})
+
+	outputsLock.Lock()
+	added, err := addOutputs([]byte("forward:\n  address: " + hub.URL + "\n  batchsize: 100\n  flushinterval: 3600\n  maxspooledbatches: 10\n"))
+	outputsLock.Unlock()
+	require.Nil(t, err)
+	require.Equal(t, []string{"forward"}, added)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Nil(t, forwardClient.ForwardPost(context.Background(), f))
+
+	// the batch of the removed output is sent without waiting for the flush interval
+	outputsLock.Lock()
+	removeOutput("forward")
+	outputsLock.Unlock()
+	require.Eventually(t, func() bool {
+		return len(posts()) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+}
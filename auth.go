@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Permissions which can be granted to a client
+const (
+	permissionIngest string = "ingest"
+	permissionTest   string = "test"
+	permissionAdmin  string = "admin"
+)
+
+// Headers used to sign the bodies with HMAC
+const (
+	HMACTimestampHeaderKey = "X-Falcosidekick-Timestamp"
+	HMACSignatureHeaderKey = "X-Falcosidekick-Signature"
+	hmacSignaturePrefix    = "sha256="
+)
+
+var (
+	// ErrMissingCredentials is returned when the request has neither a token nor a signature
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned when the token or the signature doesn't match any client
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrExpiredSignature is returned when the timestamp of a signature is outside of the allowed skew
+	ErrExpiredSignature = errors.New("expired or invalid signature timestamp")
+	// ErrReplayedSignature is returned when a signature has already been used
+	ErrReplayedSignature = errors.New("replayed signature")
+)
+
+type authClientKey struct{}
+
+// authClient is a client identity, loaded from the configuration
+type authClient struct {
+	name        string
+	token       []byte
+	hmacSecret  []byte
+	permissions map[string]bool
+}
+
+// authenticator checks the credentials of the requests against the known clients
+type authenticator struct {
+	clients []authClient
+	maxSkew time.Duration
+
+	// signatures already seen and their expiration, to block the replays
+	signatures map[string]time.Time
+	lastPurge  time.Time
+	sync.Mutex
+}
+
+func newAuthenticator(config types.AuthConfig) (*authenticator, error) {
+	a := &authenticator{
+		maxSkew:    time.Duration(config.HMACMaxSkew) * time.Second,
+		signatures: make(map[string]time.Time),
+		lastPurge:  time.Now(),
+	}
+	for _, i := range config.Clients {
+		client := authClient{name: i.Name, permissions: make(map[string]bool)}
+		if i.TokenFile != "" {
+			token, err := readSecretFile(i.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("can't read token of client '%v': %w", i.Name, err)
+			}
+			client.token = token
+		}
+		if i.HMACSecretFile != "" {
+			secret, err := readSecretFile(i.HMACSecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("can't read HMAC secret of client '%v': %w", i.Name, err)
+			}
+			client.hmacSecret = secret
+		}
+		for _, j := range i.Permissions {
+			client.permissions[strings.ToLower(j)] = true
+		}
+		a.clients = append(a.clients, client)
+	}
+	return a, nil
+}
+
+func readSecretFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, fmt.Errorf("file '%v' is empty", path)
+	}
+	return b, nil
+}
+
+// isAllowed returns true if the client has the permission, admin is allowed to do everything
+func (c *authClient) isAllowed(permission string) bool {
+	return c.permissions[permission] || c.permissions[permissionAdmin]
+}
+
+// authenticate returns the client matching the bearer token or the HMAC signature of the request
+func (a *authenticator) authenticate(w http.ResponseWriter, r *http.Request) (*authClient, error) {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token := []byte(strings.TrimSpace(strings.TrimPrefix(h, "Bearer ")))
+		for i := range a.clients {
+			if a.clients[i].token != nil && subtle.ConstantTimeCompare(a.clients[i].token, token) == 1 {
+				return &a.clients[i], nil
+			}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if r.Header.Get(HMACSignatureHeaderKey) != "" {
+		return a.verifySignature(w, r)
+	}
+
+	return nil, ErrMissingCredentials
+}
+
+// verifySignature checks the signature of the body, computed with sha256 over "<timestamp>.<body>"
+func (a *authenticator) verifySignature(w http.ResponseWriter, r *http.Request) (*authClient, error) {
+	timestamp := r.Header.Get(HMACTimestampHeaderKey)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrExpiredSignature
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > a.maxSkew || skew < -a.maxSkew {
+		return nil, ErrExpiredSignature
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(HMACSignatureHeaderKey), hmacSignaturePrefix))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var body []byte
+	if r.Body != nil {
+		reader := r.Body
+		if config.MaxRequestSize > 0 {
+			reader = http.MaxBytesReader(w, reader, config.MaxRequestSize)
+		}
+		body, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		// the body is given back to the next handler
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	var client *authClient
+	for i := range a.clients {
+		if a.clients[i].hmacSecret == nil {
+			continue
+		}
+		mac := hmac.New(sha256.New, a.clients[i].hmacSecret)
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		if hmac.Equal(mac.Sum(nil), signature) {
+			client = &a.clients[i]
+			break
+		}
+	}
+	if client == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	now := time.Now()
+	if now.Sub(a.lastPurge) > a.maxSkew {
+		for s, expiration := range a.signatures {
+			if now.After(expiration) {
+				delete(a.signatures, s)
+			}
+		}
+		a.lastPurge = now
+	}
+	key := hex.EncodeToString(signature)
+	if _, ok := a.signatures[key]; ok {
+		return nil, ErrReplayedSignature
+	}
+	a.signatures[key] = time.Unix(ts, 0).Add(a.maxSkew)
+
+	return client, nil
+}
+
+// withAuth protects a handler, only the clients with the permission are allowed, if no client is configured,
+// the handler is returned as is.
+func withAuth(permission string, next http.Handler) http.Handler {
+	if clientAuthenticator == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := clientAuthenticator.authenticate(w, r)
+		if err != nil {
+			countClientMetric("unknown", "unauthorized")
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, fmt.Sprintf("Request body exceeds the maximum size of %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="falcosidekick"`)
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !client.isAllowed(permission) {
+			countClientMetric(client.name, "forbidden")
+			http.Error(w, fmt.Sprintf("Forbidden: client '%v' is not allowed to %v", client.name, permission), http.StatusForbidden)
+			return
+		}
+		countClientMetric(client.name, "authorized")
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authClientKey{}, client.name)))
+	})
+}
+
+// getAuthClientName returns the name of the authenticated client of the request, if any
+func getAuthClientName(ctx context.Context) string {
+	if name, ok := ctx.Value(authClientKey{}).(string); ok {
+		return name
+	}
+	return ""
+}
+
+func countClientMetric(client, status string) {
+	stats.Clients.Add(client+"."+status, 1)
+	promStats.Clients.With(map[string]string{"client": client, "status": status}).Inc()
+	nullClient.CountMetric("inputs.clients", 1, []string{"client:" + client, "status:" + status})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestWithAuth(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	secretFile := filepath.Join(dir, "secret")
+	require.Nil(t, os.WriteFile(tokenFile, []byte("my-token\n"), 0600))
+	require.Nil(t, os.WriteFile(secretFile, []byte("my-secret"), 0600))
+
+	config = &types.Configuration{
+		Auth: types.AuthConfig{
+			HMACMaxSkew: 60,
+			Clients: []types.AuthClientConfig{
+				{Name: "falco", TokenFile: tokenFile, Permissions: []string{"ingest"}},
+				{Name: "hub", HMACSecretFile: secretFile, Permissions: []string{"admin"}},
+			},
+		},
+	}
+	stats = &types.Statistics{Clients: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{Clients: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, []string{"client", "status"})}
+	nullClient = &outputs.Client{Config: config, Stats: stats, PromStats: promStats}
+
+	var err error
+	clientAuthenticator, err = newAuthenticator(config.Auth)
+	require.Nil(t, err)
+	defer func() { clientAuthenticator = nil }()
+
+	var client string
+	h := withAuth(permissionTest, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client = getAuthClientName(r.Context())
+	}))
+
+	sign := func(secret, ts, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(ts + "." + body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	for name, test := range map[string]struct {
+		headers  map[string]string
+		expected int
+	}{
+		"no credentials":      {nil, http.StatusUnauthorized},
+		"bad token":           {map[string]string{"Authorization": "Bearer foo"}, http.StatusUnauthorized},
+		"missing permission":  {map[string]string{"Authorization": "Bearer my-token"}, http.StatusForbidden},
+		"bad signature":       {map[string]string{HMACTimestampHeaderKey: now, HMACSignatureHeaderKey: sign("foo", now, "body")}, http.StatusUnauthorized},
+		"expired signature":   {map[string]string{HMACTimestampHeaderKey: old, HMACSignatureHeaderKey: sign("my-secret", old, "body")}, http.StatusUnauthorized},
+		"signature for admin": {map[string]string{HMACTimestampHeaderKey: now, HMACSignatureHeaderKey: sign("my-secret", now, "body")}, http.StatusOK},
+	} {
+		client = ""
+		r := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("body"))
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		require.Equal(t, test.expected, w.Code, name)
+		if test.expected == http.StatusOK {
+			require.Equal(t, "hub", client, name)
+		}
+	}
+
+	// the same signature can't be used twice
+	r := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("body"))
+	r.Header.Set(HMACTimestampHeaderKey, now)
+	r.Header.Set(HMACSignatureHeaderKey, sign("my-secret", now, "body"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), ErrReplayedSignature.Error())
+}
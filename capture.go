@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/logger"
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// allCaptureOutputs selects all the outputs which can be captured in capture.outputs
+const allCaptureOutputs = "all"
+
+// capturableOutputs are the outputs with requests which can be captured: the outputs sending their events with the
+// HTTP client of the outputs package, and the ones rendering them as an SQL query or a syslog line. The forward
+// output sends compressed batches in the background, they're not captured.
+var capturableOutputs = map[string]bool{
+	"alertmanager":  true,
+	"cliq":          true,
+	"datadog":       true,
+	"discord":       true,
+	"dynatrace":     true,
+	"elasticsearch": true,
+	"gcpcloudrun":   true,
+	"googlechat":    true,
+	"gotify":        true,
+	"grafana":       true,
+	"grafanaoncall": true,
+	"influxdb":      true,
+	"kafkarest":     true,
+	"loki":          true,
+	"mattermost":    true,
+	"n8n":           true,
+	"nodered":       true,
+	"openobserve":   true,
+	"opsgenie":      true,
+	"rocketchat":    true,
+	"slack":         true,
+	"spyderbat":     true,
+	"syslog":        true,
+	"teams":         true,
+	"tekton":        true,
+	"telegram":      true,
+	"timescaledb":   true,
+	"webhook":       true,
+	"webui":         true,
+	"zincsearch":    true,
+}
+
+// capturedRequest is a captured request of an output, with its secrets redacted. The body is kept as is if it's
+// JSON.
+type capturedRequest struct {
+	Time    time.Time         `json:"time"`
+	Output  string            `json:"output"`
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body"`
+}
+
+// newCapturedRequest redacts the secrets of the request: the ones of the configuration, the headers with a secret
+// name and the credentials of the url.
+func newCapturedRequest(r outputs.CapturedRequest) capturedRequest {
+	req := capturedRequest{Time: time.Now().UTC(), Output: strings.ToLower(r.Output), Method: r.Method, URL: logger.Redact(r.URL)}
+	if u, err := url.Parse(req.URL); err == nil {
+		req.URL = u.Redacted()
+	}
+	if len(r.Headers) != 0 {
+		req.Headers = make(map[string]string, len(r.Headers))
+		for k, v := range r.Headers {
+			if secretHeaderRegex.MatchString(k) {
+				v = redactedValue
+			}
+			req.Headers[k] = logger.Redact(v)
+		}
+	}
+	body := []byte(logger.Redact(string(r.Body)))
+	if json.Valid(body) {
+		req.Body = json.RawMessage(strings.TrimSpace(string(body)))
+	} else {
+		req.Body = string(body)
+	}
+	return req
+}
+
+// capturer records the requests of the captured outputs instead of sending them. The last requests are kept for
+// the /captures endpoint, they're also appended to a JSON lines file if set.
+type capturer struct {
+	// outputs are the captured outputs, all the ones which can be captured if empty
+	outputs     map[string]bool
+	maxRequests int
+	requests    []capturedRequest
+	file        *os.File
+	sync.Mutex
+}
+
+var requestCapturer *capturer
+
+func newCapturer(config types.CaptureConfig) (*capturer, error) {
+	c := &capturer{outputs: make(map[string]bool), maxRequests: config.MaxRequests}
+	for _, i := range config.OutputsList {
+		if i == allCaptureOutputs {
+			c.outputs = make(map[string]bool)
+			break
+		}
+		c.outputs[i] = true
+	}
+	if config.File != "" {
+		var err error
+		if c.file, err = os.OpenFile(config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// captures tells whether the requests of the output are captured
+func (c *capturer) captures(output string) bool {
+	return capturableOutputs[output] && (len(c.outputs) == 0 || c.outputs[output])
+}
+
+// capture records the request if its output is captured, it's the hook of the outputs package
+func (c *capturer) capture(r outputs.CapturedRequest) bool {
+	if !c.captures(strings.ToLower(r.Output)) {
+		return false
+	}
+	req := newCapturedRequest(r)
+
+	c.Lock()
+	defer c.Unlock()
+	if c.maxRequests > 0 {
+		c.requests = append(c.requests, req)
+		if len(c.requests) > c.maxRequests {
+			c.requests = c.requests[1:]
+		}
+	}
+	if c.file != nil {
+		line, err := json.Marshal(req)
+		if err == nil {
+			_, err = c.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			log.Printf("[ERROR] : Capture - %v\n", err)
+		}
+	}
+	return true
+}
+
+// search returns the last captured requests of the output, or of all the outputs if empty, the last captured first
+func (c *capturer) search(output string, limit int) []capturedRequest {
+	c.Lock()
+	defer c.Unlock()
+	requests := make([]capturedRequest, 0)
+	for i := len(c.requests) - 1; i >= 0 && len(requests) < limit; i-- {
+		if output == "" || c.requests[i].Output == output {
+			requests = append(requests, c.requests[i])
+		}
+	}
+	return requests
+}
+
+// capturesHandler answers GET /captures with the last captured requests, filtered by the output and limit of the
+// query.
+func capturesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Please send with GET http method", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			http.Error(w, "Please send a valid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set(outputs.ContentTypeHeaderKey, "application/json")
+	if err := json.NewEncoder(w).Encode(requestCapturer.search(strings.ToLower(query.Get("output")), limit)); err != nil {
+		log.Printf("[ERROR] : Capture - %v\n", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// otherLabelValue replaces the values of the labels of falco_events which aren't allowed or over the limit
+const otherLabelValue = "__other__"
+
+// Reasons of the label values replaced by __other__
+const (
+	limitReason  = "limit"
+	deniedReason = "denied"
+)
+
+// labelsLimiter bounds the number of series of falco_events, the values of a label over the maximum number of
+// distinct values, not in its allowed list or in its denied list are replaced by __other__.
+type labelsLimiter struct {
+	maxValues    int
+	allowed      map[string]map[string]bool
+	denied       map[string]map[string]bool
+	dropPodNames bool
+	// values are the distinct values seen for each label, up to maxValues
+	values map[string]map[string]bool
+	sync.Mutex
+}
+
+func newLabelsLimiter(config *types.Configuration) *labelsLimiter {
+	return &labelsLimiter{
+		maxValues:    config.Prometheus.MaxLabelValues,
+		allowed:      toSets(config.Prometheus.AllowedLabelValuesList),
+		denied:       toSets(config.Prometheus.DeniedLabelValuesList),
+		dropPodNames: config.Prometheus.DropPodNames,
+		values:       make(map[string]map[string]bool),
+	}
+}
+
+func toSets(lists map[string][]string) map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(lists))
+	for label, values := range lists {
+		sets[label] = make(map[string]bool, len(values))
+		for _, i := range values {
+			sets[label][i] = true
+		}
+	}
+	return sets
+}
+
+// limit replaces the values of the labels by __other__ when needed, the replacements are counted by the
+// falcosidekick_falco_events_collapsed_labels metric. A nil limiter keeps all the values.
+func (l *labelsLimiter) limit(labels map[string]string) {
+	if l == nil {
+		return
+	}
+	if l.dropPodNames {
+		labels["k8s_pod_name"] = ""
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	for label, value := range labels {
+		// the empty values don't create any new series
+		if value == "" {
+			continue
+		}
+		if reason := l.check(label, value); reason != "" {
+			labels[label] = otherLabelValue
+			promStats.FalcoCollapsedLabels.With(map[string]string{"label": label, "reason": reason}).Inc()
+		}
+	}
+}
+
+// check returns the reason to replace the value of the label, or an empty string to keep it, the lock must be held.
+func (l *labelsLimiter) check(label, value string) string {
+	if allowed, ok := l.allowed[label]; ok && !allowed[value] {
+		return deniedReason
+	}
+	if l.denied[label][value] {
+		return deniedReason
+	}
+	if l.maxValues == 0 {
+		return ""
+	}
+	values, ok := l.values[label]
+	if !ok {
+		values = make(map[string]bool)
+		l.values[label] = values
+	}
+	if values[value] {
+		return ""
+	}
+	if len(values) >= l.maxValues {
+		return limitReason
+	}
+	values[value] = true
+	return ""
+}
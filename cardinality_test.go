@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestLabelsLimiter(t *testing.T) {
+	promStats = &types.PromStatistics{
+		FalcoCollapsedLabels: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_collapsed"}, []string{"label", "reason"}),
+	}
+	c := &types.Configuration{}
+	c.Prometheus.MaxLabelValues = 2
+	c.Prometheus.AllowedLabelValuesList = map[string][]string{"priority": {"Critical"}}
+	c.Prometheus.DeniedLabelValuesList = map[string][]string{"rule": {"Noisy rule"}}
+	c.Prometheus.DropPodNames = true
+	l := newLabelsLimiter(c)
+
+	for _, i := range []struct {
+		in, out map[string]string
+	}{
+		{
+			in:  map[string]string{"rule": "A", "priority": "Critical", "k8s_pod_name": "pod-1", "hostname": ""},
+			out: map[string]string{"rule": "A", "priority": "Critical", "k8s_pod_name": "", "hostname": ""},
+		},
+		{
+			in:  map[string]string{"rule": "B", "priority": "Warning", "k8s_pod_name": "pod-2", "hostname": ""},
+			out: map[string]string{"rule": "B", "priority": otherLabelValue, "k8s_pod_name": "", "hostname": ""},
+		},
+		{
+			// the third distinct value of rule is over the limit, the known ones are kept
+			in:  map[string]string{"rule": "C", "priority": "Critical", "k8s_pod_name": "pod-3", "hostname": ""},
+			out: map[string]string{"rule": otherLabelValue, "priority": "Critical", "k8s_pod_name": "", "hostname": ""},
+		},
+		{
+			in:  map[string]string{"rule": "A", "priority": "Critical", "k8s_pod_name": "", "hostname": ""},
+			out: map[string]string{"rule": "A", "priority": "Critical", "k8s_pod_name": "", "hostname": ""},
+		},
+		{
+			in:  map[string]string{"rule": "Noisy rule", "priority": "Critical", "k8s_pod_name": "", "hostname": ""},
+			out: map[string]string{"rule": otherLabelValue, "priority": "Critical", "k8s_pod_name": "", "hostname": ""},
+		},
+	} {
+		l.limit(i.in)
+		require.Equal(t, i.out, i.in)
+	}
+
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.FalcoCollapsedLabels.WithLabelValues("rule", limitReason)))
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.FalcoCollapsedLabels.WithLabelValues("rule", deniedReason)))
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.FalcoCollapsedLabels.WithLabelValues("priority", deniedReason)))
+
+	var nilLimiter *labelsLimiter
+	labels := map[string]string{"k8s_pod_name": "pod-1"}
+	nilLimiter.limit(labels)
+	require.Equal(t, "pod-1", labels["k8s_pod_name"])
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// configDirExtensions are the extensions of the files read in the config directory
+var configDirExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// configDirFiles returns the config files of dir, sorted by name, the hidden files are left out, like the ones of the
+// mounts of the Kubernetes configmaps.
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, i := range entries {
+		name := i.Name()
+		if strings.HasPrefix(name, ".") || !configDirExtensions[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+		// the symlinks are followed
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readSettings reads the settings of a config file, without the defaults and the env vars
+func readSettings(file string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return v.AllSettings(), nil
+}
+
+// settingSources are the files which have set the settings, by key, like slack.webhookurl
+type settingSources struct {
+	sources map[string]string
+	// overrides are the settings set by a file then by another one
+	overrides []string
+}
+
+var configSources = &settingSources{sources: make(map[string]string)}
+
+// get returns the source of a setting: the env var, the file which has set it, or "" for the defaults. The indexes
+// of the lists are ignored, like in auth.clients[0].name.
+func (s *settingSources) get(key string) string {
+	key = strings.ToLower(key)
+	if i := strings.Index(key, "["); i != -1 {
+		key = key[:i]
+	}
+	env := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if _, ok := os.LookupEnv(env); ok {
+		return "env " + env
+	}
+	return s.sources[key]
+}
+
+// mergeSettings merges the settings of src in dst and records their source. The maps are merged key by key, the
+// lists are merged too, with the items of src appended, except the ones with the same name as an item of dst, which
+// they replace. The other values of src replace the ones of dst.
+func (s *settingSources) mergeSettings(dst, src map[string]interface{}, prefix, source string) {
+	keys := make([]string, 0, len(src))
+	for i := range src {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key, value := prefix+k, src[k]
+		switch v := value.(type) {
+		case map[string]interface{}:
+			m, ok := dst[k].(map[string]interface{})
+			if !ok {
+				m = make(map[string]interface{})
+				dst[k] = m
+			}
+			if len(v) == 0 {
+				s.set(key, source)
+			}
+			s.mergeSettings(m, v, key+".", source)
+		case []interface{}:
+			if list, ok := dst[k].([]interface{}); ok {
+				dst[k] = mergeLists(list, v)
+				if previous := s.sources[key]; previous != "" && previous != source {
+					s.sources[key] = previous + ", " + source
+					continue
+				}
+			} else {
+				dst[k] = v
+			}
+			s.set(key, source)
+		default:
+			dst[k] = value
+			s.set(key, source)
+		}
+	}
+}
+
+// annotate adds the sources of the settings to the problems which start with their key
+func (s *settingSources) annotate(problems []string) []string {
+	annotated := make([]string, 0, len(problems))
+	for _, i := range problems {
+		if key, _, found := strings.Cut(i, " - "); found {
+			if source := s.get(key); source != "" {
+				i += " (set by " + source + ")"
+			}
+		}
+		annotated = append(annotated, i)
+	}
+	return annotated
+}
+
+func (s *settingSources) set(key, source string) {
+	if previous, ok := s.sources[key]; ok && previous != source {
+		s.overrides = append(s.overrides, fmt.Sprintf("%v - Set in %v, overridden in %v", key, previous, source))
+	}
+	s.sources[key] = source
+}
+
+// mergeLists appends the items of src to dst, the items with a name replace the ones of dst with the same name and
+// the other items already in dst are skipped
+func mergeLists(dst, src []interface{}) []interface{} {
+	merged := append(make([]interface{}, 0, len(dst)+len(src)), dst...)
+	for _, i := range src {
+		index := -1
+		for j, k := range merged {
+			if sameListItem(i, k) {
+				index = j
+				break
+			}
+		}
+		if index == -1 {
+			merged = append(merged, i)
+			continue
+		}
+		merged[index] = i
+	}
+	return merged
+}
+
+func sameListItem(a, b interface{}) bool {
+	ma, okA := a.(map[string]interface{})
+	mb, okB := b.(map[string]interface{})
+	if okA != okB {
+		return false
+	}
+	if !okA {
+		return fmt.Sprint(a) == fmt.Sprint(b)
+	}
+	name := itemName(ma)
+	return name != "" && name == itemName(mb)
+}
+
+func itemName(m map[string]interface{}) string {
+	for k, v := range m {
+		if strings.EqualFold(k, "name") {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// readConfigFiles merges the settings of the config file and of the files of the config directory, in this order,
+// and records their sources
+func readConfigFiles(configFile string, files []string, sources *settingSources) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	if configFile != "" {
+		files = append([]string{configFile}, files...)
+	}
+	for _, i := range files {
+		settings, err := readSettings(i)
+		if err != nil {
+			return nil, fmt.Errorf("can't read '%v': %v", i, err)
+		}
+		sources.mergeSettings(merged, settings, "", i)
+	}
+	return merged, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestConfigDirFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, i := range []string{"20-slack.yaml", "10-base.yml", "30-auth.json", "README.md", ".hidden.yaml"} {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, i), nil, 0600))
+	}
+	require.Nil(t, os.Mkdir(filepath.Join(dir, "..data"), 0700))
+	require.Nil(t, os.Symlink(filepath.Join(dir, "10-base.yml"), filepath.Join(dir, "40-link.yaml")))
+
+	files, err := configDirFiles(dir)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "10-base.yml"),
+		filepath.Join(dir, "20-slack.yaml"),
+		filepath.Join(dir, "30-auth.json"),
+		filepath.Join(dir, "40-link.yaml"),
+	}, files)
+}
+
+func TestReadConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	require.Nil(t, os.WriteFile(base, []byte("slack:\n  minimumpriority: warning\ntlsserver:\n  notlspaths: [/ping]\nauth:\n  clients:\n    - name: falco\n      permissions: [ingest]\n"), 0600))
+	slack := filepath.Join(dir, "10-slack.yaml")
+	require.Nil(t, os.WriteFile(slack, []byte("slack:\n  webhookurl: https://hooks.slack.com/services/XXX\n  minimumpriority: critical\n"), 0600))
+	auth := filepath.Join(dir, "20-auth.json")
+	require.Nil(t, os.WriteFile(auth, []byte(`{"tlsserver": {"notlspaths": ["/ping", "/healthz"]}, "auth": {"clients": [{"name": "ci", "permissions": ["test"]}, {"name": "falco", "permissions": ["ingest", "test"]}]}}`), 0600))
+
+	sources := &settingSources{sources: make(map[string]string)}
+	settings, err := readConfigFiles(base, []string{slack, auth}, sources)
+	require.Nil(t, err)
+	require.Equal(t, map[string]interface{}{"webhookurl": "https://hooks.slack.com/services/XXX", "minimumpriority": "critical"}, settings["slack"])
+	require.Equal(t, []interface{}{"/ping", "/healthz"}, settings["tlsserver"].(map[string]interface{})["notlspaths"])
+	// the items with the same name are replaced, the other ones are appended
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"name": "falco", "permissions": []interface{}{"ingest", "test"}},
+		map[string]interface{}{"name": "ci", "permissions": []interface{}{"test"}},
+	}, settings["auth"].(map[string]interface{})["clients"])
+
+	require.Equal(t, slack, sources.get("slack.minimumpriority"))
+	require.Equal(t, base+", "+auth, sources.get("tlsserver.notlspaths"))
+	require.Equal(t, base+", "+auth, sources.get("auth.clients[1].name"))
+	require.Equal(t, "", sources.get("slack.username"))
+	require.Equal(t, []string{"slack.minimumpriority - Set in " + base + ", overridden in " + slack}, sources.overrides)
+
+	t.Setenv("SLACK_USERNAME", "falco")
+	require.Equal(t, "env SLACK_USERNAME", sources.get("slack.username"))
+	require.Equal(t, []string{"slack.minimumpriority - Unknown priority 'warn' (set by " + slack + ")", "Bad listening port number"},
+		sources.annotate([]string{"slack.minimumpriority - Unknown priority 'warn'", "Bad listening port number"}))
+
+	_, err = readConfigFiles(filepath.Join(dir, "missing.yaml"), nil, sources)
+	require.NotNil(t, err)
+}
+
+func TestDumpConfigSources(t *testing.T) {
+	c := &types.Configuration{}
+	c.Slack.Username = "falco"
+	sources := func(key string) string {
+		if key == "slack.username" {
+			return "conf.d/10-slack.yaml"
+		}
+		return ""
+	}
+
+	var b bytes.Buffer
+	require.Nil(t, dumpConfig(&b, c, sources))
+	require.Contains(t, b.String(), "  username: falco # conf.d/10-slack.yaml\n")
+	require.Contains(t, b.String(), "  webhookurl: \"\"\n")
+}
@@ -2,58 +2,168 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
+	"github.com/falcosecurity/falcosidekick/logger"
+	"github.com/falcosecurity/falcosidekick/outputs"
 	"github.com/falcosecurity/falcosidekick/types"
 	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const testRule string = "Test rule"
 
+// ErrUnsupportedContentEncoding is returned when the request body is compressed with an unknown algorithm
+var ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
+
 // mainHandler is Falco Sidekick main handler (default).
 func mainHandler(w http.ResponseWriter, r *http.Request) {
 	stats.Requests.Add("total", 1)
 	nullClient.CountMetric("total", 1, []string{})
 
 	if r.Body == nil {
-		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
-		stats.Requests.Add("rejected", 1)
-		promStats.Inputs.With(map[string]string{"source": "requests", "status": "rejected"}).Inc()
-		nullClient.CountMetric("inputs.requests.rejected", 1, []string{"error:nobody"})
-
+		rejectRequest(w, r, "Please send a valid request body", http.StatusBadRequest, "nobody")
 		return
 	}
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Please send with post http method", http.StatusBadRequest)
-		stats.Requests.Add("rejected", 1)
-		promStats.Inputs.With(map[string]string{"source": "requests", "status": "rejected"}).Inc()
-		nullClient.CountMetric("inputs.requests.rejected", 1, []string{"error:nobody"})
-
+		rejectRequest(w, r, "Please send with post http method", http.StatusBadRequest, "nobody")
 		return
 	}
 
-	falcopayload, err := newFalcoPayload(r.Body)
-	if err != nil || !falcopayload.Check() {
-		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
-		stats.Requests.Add("rejected", 1)
-		promStats.Inputs.With(map[string]string{"source": "requests", "status": "rejected"}).Inc()
-		nullClient.CountMetric("inputs.requests.rejected", 1, []string{"error:invalidjson"})
+	body, err := newRequestBodyReader(w, r)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedContentEncoding) {
+			rejectRequest(w, r, fmt.Sprintf("Please send a body encoded with gzip, deflate or zstd (%v)", err), http.StatusUnsupportedMediaType, "unsupportedencoding")
+			return
+		}
+		rejectRequest(w, r, fmt.Sprintf("Please send a valid compressed request body (%v)", err), http.StatusBadRequest, "invalidencoding")
+		return
+	}
+	defer body.Close()
 
+	falcopayload, err := newFalcoPayload(r.Context(), body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			rejectRequest(w, r, fmt.Sprintf("Request body exceeds the maximum size of %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge, "toolarge")
+			return
+		}
+		if errors.Is(err, ErrMissingFields) {
+			rejectRequest(w, r, "Please send a valid request body", http.StatusBadRequest, "invalidjson")
+			return
+		}
+		rejectRequest(w, r, "Please send a valid request body: "+describeDecodingError(err), http.StatusBadRequest, "invalidjson")
 		return
 	}
 
 	nullClient.CountMetric("inputs.requests.accepted", 1, []string{})
 	stats.Requests.Add("accepted", 1)
 	promStats.Inputs.With(map[string]string{"source": "requests", "status": "accepted"}).Inc()
-	forwardEvent(falcopayload)
+	if client := getAuthClientName(r.Context()); client != "" {
+		countClientMetric(client, "accepted")
+	}
+	forwardEvent(detachContext(r.Context()), falcopayload)
+}
+
+// rejectRequest answers with an error and counts the request as rejected, reason is used as error tag.
+func rejectRequest(w http.ResponseWriter, r *http.Request, message string, code int, reason string) {
+	rejectInputRequest(w, r, "requests", stats.Requests, message, code, reason)
+}
+
+// rejectInputRequest answers with an error to a request received by an http input, and counts it as rejected.
+func rejectInputRequest(w http.ResponseWriter, r *http.Request, input string, counter *expvar.Map, message string, code int, reason string) {
+	http.Error(w, message, code)
+	if client := getAuthClientName(r.Context()); client != "" {
+		countClientMetric(client, "rejected")
+	}
+	counter.Add("rejected", 1)
+	promStats.Inputs.With(map[string]string{"source": input, "status": "rejected"}).Inc()
+	nullClient.CountMetric("inputs."+input+".rejected", 1, []string{"error:" + reason})
+}
+
+// newRequestBodyReader returns a reader of the request body, decoded according to its Content-Encoding
+// header and limited to MaxRequestSize bytes.
+func newRequestBodyReader(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	raw := r.Body
+	if config.MaxRequestSize > 0 {
+		raw = http.MaxBytesReader(w, raw, config.MaxRequestSize)
+	}
+
+	var body io.ReadCloser
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return raw, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(raw)
+		if err != nil {
+			return nil, err
+		}
+		body = zr
+	case "deflate":
+		zr, err := zlib.NewReader(raw)
+		if err != nil {
+			return nil, err
+		}
+		body = zr
+	case "zstd":
+		opts := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+		if config.MaxRequestSize > 0 {
+			opts = append(opts, zstd.WithDecoderMaxMemory(uint64(config.MaxRequestSize)))
+		}
+		zr, err := zstd.NewReader(raw, opts...)
+		if err != nil {
+			return nil, err
+		}
+		body = zr.IOReadCloser()
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedContentEncoding, encoding)
+	}
+
+	// the limit also applies once decompressed, to protect against compression bombs
+	if config.MaxRequestSize > 0 {
+		body = http.MaxBytesReader(w, body, config.MaxRequestSize)
+	}
+	return body, nil
+}
+
+// describeDecodingError returns a human readable reason of a failed decoding of a Falco payload.
+func describeDecodingError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "empty body"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "unexpected end of JSON input"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at offset %v: %v", syntaxErr.Offset, syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return fmt.Sprintf("field '%v' must be of type %v, got %v", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return fmt.Sprintf("body must be a JSON object, got %v", typeErr.Value)
+	default:
+		return strings.TrimPrefix(err.Error(), "json: ")
+	}
 }
 
 // pingHandler is a simple handler to test if daemon is UP.
@@ -69,23 +179,89 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status": "ok"}`))
 }
 
-// testHandler sends a test event to all enabled outputs.
-func testHandler(w http.ResponseWriter, r *http.Request) {
-	r.Body = io.NopCloser(bytes.NewReader([]byte(`{"output":"This is a test from falcosidekick","priority":"Debug","hostname": "falcosidekick", "rule":"Test rule", "time":"` + time.Now().UTC().Format(time.RFC3339) + `","output_fields": {"proc.name":"falcosidekick","user.name":"falcosidekick"}, "tags":["test","example"]}`)))
-	mainHandler(w, r)
+// newFalcoPayload decodes, checks and enriches a Falco event, each stage has its own span. The event gets a new
+// UUID, the one it may carry isn't trusted, only the CloudEvents and the forward inputs keep theirs.
+func newFalcoPayload(ctx context.Context, payload io.Reader) (types.FalcoPayload, error) {
+	span := startStage(ctx, "decode")
+	falcopayload, err := decodeFalcoPayload(payload)
+	if err == nil && !falcopayload.Check() {
+		err = ErrMissingFields
+	}
+	endSpan(span, err)
+	if err != nil {
+		return types.FalcoPayload{}, err
+	}
+
+	falcopayload.UUID = ""
+	span = startStage(ctx, "enrich")
+	falcopayload = processFalcoPayload(falcopayload)
+	span.SetAttributes(eventAttributes(falcopayload)...)
+	span.End()
+	return falcopayload, nil
 }
 
-func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
+// decodeFalcoPayload decodes a Falco event. The unknown fields, like the ones added by newer Falco versions, are
+// ignored and logged in debug mode, unless the strict decoding refuses them.
+func decodeFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 	var falcopayload types.FalcoPayload
 
+	if config.Debug && !config.StrictDecoding {
+		data, err := io.ReadAll(payload)
+		if err != nil {
+			return types.FalcoPayload{}, err
+		}
+		if fields := unknownFields(data); len(fields) != 0 {
+			log.Printf("[DEBUG] : Unknown fields of the event ignored: %v\n", strings.Join(fields, ", "))
+		}
+		payload = bytes.NewReader(data)
+	}
+
 	d := json.NewDecoder(payload)
 	d.UseNumber()
+	if config.StrictDecoding {
+		d.DisallowUnknownFields()
+	}
 
-	err := d.Decode(&falcopayload)
-	if err != nil {
+	if err := d.Decode(&falcopayload); err != nil {
 		return types.FalcoPayload{}, err
 	}
 
+	return falcopayload, nil
+}
+
+// falcoPayloadFields are the lowercased JSON names of the fields of a Falco event, they're matched without case
+var falcoPayloadFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(types.FalcoPayload{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		fields[strings.ToLower(name)] = true
+	}
+	return fields
+}()
+
+// unknownFields returns the sorted fields of the JSON object which aren't fields of a Falco event
+func unknownFields(data []byte) []string {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	var fields []string
+	for i := range object {
+		if !falcoPayloadFields[strings.ToLower(i)] {
+			fields = append(fields, i)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// processFalcoPayload adds the custom and templated fields to a checked Falco event and counts it. A UUID is
+// generated if the event doesn't have one yet.
+func processFalcoPayload(falcopayload types.FalcoPayload) types.FalcoPayload {
 	if len(config.Customfields) > 0 {
 		if falcopayload.OutputFields == nil {
 			falcopayload.OutputFields = make(map[string]interface{})
@@ -99,7 +275,9 @@ func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 		falcopayload.Source = "syscalls"
 	}
 
-	falcopayload.UUID = uuid.New().String()
+	if falcopayload.UUID == "" {
+		falcopayload.UUID = uuid.New().String()
+	}
 
 	var kn, kp string
 	for i, j := range falcopayload.OutputFields {
@@ -131,12 +309,11 @@ func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 		}
 	}
 
+	rates.mark(eventsRate)
 	nullClient.CountMetric("falco.accepted", 1, []string{"priority:" + falcopayload.Priority.String()})
 	stats.Falco.Add(strings.ToLower(falcopayload.Priority.String()), 1)
-	promLabels := map[string]string{"rule": falcopayload.Rule, "priority": falcopayload.Priority.String(), "k8s_ns_name": kn, "k8s_pod_name": kp}
-	if falcopayload.Hostname != "" {
-		promLabels["hostname"] = falcopayload.Hostname
-	}
+	// the events without hostname, like the ones of older Falco versions, get an empty label
+	promLabels := map[string]string{"rule": falcopayload.Rule, "priority": falcopayload.Priority.String(), "k8s_ns_name": kn, "k8s_pod_name": kp, "hostname": falcopayload.Hostname}
 
 	for key, value := range config.Customfields {
 		if regPromLabels.MatchString(key) {
@@ -156,6 +333,7 @@ func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 			}
 		}
 	}
+	falcoLabelsLimiter.limit(promLabels)
 	promStats.Falco.With(promLabels).Inc()
 
 	if config.BracketReplacer != "" {
@@ -172,234 +350,366 @@ func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 		log.Printf("[DEBUG] : Falco's payload : %v\n", string(body))
 	}
 
-	return falcopayload, nil
+	return falcopayload
+}
+
+// eventDelivery is the delivery of an event to the outputs, with the errors of the failed posts
+type eventDelivery struct {
+	falcopayload types.FalcoPayload
+	wg           sync.WaitGroup
+	errs         map[string]error
+	sync.Mutex
+}
+
+// fail records the error of the post to the output
+func (d *eventDelivery) fail(destination string, err error) {
+	d.Lock()
+	defer d.Unlock()
+	if d.errs == nil {
+		d.errs = make(map[string]error)
+	}
+	d.errs[destination] = err
+}
+
+// Wait returns once every output has handled the event, with the errors of the outputs which failed to send it
+func (d *eventDelivery) Wait() error {
+	d.wg.Wait()
+	d.Lock()
+	defer d.Unlock()
+	return joinOutputErrors(d.errs)
+}
+
+// dropPermanentErrors forgets the outputs which failed with an error a new attempt can't fix, like a rejected
+// event, they're not retried. It returns their errors, it must be called once Wait has returned.
+func (d *eventDelivery) dropPermanentErrors() error {
+	d.Lock()
+	defer d.Unlock()
+	permanent := make(map[string]error)
+	for i, err := range d.errs {
+		if !isRetryableError(err) {
+			permanent[i] = err
+			delete(d.errs, i)
+		}
+	}
+	return joinOutputErrors(permanent)
+}
+
+// joinOutputErrors joins the errors of the outputs, sorted by output and prefixed with it
+func joinOutputErrors(outputErrs map[string]error) error {
+	destinations := make([]string, 0, len(outputErrs))
+	for i := range outputErrs {
+		destinations = append(destinations, i)
+	}
+	sort.Strings(destinations)
+	errs := make([]error, 0, len(destinations))
+	for _, i := range destinations {
+		errs = append(errs, fmt.Errorf("%v: %w", i, outputErrs[i]))
+	}
+	return errors.Join(errs...)
+}
+
+// retry sends the event again to the outputs which failed to send it, it must be called once Wait has returned.
+func (d *eventDelivery) retry(ctx context.Context) *eventDelivery {
+	d.Lock()
+	failed := make(map[string]bool, len(d.errs))
+	for i := range d.errs {
+		failed[i] = true
+	}
+	d.Unlock()
+	return dispatchEvent(newTestDeliveryContext(ctx, &testDelivery{outputs: failed, results: make(map[string]testResult)}), d.falcopayload)
+}
+
+// forwardEvent sends the event to all the enabled outputs, the returned delivery is done once every output has
+// handled it. Each post gets a context derived from ctx, with the timeout of its output.
+func forwardEvent(ctx context.Context, falcopayload types.FalcoPayload) *eventDelivery {
+	eventStream.publish(falcopayload)
+	return dispatchEvent(ctx, falcopayload)
 }
 
-func forwardEvent(falcopayload types.FalcoPayload) {
+// dispatchEvent posts the event to the enabled outputs, the ones selected by the test delivery of ctx if any.
+func dispatchEvent(ctx context.Context, falcopayload types.FalcoPayload) *eventDelivery {
+	// the logs of the posts carry the uuid and the rule of the event
+	ctx = logger.NewContext(ctx, logger.F(logger.UUIDKey, falcopayload.UUID), logger.F(logger.RuleKey, falcopayload.Rule))
+	d := &eventDelivery{falcopayload: falcopayload}
+	// the outputs can be changed with the admin API, not while the event is dispatched
+	outputsLock.RLock()
+	defer outputsLock.RUnlock()
+	// the test events are only sent to their selected outputs, which return their results
+	delivery := getTestDelivery(ctx)
+	// destination is the label of the output in the metrics
+	send := func(client *outputs.Client, destination string, post func(context.Context, types.FalcoPayload) error) {
+		if !delivery.selects(destination) || !outputStatuses.allow(destination) {
+			return
+		}
+		d.wg.Add(1)
+		outputsInFlight.Add(1)
+		inFlight := promStats.OutputsInFlight.With(map[string]string{"destination": destination})
+		inFlight.Inc()
+		otlpInFlight := metric.WithAttributes(attribute.String("destination", destination))
+		otlpOutputsInFlight.Add(ctx, 1, otlpInFlight)
+		// the client isn't closed before the end of the post
+		endPost := client.BeginPost()
+		go func() {
+			defer endPost()
+			defer outputsInFlight.Done()
+			defer d.wg.Done()
+			defer inFlight.Dec()
+			defer otlpOutputsInFlight.Add(ctx, -1, otlpInFlight)
+			ctx, span := tracer.Start(ctx, "deliver "+client.OutputType, trace.WithAttributes(
+				append(eventAttributes(falcopayload), attribute.String("falcosidekick.output", client.OutputType))...,
+			))
+			defer span.End()
+			if timeout := client.Timeout(); timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			var code int
+			if delivery != nil {
+				ctx = outputs.WithStatusCode(ctx, &code)
+			}
+			start := time.Now()
+			err := post(ctx, falcopayload)
+			if err != nil {
+				d.fail(destination, err)
+			}
+			delivery.add(destination, code, start, err)
+			observeDelivery(destination, falcopayload, start, err)
+			eventJournal.record(falcopayload, destination, start, err)
+			outputStatuses.observe(destination, err)
+		}()
+	}
+
 	if config.Slack.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Slack.MinimumPriority) || falcopayload.Rule == testRule) {
-		go slackClient.SlackPost(falcopayload)
+		send(slackClient, "slack", slackClient.SlackPost)
 	}
 
 	if config.Cliq.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Cliq.MinimumPriority) || falcopayload.Rule == testRule) {
-		go cliqClient.CliqPost(falcopayload)
+		send(cliqClient, "cliq", cliqClient.CliqPost)
 	}
 
 	if config.Rocketchat.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Rocketchat.MinimumPriority) || falcopayload.Rule == testRule) {
-		go rocketchatClient.RocketchatPost(falcopayload)
+		send(rocketchatClient, "rocketchat", rocketchatClient.RocketchatPost)
 	}
 
 	if config.Mattermost.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Mattermost.MinimumPriority) || falcopayload.Rule == testRule) {
-		go mattermostClient.MattermostPost(falcopayload)
+		send(mattermostClient, "mattermost", mattermostClient.MattermostPost)
 	}
 
 	if config.Teams.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Teams.MinimumPriority) || falcopayload.Rule == testRule) {
-		go teamsClient.TeamsPost(falcopayload)
+		send(teamsClient, "teams", teamsClient.TeamsPost)
 	}
 
 	if config.Datadog.APIKey != "" && (falcopayload.Priority >= types.Priority(config.Datadog.MinimumPriority) || falcopayload.Rule == testRule) {
-		go datadogClient.DatadogPost(falcopayload)
+		send(datadogClient, "datadog", datadogClient.DatadogPost)
 	}
 
 	if config.Discord.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Discord.MinimumPriority) || falcopayload.Rule == testRule) {
-		go discordClient.DiscordPost(falcopayload)
+		send(discordClient, "discord", discordClient.DiscordPost)
 	}
 
 	if config.Alertmanager.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Alertmanager.MinimumPriority) || falcopayload.Rule == testRule) {
-		go alertmanagerClient.AlertmanagerPost(falcopayload)
+		send(alertmanagerClient, "alertmanager", alertmanagerClient.AlertmanagerPost)
 	}
 
 	if config.Elasticsearch.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Elasticsearch.MinimumPriority) || falcopayload.Rule == testRule) {
-		go elasticsearchClient.ElasticsearchPost(falcopayload)
+		send(elasticsearchClient, "elasticsearch", elasticsearchClient.ElasticsearchPost)
 	}
 
 	if config.Influxdb.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Influxdb.MinimumPriority) || falcopayload.Rule == testRule) {
-		go influxdbClient.InfluxdbPost(falcopayload)
+		send(influxdbClient, "influxdb", influxdbClient.InfluxdbPost)
 	}
 
 	if config.Loki.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Loki.MinimumPriority) || falcopayload.Rule == testRule) {
-		go lokiClient.LokiPost(falcopayload)
+		send(lokiClient, "loki", lokiClient.LokiPost)
 	}
 
 	if config.Nats.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Nats.MinimumPriority) || falcopayload.Rule == testRule) {
-		go natsClient.NatsPublish(falcopayload)
+		send(natsClient, "nats", natsClient.NatsPublish)
 	}
 
 	if config.Stan.HostPort != "" && config.Stan.ClusterID != "" && config.Stan.ClientID != "" && (falcopayload.Priority >= types.Priority(config.Stan.MinimumPriority) || falcopayload.Rule == testRule) {
-		go stanClient.StanPublish(falcopayload)
+		send(stanClient, "stan", stanClient.StanPublish)
 	}
 
 	if config.AWS.Lambda.FunctionName != "" && (falcopayload.Priority >= types.Priority(config.AWS.Lambda.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.InvokeLambda(falcopayload)
+		send(awsClient, "awslambda", awsClient.InvokeLambda)
 	}
 
 	if config.AWS.SQS.URL != "" && (falcopayload.Priority >= types.Priority(config.AWS.SQS.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.SendMessage(falcopayload)
+		send(awsClient, "awssqs", awsClient.SendMessage)
 	}
 
 	if config.AWS.SNS.TopicArn != "" && (falcopayload.Priority >= types.Priority(config.AWS.SNS.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.PublishTopic(falcopayload)
+		send(awsClient, "awssns", awsClient.PublishTopic)
 	}
 
 	if config.AWS.CloudWatchLogs.LogGroup != "" && (falcopayload.Priority >= types.Priority(config.AWS.CloudWatchLogs.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.SendCloudWatchLog(falcopayload)
+		send(awsClient, "awscloudwatchlogs", awsClient.SendCloudWatchLog)
 	}
 
 	if config.AWS.S3.Bucket != "" && (falcopayload.Priority >= types.Priority(config.AWS.S3.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.UploadS3(falcopayload)
+		send(awsClient, "awss3", awsClient.UploadS3)
 	}
 
 	if (config.AWS.SecurityLake.Bucket != "" && config.AWS.SecurityLake.Region != "" && config.AWS.SecurityLake.AccountID != "" && config.AWS.SecurityLake.Prefix != "") && (falcopayload.Priority >= types.Priority(config.AWS.SecurityLake.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.EnqueueSecurityLake(falcopayload)
+		send(awsClient, "awssecuritylake", awsClient.EnqueueSecurityLake)
 	}
 
 	if config.AWS.Kinesis.StreamName != "" && (falcopayload.Priority >= types.Priority(config.AWS.Kinesis.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.PutRecord(falcopayload)
+		send(awsClient, "awskinesis", awsClient.PutRecord)
 	}
 
 	if config.SMTP.HostPort != "" && (falcopayload.Priority >= types.Priority(config.SMTP.MinimumPriority) || falcopayload.Rule == testRule) {
-		go smtpClient.SendMail(falcopayload)
+		send(smtpClient, "smtp", smtpClient.SendMail)
 	}
 
 	if config.Opsgenie.APIKey != "" && (falcopayload.Priority >= types.Priority(config.Opsgenie.MinimumPriority) || falcopayload.Rule == testRule) {
-		go opsgenieClient.OpsgeniePost(falcopayload)
+		send(opsgenieClient, "opsgenie", opsgenieClient.OpsgeniePost)
 	}
 
 	if config.Webhook.Address != "" && (falcopayload.Priority >= types.Priority(config.Webhook.MinimumPriority) || falcopayload.Rule == testRule) {
-		go webhookClient.WebhookPost(falcopayload)
+		send(webhookClient, "webhook", webhookClient.WebhookPost)
 	}
 
 	if config.NodeRed.Address != "" && (falcopayload.Priority >= types.Priority(config.NodeRed.MinimumPriority) || falcopayload.Rule == testRule) {
-		go noderedClient.NodeRedPost(falcopayload)
+		send(noderedClient, "nodered", noderedClient.NodeRedPost)
 	}
 
 	if config.CloudEvents.Address != "" && (falcopayload.Priority >= types.Priority(config.CloudEvents.MinimumPriority) || falcopayload.Rule == testRule) {
-		go cloudeventsClient.CloudEventsSend(falcopayload)
+		send(cloudeventsClient, "cloudevents", cloudeventsClient.CloudEventsSend)
 	}
 
 	if config.Azure.EventHub.Name != "" && (falcopayload.Priority >= types.Priority(config.Azure.EventHub.MinimumPriority) || falcopayload.Rule == testRule) {
-		go azureClient.EventHubPost(falcopayload)
+		send(azureClient, "azureeventhub", azureClient.EventHubPost)
 	}
 
 	if config.GCP.PubSub.ProjectID != "" && config.GCP.PubSub.Topic != "" && (falcopayload.Priority >= types.Priority(config.GCP.PubSub.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpClient.GCPPublishTopic(falcopayload)
+		send(gcpClient, "gcppubsub", gcpClient.GCPPublishTopic)
 	}
 
 	if config.GCP.CloudFunctions.Name != "" && (falcopayload.Priority >= types.Priority(config.GCP.CloudFunctions.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpClient.GCPCallCloudFunction(falcopayload)
+		send(gcpClient, "gcpcloudfunctions", gcpClient.GCPCallCloudFunction)
 	}
 
 	if config.GCP.CloudRun.Endpoint != "" && (falcopayload.Priority >= types.Priority(config.GCP.CloudRun.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpCloudRunClient.CloudRunFunctionPost(falcopayload)
+		send(gcpCloudRunClient, "gcpcloudrun", gcpCloudRunClient.CloudRunFunctionPost)
 	}
 
 	if config.GCP.Storage.Bucket != "" && (falcopayload.Priority >= types.Priority(config.GCP.Storage.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpClient.UploadGCS(falcopayload)
+		send(gcpClient, "gcpstorage", gcpClient.UploadGCS)
 	}
 
 	if config.Googlechat.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Googlechat.MinimumPriority) || falcopayload.Rule == testRule) {
-		go googleChatClient.GooglechatPost(falcopayload)
+		send(googleChatClient, "googlechat", googleChatClient.GooglechatPost)
 	}
 
 	if config.Kafka.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Kafka.MinimumPriority) || falcopayload.Rule == testRule) {
-		go kafkaClient.KafkaProduce(falcopayload)
+		send(kafkaClient, "kafka", kafkaClient.KafkaProduce)
 	}
 
 	if config.KafkaRest.Address != "" && (falcopayload.Priority >= types.Priority(config.KafkaRest.MinimumPriority) || falcopayload.Rule == testRule) {
-		go kafkaRestClient.KafkaRestPost(falcopayload)
+		send(kafkaRestClient, "kafkarest", kafkaRestClient.KafkaRestPost)
 	}
 
 	if config.Pagerduty.RoutingKey != "" && (falcopayload.Priority >= types.Priority(config.Pagerduty.MinimumPriority) || falcopayload.Rule == testRule) {
-		go pagerdutyClient.PagerdutyPost(falcopayload)
+		send(pagerdutyClient, "pagerduty", pagerdutyClient.PagerdutyPost)
 	}
 
 	if config.Kubeless.Namespace != "" && config.Kubeless.Function != "" && (falcopayload.Priority >= types.Priority(config.Kubeless.MinimumPriority) || falcopayload.Rule == testRule) {
-		go kubelessClient.KubelessCall(falcopayload)
+		send(kubelessClient, "kubeless", kubelessClient.KubelessCall)
 	}
 
 	if config.Openfaas.FunctionName != "" && (falcopayload.Priority >= types.Priority(config.Openfaas.MinimumPriority) || falcopayload.Rule == testRule) {
-		go openfaasClient.OpenfaasCall(falcopayload)
+		send(openfaasClient, "openfaas", openfaasClient.OpenfaasCall)
 	}
 
 	if config.Tekton.EventListener != "" && (falcopayload.Priority >= types.Priority(config.Tekton.MinimumPriority) || falcopayload.Rule == testRule) {
-		go tektonClient.TektonPost(falcopayload)
+		send(tektonClient, "tekton", tektonClient.TektonPost)
 	}
 
-	if config.Rabbitmq.URL != "" && config.Rabbitmq.Queue != "" && (falcopayload.Priority >= types.Priority(config.Openfaas.MinimumPriority) || falcopayload.Rule == testRule) {
-		go rabbitmqClient.Publish(falcopayload)
+	if config.Rabbitmq.URL != "" && config.Rabbitmq.Queue != "" && (falcopayload.Priority >= types.Priority(config.Rabbitmq.MinimumPriority) || falcopayload.Rule == testRule) {
+		send(rabbitmqClient, "rabbitmq", rabbitmqClient.Publish)
 	}
 
 	if config.Wavefront.EndpointHost != "" && config.Wavefront.EndpointType != "" && (falcopayload.Priority >= types.Priority(config.Wavefront.MinimumPriority) || falcopayload.Rule == testRule) {
-		go wavefrontClient.WavefrontPost(falcopayload)
+		send(wavefrontClient, "wavefront", wavefrontClient.WavefrontPost)
 	}
 
 	if config.Grafana.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Grafana.MinimumPriority) || falcopayload.Rule == testRule) {
-		go grafanaClient.GrafanaPost(falcopayload)
+		send(grafanaClient, "grafana", grafanaClient.GrafanaPost)
 	}
 
 	if config.GrafanaOnCall.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.GrafanaOnCall.MinimumPriority) || falcopayload.Rule == testRule) {
-		go grafanaOnCallClient.GrafanaOnCallPost(falcopayload)
+		send(grafanaOnCallClient, "grafanaoncall", grafanaOnCallClient.GrafanaOnCallPost)
 	}
 
 	if config.WebUI.URL != "" {
-		go webUIClient.WebUIPost(falcopayload)
+		send(webUIClient, "webui", webUIClient.WebUIPost)
 	}
 
 	if config.Fission.Function != "" && (falcopayload.Priority >= types.Priority(config.Fission.MinimumPriority) || falcopayload.Rule == testRule) {
-		go fissionClient.FissionCall(falcopayload)
+		send(fissionClient, "fission", fissionClient.FissionCall)
 	}
 	if config.PolicyReport.Enabled && (falcopayload.Priority >= types.Priority(config.PolicyReport.MinimumPriority)) {
-		go policyReportClient.UpdateOrCreatePolicyReport(falcopayload)
+		send(policyReportClient, "policyreport", policyReportClient.UpdateOrCreatePolicyReport)
 	}
 
 	if config.Yandex.S3.Bucket != "" && (falcopayload.Priority >= types.Priority(config.Yandex.S3.MinimumPriority) || falcopayload.Rule == testRule) {
-		go yandexClient.UploadYandexS3(falcopayload)
+		send(yandexClient, "yandexs3", yandexClient.UploadYandexS3)
 	}
 
 	if config.Yandex.DataStreams.StreamName != "" && (falcopayload.Priority >= types.Priority(config.Yandex.DataStreams.MinimumPriority) || falcopayload.Rule == testRule) {
-		go yandexClient.UploadYandexDataStreams(falcopayload)
+		send(yandexClient, "yandexdatastreams", yandexClient.UploadYandexDataStreams)
 	}
 
 	if config.Syslog.Host != "" && (falcopayload.Priority >= types.Priority(config.Syslog.MinimumPriority) || falcopayload.Rule == testRule) {
-		go syslogClient.SyslogPost(falcopayload)
+		send(syslogClient, "syslog", syslogClient.SyslogPost)
 	}
 
 	if config.MQTT.Broker != "" && (falcopayload.Priority >= types.Priority(config.MQTT.MinimumPriority) || falcopayload.Rule == testRule) {
-		go mqttClient.MQTTPublish(falcopayload)
+		send(mqttClient, "mqtt", mqttClient.MQTTPublish)
 	}
 
 	if config.Zincsearch.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Zincsearch.MinimumPriority) || falcopayload.Rule == testRule) {
-		go zincsearchClient.ZincsearchPost(falcopayload)
+		send(zincsearchClient, "zincsearch", zincsearchClient.ZincsearchPost)
 	}
 
 	if config.Gotify.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Gotify.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gotifyClient.GotifyPost(falcopayload)
+		send(gotifyClient, "gotify", gotifyClient.GotifyPost)
 	}
 
 	if config.Spyderbat.OrgUID != "" && (falcopayload.Priority >= types.Priority(config.Spyderbat.MinimumPriority) || falcopayload.Rule == testRule) {
-		go spyderbatClient.SpyderbatPost(falcopayload)
+		send(spyderbatClient, "spyderbat", spyderbatClient.SpyderbatPost)
 	}
 
 	if config.TimescaleDB.Host != "" && (falcopayload.Priority >= types.Priority(config.TimescaleDB.MinimumPriority) || falcopayload.Rule == testRule) {
-		go timescaleDBClient.TimescaleDBPost(falcopayload)
+		send(timescaleDBClient, "timescaledb", timescaleDBClient.TimescaleDBPost)
 	}
 
 	if config.Redis.Address != "" && (falcopayload.Priority >= types.Priority(config.Redis.MinimumPriority) || falcopayload.Rule == testRule) {
-		go redisClient.RedisPost(falcopayload)
+		send(redisClient, "redis", redisClient.RedisPost)
 	}
 
 	if config.Telegram.ChatID != "" && config.Telegram.Token != "" && (falcopayload.Priority >= types.Priority(config.Telegram.MinimumPriority) || falcopayload.Rule == testRule) {
-		go telegramClient.TelegramPost(falcopayload)
+		send(telegramClient, "telegram", telegramClient.TelegramPost)
 	}
 
 	if config.N8N.Address != "" && (falcopayload.Priority >= types.Priority(config.N8N.MinimumPriority) || falcopayload.Rule == testRule) {
-		go n8nClient.N8NPost(falcopayload)
+		send(n8nClient, "n8n", n8nClient.N8NPost)
 	}
 
 	if config.OpenObserve.HostPort != "" && (falcopayload.Priority >= types.Priority(config.OpenObserve.MinimumPriority) || falcopayload.Rule == testRule) {
-		go openObserveClient.OpenObservePost(falcopayload)
+		send(openObserveClient, "openobserve", openObserveClient.OpenObservePost)
 	}
 
 	if config.Dynatrace.APIToken != "" && config.Dynatrace.APIUrl != "" && (falcopayload.Priority >= types.Priority(config.Dynatrace.MinimumPriority) || falcopayload.Rule == testRule) {
-		go dynatraceClient.DynatracePost(falcopayload)
+		send(dynatraceClient, "dynatrace", dynatraceClient.DynatracePost)
 	}
+
+	if config.Forward.Address != "" && (falcopayload.Priority >= types.Priority(config.Forward.MinimumPriority) || falcopayload.Rule == testRule) {
+		send(forwardClient, "forward", forwardClient.ForwardPost)
+	}
+
+	return d
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+var falcoTestInput = `{"output":"This is a test from falcosidekick","priority":"Debug","rule":"Test rule","time":"2001-01-01T01:10:00Z","output_fields":{"proc.name":"falcosidekick"}}`
+
+func TestNewRequestBodyReader(t *testing.T) {
+	config = &types.Configuration{MaxRequestSize: 1024}
+
+	gz := new(bytes.Buffer)
+	gw := gzip.NewWriter(gz)
+	gw.Write([]byte(falcoTestInput))
+	gw.Close()
+
+	zl := new(bytes.Buffer)
+	zw := zlib.NewWriter(zl)
+	zw.Write([]byte(falcoTestInput))
+	zw.Close()
+
+	zs := new(bytes.Buffer)
+	ze, _ := zstd.NewWriter(zs)
+	ze.Write([]byte(falcoTestInput))
+	ze.Close()
+
+	for encoding, body := range map[string][]byte{
+		"":        []byte(falcoTestInput),
+		"gzip":    gz.Bytes(),
+		"deflate": zl.Bytes(),
+		"zstd":    zs.Bytes(),
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		r.Header.Set("Content-Encoding", encoding)
+		rc, err := newRequestBodyReader(httptest.NewRecorder(), r)
+		require.Nil(t, err, encoding)
+		b, err := io.ReadAll(rc)
+		require.Nil(t, err, encoding)
+		require.Equal(t, falcoTestInput, string(b), encoding)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(falcoTestInput))
+	r.Header.Set("Content-Encoding", "br")
+	_, err := newRequestBodyReader(httptest.NewRecorder(), r)
+	require.True(t, errors.Is(err, ErrUnsupportedContentEncoding))
+
+	// a small compressed body must not bypass the limit once decompressed
+	bomb := new(bytes.Buffer)
+	gw = gzip.NewWriter(bomb)
+	gw.Write(bytes.Repeat([]byte(" "), 4096))
+	gw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/", bomb)
+	r.Header.Set("Content-Encoding", "gzip")
+	rc, err := newRequestBodyReader(httptest.NewRecorder(), r)
+	require.Nil(t, err)
+	_, err = io.ReadAll(rc)
+	var maxBytesErr *http.MaxBytesError
+	require.True(t, errors.As(err, &maxBytesErr))
+}
+
+func TestDescribeDecodingError(t *testing.T) {
+	for input, expected := range map[string]string{
+		``:                        "empty body",
+		`{"rule":`:                "unexpected end of JSON input",
+		`{"rule": 1}`:             "field 'rule' must be of type string, got number",
+		`{"rule": "r", "foo": 1}`: `unknown field "foo"`,
+		`[]`:                      "body must be a JSON object, got array",
+	} {
+		var falcopayload types.FalcoPayload
+		d := json.NewDecoder(strings.NewReader(input))
+		d.DisallowUnknownFields()
+		err := d.Decode(&falcopayload)
+		require.NotNil(t, err, input)
+		require.Equal(t, expected, describeDecodingError(err), input)
+	}
+}
+
+func TestNewFalcoPayload(t *testing.T) {
+	_, closeTest := newInputTest(t, 0)
+	defer closeTest()
+
+	// the uuid of the body isn't kept
+	falcopayload, err := newFalcoPayload(context.Background(), strings.NewReader(strings.Replace(falcoTestInput, "{", `{"uuid":"my-uuid",`, 1)))
+	require.Nil(t, err)
+	require.NotEmpty(t, falcopayload.UUID)
+	require.NotEqual(t, "my-uuid", falcopayload.UUID)
+	require.Equal(t, "1", stats.Falco.Get("debug").String())
+
+	// the incomplete events are refused before being counted
+	_, err = newFalcoPayload(context.Background(), strings.NewReader(`{"output":"missing fields"}`))
+	require.ErrorIs(t, err, ErrMissingFields)
+	require.Equal(t, "1", stats.Falco.Get("debug").String())
+}
+
+func TestDecodeFalcoPayloadUnknownFields(t *testing.T) {
+	config = &types.Configuration{Debug: true}
+	input := strings.Replace(falcoTestInput, "{", `{"foo":1,"Bar":"b","RULE_ID":2,`, 1)
+	require.Equal(t, []string{"Bar", "RULE_ID", "foo"}, unknownFields([]byte(input)))
+	require.Empty(t, unknownFields([]byte(falcoTestInput)))
+
+	// the unknown fields are ignored, unless the decoding is strict
+	falcopayload, err := decodeFalcoPayload(strings.NewReader(input))
+	require.Nil(t, err)
+	require.Equal(t, "Test rule", falcopayload.Rule)
+
+	config.StrictDecoding = true
+	_, err = decodeFalcoPayload(strings.NewReader(input))
+	require.NotNil(t, err)
+	require.Equal(t, `unknown field "foo"`, describeDecodingError(err))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// cloudEventsReservedExtensions are the extensions set by the CloudEvents output, they duplicate fields of the
+// Falco payload and aren't copied into the output fields.
+var cloudEventsReservedExtensions = map[string]bool{
+	"priority":     true,
+	"rule":         true,
+	"event_source": true,
+	"hostname":     true,
+}
+
+// cloudEventsHandler receives CloudEvents in binary or structured mode, their data must be a Falco payload. The id
+// of the CloudEvent is kept as UUID of the event and its extensions are added to the output fields.
+func cloudEventsHandler(w http.ResponseWriter, r *http.Request) {
+	stats.CloudEventsInput.Add("total", 1)
+	nullClient.CountMetric("inputs.cloudevents.total", 1, []string{})
+
+	if r.Body == nil || r.Method != http.MethodPost {
+		rejectInputRequest(w, r, "cloudevents", stats.CloudEventsInput, "Please send a CloudEvent with post http method", http.StatusBadRequest, "nobody")
+		return
+	}
+
+	body, err := newRequestBodyReader(w, r)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedContentEncoding) {
+			rejectInputRequest(w, r, "cloudevents", stats.CloudEventsInput, fmt.Sprintf("Please send a body encoded with gzip, deflate or zstd (%v)", err), http.StatusUnsupportedMediaType, "unsupportedencoding")
+			return
+		}
+		rejectInputRequest(w, r, "cloudevents", stats.CloudEventsInput, fmt.Sprintf("Please send a valid compressed request body (%v)", err), http.StatusBadRequest, "invalidencoding")
+		return
+	}
+	defer body.Close()
+	r.Body = body
+
+	event, err := cehttp.NewEventFromHTTPRequest(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			rejectInputRequest(w, r, "cloudevents", stats.CloudEventsInput, fmt.Sprintf("Request body exceeds the maximum size of %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge, "toolarge")
+			return
+		}
+		rejectInputRequest(w, r, "cloudevents", stats.CloudEventsInput, fmt.Sprintf("Please send a valid CloudEvent (%v)", err), http.StatusBadRequest, "invalidcloudevent")
+		return
+	}
+
+	falcopayload, err := decodeCloudEvent(event)
+	if err != nil {
+		rejectInputRequest(w, r, "cloudevents", stats.CloudEventsInput, "Please send a CloudEvent with a valid Falco payload as data: "+describeDecodingError(err), http.StatusBadRequest, "invalidjson")
+		return
+	}
+
+	if !falcopayload.Check() {
+		rejectInputRequest(w, r, "cloudevents", stats.CloudEventsInput, "Please send a CloudEvent with a valid Falco payload as data", http.StatusBadRequest, "invalidjson")
+		return
+	}
+
+	falcopayload = processFalcoPayload(falcopayload)
+
+	nullClient.CountMetric("inputs.cloudevents.accepted", 1, []string{})
+	stats.CloudEventsInput.Add("accepted", 1)
+	promStats.Inputs.With(map[string]string{"source": "cloudevents", "status": "accepted"}).Inc()
+	if client := getAuthClientName(r.Context()); client != "" {
+		countClientMetric(client, "accepted")
+	}
+	forwardEvent(detachContext(r.Context()), falcopayload)
+}
+
+// decodeCloudEvent returns the Falco payload carried by a CloudEvent, with the id of the CloudEvent as UUID. The
+// extensions are added to the output fields, without overriding the existing ones.
+func decodeCloudEvent(event *cloudevents.Event) (types.FalcoPayload, error) {
+	falcopayload, err := decodeFalcoPayload(bytes.NewReader(event.Data()))
+	if err != nil {
+		return types.FalcoPayload{}, err
+	}
+
+	falcopayload.UUID = event.ID()
+	if falcopayload.Time.IsZero() {
+		falcopayload.Time = event.Time()
+	}
+	for k, v := range event.Extensions() {
+		if cloudEventsReservedExtensions[k] {
+			continue
+		}
+		if falcopayload.OutputFields == nil {
+			falcopayload.OutputFields = make(map[string]interface{})
+		}
+		if _, ok := falcopayload.OutputFields[k]; ok {
+			continue
+		}
+		value, err := cetypes.Format(v)
+		if err != nil {
+			value = fmt.Sprintf("%v", v)
+		}
+		falcopayload.OutputFields[k] = value
+	}
+
+	return falcopayload, nil
+}
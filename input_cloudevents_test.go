@@ -0,0 +1,65 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestCloudEventsHandler(t *testing.T) {
+	config = &types.Configuration{MaxRequestSize: 1024}
+	stats = &types.Statistics{Falco: new(expvar.Map).Init(), CloudEventsInput: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		Falco:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_falco"}, []string{"hostname", "rule", "priority", "k8s_ns_name", "k8s_pod_name"}),
+		Inputs: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_inputs"}, []string{"source", "status"}),
+	}
+	nullClient = &outputs.Client{Config: config, Stats: stats, PromStats: promStats}
+
+	binary := httptest.NewRequest(http.MethodPost, "/cloudevents", strings.NewReader(falcoTestInput))
+	binary.Header.Set("Content-Type", "application/json")
+	binary.Header.Set("ce-specversion", "1.0")
+	binary.Header.Set("ce-id", "binary-id")
+	binary.Header.Set("ce-source", "https://falco.org")
+	binary.Header.Set("ce-type", "falco.rule.output.v1")
+	binary.Header.Set("ce-cluster", "prod")
+	binary.Header.Set("ce-rule", "Test rule")
+
+	structured := httptest.NewRequest(http.MethodPost, "/cloudevents", strings.NewReader(`{"specversion":"1.0","id":"structured-id","source":"https://falco.org","type":"falco.rule.output.v1","datacontenttype":"application/json","cluster":"prod","data":`+falcoTestInput+`}`))
+	structured.Header.Set("Content-Type", "application/cloudevents+json")
+
+	for name, r := range map[string]*http.Request{"binary": binary, "structured": structured} {
+		w := httptest.NewRecorder()
+		cloudEventsHandler(w, r)
+		require.Equal(t, http.StatusOK, w.Code, name)
+	}
+	require.Equal(t, "2", stats.CloudEventsInput.Get("accepted").String())
+
+	w := httptest.NewRecorder()
+	cloudEventsHandler(w, httptest.NewRequest(http.MethodPost, "/cloudevents", strings.NewReader(falcoTestInput)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "1", stats.CloudEventsInput.Get("rejected").String())
+}
+
+func TestDecodeCloudEvent(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("my-id")
+	event.SetExtension("cluster", "prod")
+	event.SetExtension("rule", "Other rule")
+	event.SetExtension("procname", 1)
+	require.Nil(t, event.SetData(cloudevents.ApplicationJSON, []byte(`{"output":"test","priority":"Debug","rule":"Test rule","output_fields":{"procname":"falcosidekick"}}`)))
+
+	falcopayload, err := decodeCloudEvent(&event)
+	require.Nil(t, err)
+	require.Equal(t, "my-id", falcopayload.UUID)
+	require.Equal(t, "Test rule", falcopayload.Rule)
+	require.Equal(t, map[string]interface{}{"procname": "falcosidekick", "cluster": "prod"}, falcopayload.OutputFields)
+}
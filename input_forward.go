@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// forwardHandler receives the batches of events sent by the Forward output of other falcosidekick. A batch is
+// acknowledged once all the outputs have handled its events, even if some of them failed: the failures are counted by
+// the outputs, and sending the batch again would duplicate the events of the other ones. A batch with an invalid event
+// is refused as a whole.
+func forwardHandler(w http.ResponseWriter, r *http.Request) {
+	stats.ForwardInput.Add("total", 1)
+	nullClient.CountMetric("inputs.forward.total", 1, []string{})
+
+	if r.Body == nil || r.Method != http.MethodPost {
+		rejectInputRequest(w, r, "forward", stats.ForwardInput, "Please send a batch of events with post http method", http.StatusBadRequest, "nobody")
+		return
+	}
+
+	body, err := newRequestBodyReader(w, r)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedContentEncoding) {
+			rejectInputRequest(w, r, "forward", stats.ForwardInput, fmt.Sprintf("Please send a body encoded with gzip, deflate or zstd (%v)", err), http.StatusUnsupportedMediaType, "unsupportedencoding")
+			return
+		}
+		rejectInputRequest(w, r, "forward", stats.ForwardInput, fmt.Sprintf("Please send a valid compressed request body (%v)", err), http.StatusBadRequest, "invalidencoding")
+		return
+	}
+	defer body.Close()
+
+	var batch []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			rejectInputRequest(w, r, "forward", stats.ForwardInput, fmt.Sprintf("Request body exceeds the maximum size of %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge, "toolarge")
+			return
+		}
+		rejectInputRequest(w, r, "forward", stats.ForwardInput, "Please send a valid batch of events: "+describeDecodingError(err), http.StatusBadRequest, "invalidjson")
+		return
+	}
+
+	falcopayloads := make([]types.FalcoPayload, 0, len(batch))
+	for i, j := range batch {
+		falcopayload, err := decodeFalcoPayload(bytes.NewReader(j))
+		if err != nil {
+			rejectInputRequest(w, r, "forward", stats.ForwardInput, fmt.Sprintf("Please send a valid batch of events: event %v: %v", i, describeDecodingError(err)), http.StatusBadRequest, "invalidjson")
+			return
+		}
+		if !falcopayload.Check() {
+			rejectInputRequest(w, r, "forward", stats.ForwardInput, fmt.Sprintf("Please send a valid batch of events: event %v: missing fields", i), http.StatusBadRequest, "invalidjson")
+			return
+		}
+		falcopayloads = append(falcopayloads, falcopayload)
+	}
+
+	deliveries := make([]*eventDelivery, 0, len(falcopayloads))
+	for _, i := range falcopayloads {
+		deliveries = append(deliveries, forwardEvent(detachContext(r.Context()), processFalcoPayload(i)))
+	}
+	for _, i := range deliveries {
+		_ = i.Wait()
+	}
+
+	nullClient.CountMetric("inputs.forward.accepted", 1, []string{})
+	stats.ForwardInput.Add("accepted", 1)
+	promStats.Inputs.With(map[string]string{"source": "forward", "status": "accepted"}).Inc()
+	if client := getAuthClientName(r.Context()); client != "" {
+		countClientMetric(client, "accepted")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"accepted":%v}`, len(falcopayloads))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// newForwardHub sets the hub receiving the forwarded events, with a webhook output, and returns the rules of the
+// events posted to the webhook
+func newForwardHub(t *testing.T) (func() []string, func()) {
+	var (
+		posts []string
+		lock  sync.Mutex
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload types.FalcoPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+		lock.Lock()
+		posts = append(posts, payload.Rule)
+		lock.Unlock()
+		if payload.Rule == "Failing rule" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	config = &types.Configuration{MaxRequestSize: 1024}
+	config.Webhook.Address = ts.URL
+	config.Webhook.CustomHeaders = make(map[string]string)
+	stats = &types.Statistics{Falco: new(expvar.Map).Init(), ForwardInput: new(expvar.Map).Init(), Webhook: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		Falco:              prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_falco"}, []string{"hostname", "rule", "priority", "k8s_ns_name", "k8s_pod_name"}),
+		Inputs:             prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_inputs"}, []string{"source", "status"}),
+		Outputs:            prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_outputs"}, []string{"destination", "status"}),
+		OutputLatency:      prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_latency"}, []string{"destination"}),
+		OutputSendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_send_duration"}, []string{"destination", "status"}),
+		OutputsInFlight:    prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_in_flight"}, []string{"destination"}),
+		OutputErrors:       prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors"}, []string{"destination", "class"}),
+	}
+	nullClient = &outputs.Client{Config: config, Stats: stats, PromStats: promStats}
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	var err error
+	webhookClient, err = outputs.NewClient("Webhook", config.Webhook.Address, false, true, config, stats, promStats, nil, nil)
+	require.Nil(t, err)
+	outputs.EnabledOutputs = []string{"Webhook"}
+
+	return func() []string {
+			lock.Lock()
+			defer lock.Unlock()
+			return append([]string(nil), posts...)
+		}, func() {
+			outputs.EnabledOutputs = nil
+			ts.Close()
+		}
+}
+
+func newForwardRequest(t *testing.T, batch string) *http.Request {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	_, err := zw.Write([]byte(batch))
+	require.Nil(t, err)
+	require.Nil(t, zw.Close())
+	r := httptest.NewRequest(http.MethodPost, "/forward", &b)
+	r.Header.Set("Content-Encoding", "gzip")
+	return r
+}
+
+func TestForwardHandler(t *testing.T) {
+	posts, closeHub := newForwardHub(t)
+	defer closeHub()
+
+	failing := strings.Replace(falcoTestInput, "Test rule", "Failing rule", 1)
+	w := httptest.NewRecorder()
+	forwardHandler(w, newForwardRequest(t, "["+falcoTestInput+","+failing+"]"))
+	// the batch is acknowledged once the outputs have handled its events, even if they failed
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `{"accepted":2}`, w.Body.String())
+	require.ElementsMatch(t, []string{"Test rule", "Failing rule"}, posts())
+	require.Equal(t, "1", stats.Webhook.Get(outputs.Error).String())
+
+	// a batch with an invalid event is refused as a whole
+	w = httptest.NewRecorder()
+	forwardHandler(w, newForwardRequest(t, "["+falcoTestInput+`,{"output":"missing fields"}]`))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Len(t, posts(), 2)
+
+	// the maximum size applies to the decompressed batch
+	w = httptest.NewRecorder()
+	forwardHandler(w, newForwardRequest(t, "["+strings.Repeat(falcoTestInput+",", 10)+falcoTestInput+"]"))
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = httptest.NewRecorder()
+	forwardHandler(w, httptest.NewRequest(http.MethodGet, "/forward", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "1", stats.ForwardInput.Get("accepted").String())
+	require.Equal(t, "3", stats.ForwardInput.Get("rejected").String())
+}
+
+func TestForwardToHub(t *testing.T) {
+	posts, closeHub := newForwardHub(t)
+	defer closeHub()
+	hub := httptest.NewServer(http.HandlerFunc(forwardHandler))
+	defer hub.Close()
+
+	// the events of the edge are forwarded in a batch below the maximum size of the hub
+	edgeConfig := &types.Configuration{
+		Forward: types.ForwardOutputConfig{Address: hub.URL, BatchSize: 100, MaxBytes: int(config.MaxRequestSize), FlushInterval: 1, MaxSpooledBatches: 10, RetryInterval: 60, CheckCert: true},
+	}
+	edgeStats := &types.Statistics{Forward: new(expvar.Map).Init()}
+	edgePromStats := &types.PromStatistics{
+		Outputs:          prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_edge_outputs"}, []string{"destination", "status"}),
+		OutputQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_edge_queue_depth"}, []string{"destination"}),
+	}
+	edge, err := outputs.NewForwardClient(edgeConfig, edgeStats, edgePromStats, nil, nil, "1.0.0")
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	for i := 0; i < 10; i++ {
+		require.Nil(t, edge.ForwardPost(context.Background(), f))
+	}
+	require.Eventually(t, func() bool {
+		return edgeStats.Forward.Get(outputs.OK) != nil && edgeStats.Forward.Get(outputs.OK).String() == "10"
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Len(t, posts(), 10)
+	require.Nil(t, edgeStats.Forward.Get(outputs.Error))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// kafkaInFlightMessage is a consumed message, with the delivery of its event to the outputs
+type kafkaInFlightMessage struct {
+	message  kafka.Message
+	delivery *eventDelivery
+}
+
+// kafkaMessageReader is the part of kafka.Reader used by the Kafka input
+type kafkaMessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// kafkaInputDone is closed once the Kafka input has committed the offsets of its last messages and closed its reader
+var kafkaInputDone chan struct{}
+
+// newKafkaInputReader returns a reader consuming the topics of the Kafka input as part of a consumer group.
+func newKafkaInputReader(config *types.Configuration) (*kafka.Reader, error) {
+	dialer := &kafka.Dialer{
+		Timeout:   10 * time.Second,
+		DualStack: true,
+		ClientID:  config.Inputs.Kafka.ClientID,
+	}
+
+	if config.Inputs.Kafka.TLS {
+		dialer.TLS = outputs.NewKafkaTLSConfig()
+	}
+
+	if config.Inputs.Kafka.SASL != "" {
+		var err error
+		dialer.SASLMechanism, err = outputs.NewKafkaSASLMechanism(config.Inputs.Kafka.SASL, config.Inputs.Kafka.Username, config.Inputs.Kafka.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var startOffset int64
+	switch strings.ToLower(config.Inputs.Kafka.StartOffset) {
+	case "first":
+		startOffset = kafka.FirstOffset
+	case "last":
+		startOffset = kafka.LastOffset
+	default:
+		return nil, fmt.Errorf("unsupported start offset %q", config.Inputs.Kafka.StartOffset)
+	}
+
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        strings.Split(config.Inputs.Kafka.HostPort, ","),
+		GroupID:        config.Inputs.Kafka.GroupID,
+		GroupTopics:    config.Inputs.Kafka.TopicsList,
+		Dialer:         dialer,
+		StartOffset:    startOffset,
+		CommitInterval: time.Second,
+		ErrorLogger: kafka.LoggerFunc(func(msg string, args ...interface{}) {
+			log.Printf("[ERROR] : Kafka Input - "+msg+"\n", args...)
+		}),
+	}), nil
+}
+
+// consumeKafka forwards the messages of the Kafka input to the outputs. The offsets are committed in order, once
+// all the outputs have sent the events, at most maxInFlight messages are handled at the same time. The reader is
+// closed once the offsets of the messages fetched before ctx is done are committed.
+func consumeKafka(ctx context.Context, reader kafkaMessageReader, maxInFlight int) {
+	inFlight := make(chan kafkaInFlightMessage, maxInFlight)
+	committed := make(chan struct{})
+	// the offsets of the messages fetched before a shutdown are still committed once they're handled
+	go func() {
+		defer close(committed)
+		commitKafkaMessages(detachContext(ctx), reader, inFlight)
+	}()
+	defer func() {
+		close(inFlight)
+		<-committed
+		if err := reader.Close(); err != nil {
+			log.Printf("[ERROR] : Kafka Input - %v\n", err)
+		}
+	}()
+
+	for {
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return
+			}
+			log.Printf("[ERROR] : Kafka Input - %v\n", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		promStats.KafkaInputLag.With(map[string]string{"topic": m.Topic, "partition": strconv.Itoa(m.Partition)}).Set(float64(m.HighWaterMark - m.Offset - 1))
+		inFlight <- kafkaInFlightMessage{message: m, delivery: handleKafkaMessage(detachContext(ctx), m)}
+	}
+}
+
+// handleKafkaMessage forwards the event of the message to the outputs, nil is returned for invalid messages.
+func handleKafkaMessage(ctx context.Context, m kafka.Message) *eventDelivery {
+	delivery, err := forwardInputMessage(ctx, "kafka", stats.KafkaInput, m.Value)
+	if err != nil {
+		log.Printf("[ERROR] : Kafka Input - Invalid event in %v/%v at offset %v: %v\n", m.Topic, m.Partition, m.Offset, err)
+	}
+	return delivery
+}
+
+// commitKafkaMessages commits the offsets of the messages once the outputs have sent their events. The committed
+// offset of a partition covers the previous messages, the commits stop at the first message which can't be
+// delivered before ctx is done, it's consumed again after a restart.
+func commitKafkaMessages(ctx context.Context, reader kafkaMessageReader, inFlight <-chan kafkaInFlightMessage) {
+	for i := range inFlight {
+		if i.delivery != nil && !deliverKafkaMessage(ctx, i) {
+			break
+		}
+		if err := reader.CommitMessages(ctx, i.message); err != nil {
+			log.Printf("[ERROR] : Kafka Input - Can't commit offset %v of %v/%v: %v\n", i.message.Offset, i.message.Topic, i.message.Partition, err)
+		}
+	}
+	// the messages still in flight aren't committed
+	for i := range inFlight {
+		if i.delivery != nil {
+			_ = i.delivery.Wait()
+		}
+	}
+}
+
+// deliverKafkaMessage waits for the outputs to handle the event of the message, the ones which failed with an error
+// which can be fixed get it again, up to inputs.kafka.maxretries times, before ctx is done. The event is logged if
+// an output can't send it. It tells whether the offset of the message can be committed.
+func deliverKafkaMessage(ctx context.Context, i kafkaInFlightMessage) bool {
+	message := fmt.Sprintf("in %v/%v at offset %v", i.message.Topic, i.message.Partition, i.message.Offset)
+	if !deliverInputEvent(ctx, "Kafka", message, i.delivery, config.Inputs.Kafka.MaxRetries) {
+		log.Printf("[ERROR] : Kafka Input - The offset %v of %v/%v isn't committed, the message will be consumed again\n", i.message.Offset, i.message.Topic, i.message.Partition)
+		return false
+	}
+	return true
+}
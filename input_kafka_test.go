@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// testKafkaReader serves the messages of its channel and records the committed offsets
+type testKafkaReader struct {
+	messages  chan kafka.Message
+	committed []int64
+	closed    bool
+	sync.Mutex
+}
+
+func (r *testKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case m := <-r.messages:
+		return m, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *testKafkaReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, i := range msgs {
+		r.committed = append(r.committed, i.Offset)
+	}
+	return nil
+}
+
+func (r *testKafkaReader) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *testKafkaReader) getCommitted() []int64 {
+	r.Lock()
+	defer r.Unlock()
+	return append([]int64(nil), r.committed...)
+}
+
+// newInputTest sets a webhook output which fails to send the events of the failing rule the first failures
+// times and rejects the events of the rejected rule, and returns the rules of the posted events
+func newInputTest(t *testing.T, failures int) (func() []string, func()) {
+	var (
+		posts []string
+		lock  sync.Mutex
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload types.FalcoPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+		lock.Lock()
+		defer lock.Unlock()
+		posts = append(posts, payload.Rule)
+		switch {
+		case payload.Rule == "Failing rule" && failures != 0:
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+		case payload.Rule == "Rejected rule":
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+
+	config = &types.Configuration{}
+	config.Webhook.Address = ts.URL
+	config.Webhook.CustomHeaders = make(map[string]string)
+	config.Inputs.Kafka.MaxRetries = 10
+	stats = &types.Statistics{Falco: new(expvar.Map).Init(), KafkaInput: new(expvar.Map).Init(), NatsInput: new(expvar.Map).Init(), Webhook: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		Falco:              prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_falco"}, []string{"hostname", "rule", "priority", "k8s_ns_name", "k8s_pod_name"}),
+		Inputs:             prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_inputs"}, []string{"source", "status"}),
+		KafkaInputLag:      prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_kafka_lag"}, []string{"topic", "partition"}),
+		Outputs:            prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_outputs"}, []string{"destination", "status"}),
+		OutputLatency:      prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_latency"}, []string{"destination"}),
+		OutputSendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_send_duration"}, []string{"destination", "status"}),
+		OutputsInFlight:    prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_in_flight"}, []string{"destination"}),
+		OutputErrors:       prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors"}, []string{"destination", "class"}),
+	}
+	nullClient = &outputs.Client{Config: config, Stats: stats, PromStats: promStats}
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	var err error
+	webhookClient, err = outputs.NewClient("Webhook", config.Webhook.Address, false, true, config, stats, promStats, nil, nil)
+	require.Nil(t, err)
+	outputs.EnabledOutputs = []string{"Webhook"}
+	interval := inputRetryInterval
+	inputRetryInterval = 10 * time.Millisecond
+
+	return func() []string {
+			lock.Lock()
+			defer lock.Unlock()
+			return append([]string(nil), posts...)
+		}, func() {
+			inputRetryInterval = interval
+			outputs.EnabledOutputs = nil
+			ts.Close()
+		}
+}
+
+func TestConsumeKafka(t *testing.T) {
+	posts, closeTest := newInputTest(t, 2)
+	defer closeTest()
+
+	reader := &testKafkaReader{messages: make(chan kafka.Message, 4)}
+	for i, j := range []string{
+		falcoTestInput,
+		`{"output":"invalid"}`,
+		strings.Replace(falcoTestInput, "Test rule", "Failing rule", 1),
+		falcoTestInput,
+	} {
+		reader.messages <- kafka.Message{Topic: "falco", Offset: int64(i), HighWaterMark: 4, Value: []byte(j)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumeKafka(ctx, reader, 2)
+	}()
+
+	// the offsets are committed in order, once the failing output has sent the event
+	require.Eventually(t, func() bool {
+		return len(reader.getCommitted()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []int64{0, 1, 2, 3}, reader.getCommitted())
+	require.ElementsMatch(t, []string{"Test rule", "Failing rule", "Failing rule", "Failing rule", "Test rule"}, posts())
+	require.Equal(t, "1", stats.KafkaInput.Get(outputs.Rejected).String())
+
+	cancel()
+	<-done
+	reader.Lock()
+	defer reader.Unlock()
+	require.True(t, reader.closed)
+}
+
+func TestCommitKafkaMessagesNotSent(t *testing.T) {
+	_, closeTest := newInputTest(t, -1)
+	defer closeTest()
+
+	reader := &testKafkaReader{}
+	inFlight := make(chan kafkaInFlightMessage, 3)
+	for i, j := range []string{falcoTestInput, strings.Replace(falcoTestInput, "Test rule", "Failing rule", 1), falcoTestInput} {
+		delivery, err := forwardInputMessage(context.Background(), "kafka", stats.KafkaInput, []byte(j))
+		require.Nil(t, err)
+		inFlight <- kafkaInFlightMessage{message: kafka.Message{Topic: "falco", Offset: int64(i)}, delivery: delivery}
+	}
+	close(inFlight)
+
+	// the commits stop at the message which isn't sent before the end
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	commitKafkaMessages(ctx, reader, inFlight)
+	require.Equal(t, []int64{0}, reader.getCommitted())
+}
+
+func TestDeliverKafkaMessageDropped(t *testing.T) {
+	posts, closeTest := newInputTest(t, -1)
+	defer closeTest()
+	config.Inputs.Kafka.MaxRetries = 2
+
+	for i, j := range []string{"Rejected rule", "Failing rule"} {
+		delivery, err := forwardInputMessage(context.Background(), "kafka", stats.KafkaInput, []byte(strings.Replace(falcoTestInput, "Test rule", j, 1)))
+		require.Nil(t, err)
+		// the offset is committed once the event is dropped
+		require.True(t, deliverKafkaMessage(context.Background(), kafkaInFlightMessage{message: kafka.Message{Topic: "falco", Offset: int64(i)}, delivery: delivery}))
+	}
+	// the rejected event isn't retried, the failing one is retried twice
+	require.Equal(t, []string{"Rejected rule", "Failing rule", "Failing rule", "Failing rule"}, posts())
+}
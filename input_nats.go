@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+var natsConsumerNameRegularExpression = regexp.MustCompile("[^A-Za-z0-9_-]+")
+
+// subscribeNats subscribes to the subjects of the NATS input, the events are forwarded to the outputs. With
+// JetStream, the messages are acknowledged once all the outputs have handled them.
+func subscribeNats(config *types.Configuration) (*nats.Conn, error) {
+	nc, err := nats.Connect(config.Inputs.Nats.HostPort,
+		nats.Name("falcosidekick"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("[ERROR] : NATS Input - Disconnected: %v\n", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			log.Printf("[INFO]  : NATS Input - Reconnected to %v\n", nc.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.Inputs.Nats.JetStream {
+		for _, subject := range config.Inputs.Nats.SubjectsList {
+			if _, err := nc.QueueSubscribe(subject, config.Inputs.Nats.QueueGroup, handleNatsMessage); err != nil {
+				nc.Close()
+				return nil, err
+			}
+		}
+		return nc, nil
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	for _, subject := range config.Inputs.Nats.SubjectsList {
+		opts := []nats.SubOpt{
+			nats.Durable(natsDurableName(config.Inputs.Nats.Durable, subject, len(config.Inputs.Nats.SubjectsList))),
+			nats.ManualAck(),
+			nats.AckExplicit(),
+			nats.AckWait(time.Duration(config.Inputs.Nats.AckWait) * time.Second),
+			nats.MaxAckPending(config.Inputs.Nats.MaxAckPending),
+			nats.DeliverAll(),
+		}
+		if config.Inputs.Nats.Stream != "" {
+			opts = append(opts, nats.BindStream(config.Inputs.Nats.Stream))
+		}
+		if _, err := js.QueueSubscribe(subject, config.Inputs.Nats.QueueGroup, handleJetStreamMessage, opts...); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return nc, nil
+}
+
+// natsDurableName returns the name of the durable consumer of a subject, each subject needs its own consumer.
+func natsDurableName(durable, subject string, subjects int) string {
+	if subjects == 1 {
+		return durable
+	}
+	return durable + "_" + natsConsumerNameRegularExpression.ReplaceAllString(subject, "_")
+}
+
+func handleNatsMessage(m *nats.Msg) {
+	if _, err := forwardInputMessage(outputsContext, "nats", stats.NatsInput, m.Data); err != nil {
+		log.Printf("[ERROR] : NATS Input - Invalid event on %v: %v\n", m.Subject, err)
+	}
+}
+
+// handleJetStreamMessage acknowledges the message once the outputs have handled its event, the ones which failed
+// get it again, the message is kept in progress meanwhile to not be redelivered. It's negatively acknowledged if
+// the event is still not sent at the shutdown. Invalid messages are terminated to not be redelivered.
+func handleJetStreamMessage(m *nats.Msg) {
+	delivery, err := forwardInputMessage(outputsContext, "nats", stats.NatsInput, m.Data)
+	if err != nil {
+		log.Printf("[ERROR] : NATS Input - Invalid event on %v: %v\n", m.Subject, err)
+		if err := m.Term(); err != nil {
+			log.Printf("[ERROR] : NATS Input - Can't terminate message on %v: %v\n", m.Subject, err)
+		}
+		return
+	}
+
+	go func() {
+		stop := make(chan struct{})
+		go keepNatsMessageInProgress(m, time.Duration(config.Inputs.Nats.AckWait)*time.Second/2, stop)
+		defer close(stop)
+
+		if !deliverInputEvent(outputsContext, "NATS", "on "+m.Subject, delivery, config.Inputs.Nats.MaxRetries) {
+			// the message is redelivered after the restart, its event is sent again to all the outputs
+			log.Printf("[ERROR] : NATS Input - The message on %v isn't acknowledged, it will be redelivered\n", m.Subject)
+			if err := m.Nak(); err != nil {
+				log.Printf("[ERROR] : NATS Input - Can't negatively acknowledge message on %v: %v\n", m.Subject, err)
+			}
+			return
+		}
+		if err := m.Ack(); err != nil {
+			log.Printf("[ERROR] : NATS Input - Can't acknowledge message on %v: %v\n", m.Subject, err)
+		}
+	}()
+}
+
+// keepNatsMessageInProgress resets the redelivery timer of the message at each interval, until stop is closed
+func keepNatsMessageInProgress(m *nats.Msg, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.InProgress(); err != nil {
+				log.Printf("[ERROR] : NATS Input - Can't mark message on %v in progress: %v\n", m.Subject, err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	nats "github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestSubscribeNatsJetStream(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	require.Nil(t, err)
+	go ns.Start()
+	defer ns.Shutdown()
+	require.True(t, ns.ReadyForConnections(5*time.Second))
+
+	config = &types.Configuration{
+		Inputs: types.InputsConfig{
+			Nats: types.NatsInputConfig{
+				HostPort:      ns.ClientURL(),
+				SubjectsList:  []string{"falco.>"},
+				QueueGroup:    "falcosidekick",
+				JetStream:     true,
+				Durable:       "falcosidekick",
+				AckWait:       30,
+				MaxAckPending: 10,
+			},
+		},
+	}
+	stats = &types.Statistics{Falco: new(expvar.Map).Init(), NatsInput: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		Falco:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_falco"}, []string{"hostname", "rule", "priority", "k8s_ns_name", "k8s_pod_name"}),
+		Inputs: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_inputs"}, []string{"source", "status"}),
+	}
+	nullClient = &outputs.Client{Config: config, Stats: stats, PromStats: promStats}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.Nil(t, err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	require.Nil(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "FALCO", Subjects: []string{"falco.>"}})
+	require.Nil(t, err)
+
+	input, err := subscribeNats(config)
+	require.Nil(t, err)
+	defer input.Close()
+
+	_, err = js.Publish("falco.debug.test_rule", []byte(falcoTestInput))
+	require.Nil(t, err)
+	_, err = js.Publish("falco.debug.test_rule", []byte(`{"output":"invalid"}`))
+	require.Nil(t, err)
+
+	// both messages are acknowledged, the invalid one is terminated
+	require.Eventually(t, func() bool {
+		info, err := js.ConsumerInfo("FALCO", "falcosidekick")
+		return err == nil && info.AckFloor.Stream == 2 && info.NumAckPending == 0
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, "1", stats.NatsInput.Get(outputs.Accepted).String())
+	require.Equal(t, "1", stats.NatsInput.Get(outputs.Rejected).String())
+}
+
+func TestNatsDurableName(t *testing.T) {
+	require.Equal(t, "falcosidekick", natsDurableName("falcosidekick", "falco.>", 1))
+	require.Equal(t, "falcosidekick_falco_critical_", natsDurableName("falcosidekick", "falco.critical.*", 2))
+}
+
+func TestHandleJetStreamMessageFailure(t *testing.T) {
+	posts, closeTest := newInputTest(t, 1)
+	defer closeTest()
+	// the retry comes after the ack wait, the message is kept in progress meanwhile
+	inputRetryInterval = 1500 * time.Millisecond
+
+	// the event is sent once to the output which doesn't fail
+	var slackPosts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		slackPosts.Add(1)
+	}))
+	defer ts.Close()
+	config.Slack.WebhookURL = ts.URL
+	stats.Slack = new(expvar.Map).Init()
+	var err error
+	slackClient, err = outputs.NewClient("Slack", config.Slack.WebhookURL, false, true, config, stats, promStats, nil, nil)
+	require.Nil(t, err)
+	outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Slack")
+
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	require.Nil(t, err)
+	go ns.Start()
+	defer ns.Shutdown()
+	require.True(t, ns.ReadyForConnections(5*time.Second))
+	config.Inputs.Nats = types.NatsInputConfig{
+		HostPort:      ns.ClientURL(),
+		SubjectsList:  []string{"falco.>"},
+		JetStream:     true,
+		Durable:       "falcosidekick",
+		AckWait:       1,
+		MaxAckPending: 10,
+		MaxRetries:    10,
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.Nil(t, err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	require.Nil(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "FALCO", Subjects: []string{"falco.>"}})
+	require.Nil(t, err)
+
+	input, err := subscribeNats(config)
+	require.Nil(t, err)
+	defer input.Close()
+
+	_, err = js.Publish("falco.debug.failing_rule", []byte(strings.Replace(falcoTestInput, "Test rule", "Failing rule", 1)))
+	require.Nil(t, err)
+
+	// the message is acknowledged once the failing output has sent the event again, without a redelivery
+	require.Eventually(t, func() bool {
+		info, err := js.ConsumerInfo("FALCO", "falcosidekick")
+		return err == nil && info.AckFloor.Stream == 1 && info.NumAckPending == 0
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, []string{"Failing rule", "Failing rule"}, posts())
+	require.Equal(t, int32(1), slackPosts.Load())
+	require.Equal(t, "1", stats.NatsInput.Get(outputs.Accepted).String())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// ErrMissingFields is returned when an event received by an input isn't a complete Falco event
+var ErrMissingFields = errors.New("missing fields")
+
+// inputRetryInterval is the delay before the first new attempt to send an event of a message to the outputs which
+// failed, it's doubled at each attempt up to inputMaxRetryInterval
+var (
+	inputRetryInterval    = time.Second
+	inputMaxRetryInterval = time.Minute
+)
+
+// inputRetryDelay returns the delay before the new attempt to send an event, attempt starts at 1
+func inputRetryDelay(attempt int) time.Duration {
+	delay := inputRetryInterval
+	for i := 1; i < attempt && delay < inputMaxRetryInterval; i++ {
+		delay *= 2
+	}
+	if delay > inputMaxRetryInterval {
+		delay = inputMaxRetryInterval
+	}
+	return delay
+}
+
+// isRetryableError tells whether an output which failed to send an event can succeed with a new attempt: the
+// events rejected with a 4xx status, except 429, are never accepted.
+func isRetryableError(err error) bool {
+	return outputs.ClassifyError(err) != outputs.ClientErrorClass || errors.Is(err, outputs.ErrTooManyRequest)
+}
+
+// logDroppedEvent logs an event of an input which won't be sent to some outputs, to not lose it
+func logDroppedEvent(input, message string, err error, falcopayload types.FalcoPayload) {
+	event, _ := json.Marshal(falcopayload)
+	log.Printf("[ERROR] : %v Input - The event %v isn't sent (%v), it's dropped: %s\n", input, message, strings.ReplaceAll(err.Error(), "\n", ", "), event)
+}
+
+// deliverInputEvent waits for the outputs to handle the event of a message, the ones which failed with an error
+// which can be fixed get it again, up to maxRetries times, before ctx is done. The event is logged if an output
+// can't send it. It tells whether the message is handled, it's not if ctx is done first.
+func deliverInputEvent(ctx context.Context, input, message string, delivery *eventDelivery, maxRetries int) bool {
+	for attempt := 1; ; attempt++ {
+		err := delivery.Wait()
+		if err == nil {
+			return true
+		}
+		if permanentErr := delivery.dropPermanentErrors(); permanentErr != nil {
+			logDroppedEvent(input, message, permanentErr, delivery.falcopayload)
+			if err = delivery.Wait(); err == nil {
+				return true
+			}
+		}
+		if attempt > maxRetries {
+			logDroppedEvent(input, message, err, delivery.falcopayload)
+			return true
+		}
+		interval := inputRetryDelay(attempt)
+		log.Printf("[ERROR] : %v Input - The event %v isn't sent (%v), retrying in %v\n", input, message, strings.ReplaceAll(err.Error(), "\n", ", "), interval)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return false
+		}
+		delivery = delivery.retry(ctx)
+	}
+}
+
+// forwardInputMessage decodes the event of a message received by an input and forwards it to the outputs, the
+// returned delivery is done once every output has handled it.
+func forwardInputMessage(ctx context.Context, input string, counter *expvar.Map, message []byte) (*eventDelivery, error) {
+	counter.Add(outputs.Total, 1)
+
+	ctx, span := tracer.Start(ctx, "consume "+input, trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	falcopayload, err := newFalcoPayload(ctx, bytes.NewReader(message))
+	if err != nil {
+		counter.Add(outputs.Rejected, 1)
+		promStats.Inputs.With(map[string]string{"source": input, "status": outputs.Rejected}).Inc()
+		nullClient.CountMetric("inputs."+input+".rejected", 1, []string{"error:invalidjson"})
+		span.SetStatus(codes.Error, err.Error())
+		if errors.Is(err, ErrMissingFields) {
+			return nil, err
+		}
+		return nil, errors.New(describeDecodingError(err))
+	}
+
+	counter.Add(outputs.Accepted, 1)
+	promStats.Inputs.With(map[string]string{"source": input, "status": outputs.Accepted}).Inc()
+	nullClient.CountMetric("inputs."+input+".accepted", 1, []string{})
+	return forwardEvent(ctx, falcopayload), nil
+}
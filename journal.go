@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// journalDelivery is the result of the posts of an event to an output
+type journalDelivery struct {
+	Output   string    `json:"output"`
+	Status   string    `json:"status"`
+	Attempts int       `json:"attempts"`
+	Latency  float64   `json:"latency_ms"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// journalEntry is an event with its deliveries to the outputs
+type journalEntry struct {
+	UUID       string             `json:"uuid"`
+	Rule       string             `json:"rule"`
+	Priority   string             `json:"priority"`
+	Source     string             `json:"source,omitempty"`
+	Hostname   string             `json:"hostname,omitempty"`
+	Time       time.Time          `json:"time"`
+	Deliveries []*journalDelivery `json:"deliveries"`
+}
+
+// journalRecord is a line of the file of the journal, a delivery with its event
+type journalRecord struct {
+	journalDelivery
+	UUID      string    `json:"uuid"`
+	Rule      string    `json:"rule"`
+	Priority  string    `json:"priority"`
+	Source    string    `json:"source,omitempty"`
+	Hostname  string    `json:"hostname,omitempty"`
+	EventTime time.Time `json:"event_time"`
+}
+
+// journal keeps the deliveries of the last events, the oldest events are removed once maxevents is reached. The
+// deliveries are also appended to a JSON lines file if set, it's read at the start and compacted when it gets twice
+// bigger than needed.
+type journal struct {
+	maxEvents int
+	entries   map[string]*journalEntry
+	// order is the uuids of the events, the oldest first
+	order []string
+	// deliveries is the number of deliveries of the entries, the lines needed in the file
+	deliveries int
+
+	path  string
+	file  *os.File
+	lines int
+	sync.Mutex
+}
+
+func newJournal(config types.JournalConfig) (*journal, error) {
+	j := &journal{maxEvents: config.MaxEvents, entries: make(map[string]*journalEntry), path: config.File}
+	if j.path == "" {
+		return j, nil
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// record adds the result of a post of the event to an output, a new attempt for the same output updates its
+// delivery. A nil journal records nothing.
+func (j *journal) record(falcopayload types.FalcoPayload, output string, start time.Time, err error) {
+	if j == nil {
+		return
+	}
+	r := journalRecord{
+		journalDelivery: journalDelivery{
+			Output:   output,
+			Status:   outputs.OK,
+			Attempts: 1,
+			Latency:  float64(time.Since(start).Microseconds()) / 1000,
+			Time:     time.Now().UTC(),
+		},
+		UUID:      falcopayload.UUID,
+		Rule:      falcopayload.Rule,
+		Priority:  falcopayload.Priority.String(),
+		Source:    falcopayload.Source,
+		Hostname:  falcopayload.Hostname,
+		EventTime: falcopayload.Time,
+	}
+	if err != nil {
+		r.Status = outputs.Error
+		r.Error = err.Error()
+	}
+
+	j.Lock()
+	defer j.Unlock()
+	j.add(r)
+	if j.file == nil {
+		return
+	}
+	if err := j.write(r); err != nil {
+		log.Printf("[ERROR] : Journal - %v\n", err)
+		return
+	}
+	if j.lines > 2*j.deliveries {
+		if err := j.compact(); err != nil {
+			log.Printf("[ERROR] : Journal - %v\n", err)
+		}
+	}
+}
+
+// add adds the record to the entries, the lock must be held
+func (j *journal) add(r journalRecord) {
+	entry, ok := j.entries[r.UUID]
+	if !ok {
+		entry = &journalEntry{UUID: r.UUID, Rule: r.Rule, Priority: r.Priority, Source: r.Source, Hostname: r.Hostname, Time: r.EventTime}
+		j.entries[r.UUID] = entry
+		j.order = append(j.order, r.UUID)
+		if len(j.order) > j.maxEvents {
+			j.deliveries -= len(j.entries[j.order[0]].Deliveries)
+			delete(j.entries, j.order[0])
+			j.order = j.order[1:]
+		}
+	}
+
+	delivery := r.journalDelivery
+	for _, i := range entry.Deliveries {
+		if i.Output == delivery.Output {
+			delivery.Attempts += i.Attempts
+			*i = delivery
+			return
+		}
+	}
+	entry.Deliveries = append(entry.Deliveries, &delivery)
+	j.deliveries++
+}
+
+// write appends the record to the file, the lock must be held
+func (j *journal) write(r journalRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	j.lines++
+	return nil
+}
+
+// load reads the records of the file, the invalid lines are skipped
+func (j *journal) load() error {
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var skipped int
+	for scanner.Scan() {
+		var r journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil || r.UUID == "" {
+			skipped++
+			continue
+		}
+		j.add(r)
+	}
+	if skipped != 0 {
+		log.Printf("[WARN] : Journal - %v invalid lines skipped in %v\n", skipped, j.path)
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the file with the deliveries kept in memory only, the lock must be held
+func (j *journal) compact() error {
+	tmp, err := os.OpenFile(j.path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	var lines int
+	encoder := json.NewEncoder(tmp)
+	for _, uuid := range j.order {
+		entry := j.entries[uuid]
+		for _, i := range entry.Deliveries {
+			r := journalRecord{journalDelivery: *i, UUID: entry.UUID, Rule: entry.Rule, Priority: entry.Priority, Source: entry.Source, Hostname: entry.Hostname, EventTime: entry.Time}
+			if err := encoder.Encode(r); err != nil {
+				tmp.Close()
+				return err
+			}
+			lines++
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(j.path+".tmp", j.path); err != nil {
+		return err
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	j.lines = lines
+	return nil
+}
+
+// get returns a copy of the entry of the event
+func (j *journal) get(uuid string) (journalEntry, bool) {
+	j.Lock()
+	defer j.Unlock()
+	entry, ok := j.entries[uuid]
+	if !ok {
+		return journalEntry{}, false
+	}
+	return entry.copy(), true
+}
+
+func (e *journalEntry) copy() journalEntry {
+	c := *e
+	c.Deliveries = make([]*journalDelivery, 0, len(e.Deliveries))
+	for _, i := range e.Deliveries {
+		d := *i
+		c.Deliveries = append(c.Deliveries, &d)
+	}
+	return c
+}
+
+// journalFilter selects the entries returned by a search, the empty criteria match everything
+type journalFilter struct {
+	rule     string
+	priority types.PriorityType
+	output   string
+	status   string
+	since    time.Time
+	until    time.Time
+	limit    int
+}
+
+// newJournalFilter parses the filters of the query: rule, priority (minimum), output, status (ok or error), since
+// and until (RFC 3339 times of the events) and limit.
+func newJournalFilter(r *http.Request) (journalFilter, error) {
+	filter := journalFilter{limit: 100}
+	query := r.URL.Query()
+	filter.rule = query.Get("rule")
+	if p := query.Get("priority"); p != "" {
+		if checkPriority(p) == "" {
+			return journalFilter{}, fmt.Errorf("unknown priority '%v'", p)
+		}
+		filter.priority = types.Priority(p)
+	}
+	filter.output = strings.ToLower(query.Get("output"))
+	filter.status = strings.ToLower(query.Get("status"))
+	if filter.status != "" && filter.status != outputs.OK && filter.status != outputs.Error {
+		return journalFilter{}, fmt.Errorf("unknown status '%v'", filter.status)
+	}
+	for key, t := range map[string]*time.Time{"since": &filter.since, "until": &filter.until} {
+		if v := query.Get(key); v != "" {
+			var err error
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return journalFilter{}, fmt.Errorf("invalid %v time: %v", key, err)
+			}
+		}
+	}
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			return journalFilter{}, fmt.Errorf("invalid limit '%v'", l)
+		}
+		filter.limit = limit
+	}
+	return filter, nil
+}
+
+func (f journalFilter) match(entry *journalEntry) bool {
+	if f.rule != "" && entry.Rule != f.rule {
+		return false
+	}
+	if types.Priority(entry.Priority) < f.priority {
+		return false
+	}
+	if !f.since.IsZero() && entry.Time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && entry.Time.After(f.until) {
+		return false
+	}
+	if f.output == "" && f.status == "" {
+		return true
+	}
+	for _, i := range entry.Deliveries {
+		if (f.output == "" || i.Output == f.output) && (f.status == "" || i.Status == f.status) {
+			return true
+		}
+	}
+	return false
+}
+
+// search returns the matching entries, the last recorded first
+func (j *journal) search(filter journalFilter) []journalEntry {
+	j.Lock()
+	defer j.Unlock()
+	entries := make([]journalEntry, 0)
+	for i := len(j.order) - 1; i >= 0 && len(entries) < filter.limit; i-- {
+		if entry := j.entries[j.order[i]]; filter.match(entry) {
+			entries = append(entries, entry.copy())
+		}
+	}
+	return entries
+}
+
+// journalHandler answers GET /events/{uuid}/deliveries with the deliveries of an event, and GET /events with the
+// events matching the filters of the query.
+func journalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Please send with GET http method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var response interface{}
+	if r.URL.Path == "/events" || r.URL.Path == "/events/" {
+		filter, err := newJournalFilter(r)
+		if err != nil {
+			http.Error(w, "Please send valid filters: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		response = eventJournal.search(filter)
+	} else {
+		uuid, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/events/"), "/deliveries")
+		if !found || uuid == "" || strings.Contains(uuid, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		entry, ok := eventJournal.get(uuid)
+		if !ok {
+			http.Error(w, "Unknown event", http.StatusNotFound)
+			return
+		}
+		response = entry
+	}
+
+	w.Header().Set(outputs.ContentTypeHeaderKey, "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[ERROR] : Journal - %v\n", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func newJournalTestEvent(uuid, rule string) types.FalcoPayload {
+	return types.FalcoPayload{UUID: uuid, Rule: rule, Priority: types.Critical, Time: time.Now().UTC()}
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := newJournal(types.JournalConfig{MaxEvents: 2, File: path})
+	require.Nil(t, err)
+
+	j.record(newJournalTestEvent("1", "A"), "slack", time.Now(), nil)
+	j.record(newJournalTestEvent("1", "A"), "pagerduty", time.Now(), errors.New("timeout"))
+	j.record(newJournalTestEvent("1", "A"), "pagerduty", time.Now(), nil)
+	j.record(newJournalTestEvent("2", "B"), "slack", time.Now(), nil)
+
+	entry, ok := j.get("1")
+	require.True(t, ok)
+	require.Equal(t, "A", entry.Rule)
+	require.Len(t, entry.Deliveries, 2)
+	require.Equal(t, "pagerduty", entry.Deliveries[1].Output)
+	require.Equal(t, "ok", entry.Deliveries[1].Status)
+	require.Equal(t, 2, entry.Deliveries[1].Attempts)
+
+	// the oldest event is removed once the maximum is reached
+	j.record(newJournalTestEvent("3", "C"), "slack", time.Now(), errors.New("refused"))
+	_, ok = j.get("1")
+	require.False(t, ok)
+
+	require.Len(t, j.search(journalFilter{limit: 10}), 2)
+	found := j.search(journalFilter{status: "error", limit: 10})
+	require.Len(t, found, 1)
+	require.Equal(t, "3", found[0].UUID)
+	require.Equal(t, "refused", found[0].Deliveries[0].Error)
+
+	// the journal is reloaded from its file, which has been compacted
+	reloaded, err := newJournal(types.JournalConfig{MaxEvents: 2, File: path})
+	require.Nil(t, err)
+	require.Equal(t, []string{"2", "3"}, reloaded.order)
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "\n"))
+}
+
+func TestJournalHandler(t *testing.T) {
+	var err error
+	eventJournal, err = newJournal(types.JournalConfig{MaxEvents: 10})
+	require.Nil(t, err)
+	defer func() { eventJournal = nil }()
+	eventJournal.record(newJournalTestEvent("1", "A"), "slack", time.Now(), nil)
+	eventJournal.record(newJournalTestEvent("2", "B"), "slack", time.Now(), errors.New("refused"))
+
+	for _, i := range []struct {
+		path   string
+		status int
+		uuids  []string
+	}{
+		{path: "/events/1/deliveries", status: http.StatusOK, uuids: []string{"1"}},
+		{path: "/events/3/deliveries", status: http.StatusNotFound},
+		{path: "/events/1", status: http.StatusNotFound},
+		{path: "/events", status: http.StatusOK, uuids: []string{"2", "1"}},
+		{path: "/events?rule=A&output=slack", status: http.StatusOK, uuids: []string{"1"}},
+		{path: "/events?status=error", status: http.StatusOK, uuids: []string{"2"}},
+		{path: "/events?since=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339), status: http.StatusOK, uuids: []string{}},
+		{path: "/events?priority=unknown", status: http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		journalHandler(w, httptest.NewRequest(http.MethodGet, i.path, nil))
+		require.Equal(t, i.status, w.Code, i.path)
+		if i.status != http.StatusOK {
+			continue
+		}
+		var entries []journalEntry
+		if strings.Contains(i.path, "/deliveries") {
+			var entry journalEntry
+			require.Nil(t, json.Unmarshal(w.Body.Bytes(), &entry))
+			entries = append(entries, entry)
+		} else {
+			require.Nil(t, json.Unmarshal(w.Body.Bytes(), &entries))
+		}
+		uuids := []string{}
+		for _, j := range entries {
+			uuids = append(uuids, j.UUID)
+		}
+		require.Equal(t, i.uuids, uuids, i.path)
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := newJournal(types.JournalConfig{MaxEvents: 2, File: path})
+	require.Nil(t, err)
+
+	// the deliveries of the events to several outputs don't compact the file at each record
+	outputs := []string{"slack", "pagerduty", "webhook"}
+	compactions := 0
+	for i := 0; i < 10; i++ {
+		for _, o := range outputs {
+			lines := j.lines
+			j.record(newJournalTestEvent(strconv.Itoa(i), "A"), o, time.Now(), nil)
+			if j.lines <= lines {
+				compactions++
+			}
+		}
+	}
+	require.Equal(t, 6, j.deliveries)
+	require.LessOrEqual(t, j.lines, 2*j.deliveries)
+	require.Less(t, compactions, 10)
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, j.lines, strings.Count(string(data), "\n"))
+}
@@ -0,0 +1,386 @@
+// Package logger writes the logs of falcosidekick, as text or JSON lines, with a level and a set of fields for each
+// record. The known secrets of the configuration are redacted from the messages and the values of the fields.
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a record
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// Formats of the records
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Keys of the common fields
+const (
+	OutputKey     = "output"
+	UUIDKey       = "uuid"
+	RuleKey       = "rule"
+	StatusKey     = "status"
+	LatencyKey    = "latency_ms"
+	ErrorKey      = "error"
+	SuppressedKey = "suppressed"
+)
+
+const redacted = "*****"
+
+// ParseLevel returns the level matching its name: debug, info, warning or error.
+func ParseLevel(level string) (Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return DebugLevel, nil
+	case "info", "":
+		return InfoLevel, nil
+	case "warning", "warn":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("unknown log level '%v'", level)
+}
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case WarnLevel:
+		return "warning"
+	case ErrorLevel:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// tag is the prefix of the level in the text format, the same as the one used before the structured logs
+func (l Level) tag() string {
+	switch l {
+	case DebugLevel:
+		return "[DEBUG] :"
+	case WarnLevel:
+		return "[WARN] :"
+	case ErrorLevel:
+		return "[ERROR] :"
+	default:
+		return "[INFO]  :"
+	}
+}
+
+// Settings are the global settings of the logs
+type Settings struct {
+	Format string
+	Level  Level
+	// SuccessInterval is the minimum delay between two records of InfoLimited with the same output and message, 0
+	// logs all of them.
+	SuccessInterval time.Duration
+	// Secrets are redacted from the records
+	Secrets []string
+	Output  io.Writer
+}
+
+// limit is the state of the records of InfoLimited with the same output and message
+type limit struct {
+	last       time.Time
+	suppressed int
+}
+
+var (
+	settings = Settings{Format: TextFormat, Level: InfoLevel, Output: os.Stdout}
+	replacer *strings.Replacer
+	limits   = make(map[string]*limit)
+	lock     sync.Mutex
+)
+
+// Configure sets the global settings, the logs of the standard log package are written with them too.
+func Configure(s Settings) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if s.Format != JSONFormat {
+		s.Format = TextFormat
+	}
+	if s.Output == nil {
+		s.Output = os.Stdout
+	}
+	settings = s
+	limits = make(map[string]*limit)
+
+	secrets := make([]string, 0, len(s.Secrets))
+	for _, i := range s.Secrets {
+		if i != "" {
+			secrets = append(secrets, i)
+		}
+	}
+	// the longest secrets first, in case one contains another one
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	replacer = nil
+	if len(secrets) != 0 {
+		pairs := make([]string, 0, 2*len(secrets))
+		for _, i := range secrets {
+			pairs = append(pairs, i, redacted)
+		}
+		replacer = strings.NewReplacer(pairs...)
+	}
+
+	log.SetFlags(0)
+	log.SetOutput(stdWriter{})
+}
+
+// Field is a key/value pair added to a record
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Latency returns the field of the latency since start, in milliseconds
+func Latency(start time.Time) Field {
+	return F(LatencyKey, float64(time.Since(start).Microseconds())/1000)
+}
+
+// Logger writes records with a set of fields, it's safe for concurrent use. The nil Logger has no fields.
+type Logger struct {
+	fields []Field
+}
+
+// New returns a logger adding the fields to all its records
+func New(fields ...Field) *Logger {
+	return &Logger{fields: fields}
+}
+
+// ForOutput returns the logger of an output
+func ForOutput(output string) *Logger {
+	return New(F(OutputKey, output))
+}
+
+// With returns a logger adding the fields to the ones of l
+func (l *Logger) With(fields ...Field) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	n := &Logger{}
+	if l != nil {
+		n.fields = append(n.fields, l.fields...)
+	}
+	n.fields = append(n.fields, fields...)
+	return n
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the fields, like the uuid and the rule of an event, they're added to the
+// records of the loggers getting it with WithContext.
+func NewContext(ctx context.Context, fields ...Field) context.Context {
+	previous, _ := ctx.Value(contextKey{}).([]Field)
+	return context.WithValue(ctx, contextKey{}, append(append([]Field{}, previous...), fields...))
+}
+
+// WithContext returns a logger adding the fields of the context to the ones of l
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return l
+	}
+	fields, _ := ctx.Value(contextKey{}).([]Field)
+	return l.With(fields...)
+}
+
+// Enabled returns true if the records of the level are written
+func Enabled(level Level) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	return level >= settings.Level
+}
+
+func (l *Logger) Debug(msg string, fields ...Field) { l.write(DebugLevel, msg, fields) }
+func (l *Logger) Info(msg string, fields ...Field)  { l.write(InfoLevel, msg, fields) }
+func (l *Logger) Warn(msg string, fields ...Field)  { l.write(WarnLevel, msg, fields) }
+func (l *Logger) Error(msg string, fields ...Field) { l.write(ErrorLevel, msg, fields) }
+
+// InfoLimited writes an info record at most once per success interval for the same output and message, the number
+// of the records skipped in between is added to the next one. It's meant for the success of the posts.
+func (l *Logger) InfoLimited(msg string, fields ...Field) {
+	lock.Lock()
+	interval := settings.SuccessInterval
+	if interval > 0 {
+		key := msg
+		if l != nil {
+			for _, i := range l.fields {
+				if i.Key == OutputKey {
+					key = fmt.Sprint(i.Value) + "/" + msg
+				}
+			}
+		}
+		state, ok := limits[key]
+		if !ok {
+			state = &limit{}
+			limits[key] = state
+		}
+		if time.Since(state.last) < interval {
+			state.suppressed++
+			lock.Unlock()
+			return
+		}
+		if state.suppressed != 0 {
+			fields = append(fields, F(SuppressedKey, state.suppressed))
+		}
+		state.last, state.suppressed = time.Now(), 0
+	}
+	lock.Unlock()
+	l.write(InfoLevel, msg, fields)
+}
+
+func (l *Logger) write(level Level, msg string, fields []Field) {
+	lock.Lock()
+	defer lock.Unlock()
+	if level < settings.Level {
+		return
+	}
+
+	var all []Field
+	if l != nil {
+		all = append(all, l.fields...)
+	}
+	all = append(all, fields...)
+
+	var line []byte
+	if settings.Format == JSONFormat {
+		line = formatJSON(time.Now(), level, redact(msg), all)
+	} else {
+		line = formatText(time.Now(), level, redact(msg), all)
+	}
+	_, _ = settings.Output.Write(line)
+}
+
+// Redact replaces the secrets of the configuration in s
+func Redact(s string) string {
+	lock.Lock()
+	defer lock.Unlock()
+	return redact(s)
+}
+
+// redact replaces the secrets, the lock must be held
+func redact(s string) string {
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// value returns the value of a field ready to be encoded, with the secrets redacted
+func value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return redact(t)
+	case error:
+		return redact(t.Error())
+	case time.Duration:
+		return t.String()
+	case fmt.Stringer:
+		return redact(t.String())
+	case int, int32, int64, uint, uint32, uint64, float32, float64, bool, nil:
+		return t
+	default:
+		return redact(fmt.Sprint(t))
+	}
+}
+
+func formatJSON(now time.Time, level Level, msg string, fields []Field) []byte {
+	record := make(map[string]interface{}, len(fields)+3)
+	for _, i := range fields {
+		record[i.Key] = value(i.Value)
+	}
+	record["time"] = now.UTC().Format(time.RFC3339Nano)
+	record["level"] = level.String()
+	record["msg"] = msg
+
+	buf := new(strings.Builder)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(record); err != nil {
+		return []byte(fmt.Sprintf("{\"level\":\"error\",\"msg\":%q}\n", err.Error()))
+	}
+	return []byte(buf.String())
+}
+
+// formatText writes the record like the logs before the structured ones, the output prefixes the message and the
+// other fields follow it as key=value.
+func formatText(now time.Time, level Level, msg string, fields []Field) []byte {
+	buf := new(strings.Builder)
+	buf.WriteString(now.Format("2006/01/02 15:04:05 "))
+	buf.WriteString(level.tag())
+	buf.WriteString(" ")
+	for _, i := range fields {
+		if i.Key == OutputKey {
+			fmt.Fprintf(buf, "%v - ", value(i.Value))
+		}
+	}
+	buf.WriteString(msg)
+	for _, i := range fields {
+		if i.Key == OutputKey {
+			continue
+		}
+		s := fmt.Sprint(value(i.Value))
+		if s == "" || strings.ContainsAny(s, " =\"\n") {
+			s = fmt.Sprintf("%q", s)
+		}
+		fmt.Fprintf(buf, " %v=%v", i.Key, s)
+	}
+	buf.WriteString("\n")
+	return []byte(buf.String())
+}
+
+var (
+	stdLevelRegex  = regexp.MustCompile(`^\[(DEBUG|INFO|WARN|WARNING|ERROR)\]\s*:\s?`)
+	stdOutputRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9 ._()/-]{0,39}?) - `)
+)
+
+// stdWriter converts the lines of the standard log package, like "[ERROR] : Slack - message", into records
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	level := InfoLevel
+	if m := stdLevelRegex.FindStringSubmatch(msg); m != nil {
+		switch m[1] {
+		case "DEBUG":
+			level = DebugLevel
+		case "WARN", "WARNING":
+			level = WarnLevel
+		case "ERROR":
+			level = ErrorLevel
+		}
+		msg = msg[len(m[0]):]
+	}
+	var l *Logger
+	if m := stdOutputRegex.FindStringSubmatch(msg); m != nil {
+		l = ForOutput(m[1])
+		msg = msg[len(m[0]):]
+	}
+	l.write(level, msg, nil)
+	return len(p), nil
+}
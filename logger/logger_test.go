@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFormat(t *testing.T) {
+	out := new(bytes.Buffer)
+	Configure(Settings{Format: JSONFormat, Level: InfoLevel, Secrets: []string{"s3cr3t"}, Output: out})
+
+	ctx := NewContext(context.Background(), F(UUIDKey, "1234"), F(RuleKey, "Test rule"))
+	ForOutput("Slack").WithContext(ctx).Error("post failed", F(StatusKey, 401), F(ErrorKey, errors.New("bad token s3cr3t")))
+
+	var record map[string]interface{}
+	require.Nil(t, json.Unmarshal(out.Bytes(), &record))
+	require.Equal(t, "error", record["level"])
+	require.Equal(t, "post failed", record["msg"])
+	require.Equal(t, "Slack", record[OutputKey])
+	require.Equal(t, "1234", record[UUIDKey])
+	require.Equal(t, "Test rule", record[RuleKey])
+	require.Equal(t, float64(401), record[StatusKey])
+	require.Equal(t, "bad token *****", record[ErrorKey])
+}
+
+func TestTextFormat(t *testing.T) {
+	out := new(bytes.Buffer)
+	Configure(Settings{Format: TextFormat, Level: WarnLevel, Secrets: []string{"s3cr3t"}, Output: out})
+
+	l := ForOutput("Loki")
+	l.Info("dropped")
+	require.Empty(t, out.String())
+
+	l.Warn("token s3cr3t refused", F(RuleKey, "Test rule"), F(StatusKey, 403))
+	require.True(t, strings.HasSuffix(out.String(), ` [WARN] : Loki - token ***** refused rule="Test rule" status=403`+"\n"), out.String())
+}
+
+func TestStdLog(t *testing.T) {
+	out := new(bytes.Buffer)
+	Configure(Settings{Format: JSONFormat, Level: InfoLevel, Secrets: []string{"s3cr3t"}, Output: out})
+
+	log.Printf("[DEBUG] : Slack payload : {}\n")
+	require.Empty(t, out.String())
+
+	log.Printf("[ERROR] : Kafka Input - can't connect with s3cr3t\n")
+	var record map[string]interface{}
+	require.Nil(t, json.Unmarshal(out.Bytes(), &record))
+	require.Equal(t, "error", record["level"])
+	require.Equal(t, "Kafka Input", record[OutputKey])
+	require.Equal(t, "can't connect with *****", record["msg"])
+
+	out.Reset()
+	log.Printf("[INFO]  : Enabled Outputs : [Slack]\n")
+	record = nil
+	require.Nil(t, json.Unmarshal(out.Bytes(), &record))
+	require.Equal(t, "info", record["level"])
+	require.Nil(t, record[OutputKey])
+	require.Equal(t, "Enabled Outputs : [Slack]", record["msg"])
+}
+
+func TestInfoLimited(t *testing.T) {
+	out := new(bytes.Buffer)
+	Configure(Settings{Format: JSONFormat, Level: InfoLevel, SuccessInterval: time.Hour, Output: out})
+
+	for i := 0; i < 5; i++ {
+		ForOutput("Slack").InfoLimited("Post OK")
+	}
+	// the other outputs have their own limit
+	ForOutput("Loki").InfoLimited("Post OK")
+	require.Equal(t, 2, strings.Count(out.String(), "\n"))
+
+	// the next record counts the skipped ones
+	limits["Slack/Post OK"].last = time.Time{}
+	out.Reset()
+	ForOutput("Slack").InfoLimited("Post OK")
+	var record map[string]interface{}
+	require.Nil(t, json.Unmarshal(out.Bytes(), &record))
+	require.Equal(t, float64(4), record[SuppressedKey])
+}
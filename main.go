@@ -7,14 +7,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
 	"github.com/embano1/memlog"
+	nats "github.com/nats-io/nats.go"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
@@ -73,11 +77,18 @@ var (
 	n8nClient           *outputs.Client
 	openObserveClient   *outputs.Client
 	dynatraceClient     *outputs.Client
+	forwardClient       *outputs.Client
+
+	eventStream  *streamBroker
+	eventJournal *journal
+	natsInput    *nats.Conn
 
 	statsdClient, dogstatsdClient *statsd.Client
 	config                        *types.Configuration
 	stats                         *types.Statistics
 	promStats                     *types.PromStatistics
+	clientAuthenticator           *authenticator
+	falcoLabelsLimiter            *labelsLimiter
 
 	regPromLabels *regexp.Regexp
 )
@@ -94,8 +105,20 @@ func init() {
 	regPromLabels, _ = regexp.Compile("^[a-zA-Z_:][a-zA-Z0-9_:]*$")
 
 	config = getConfig()
+	configureLogs(config)
+	if err := initTracing(config); err != nil {
+		log.Printf("[ERROR] : Tracing - %v\n", err)
+	} else if config.Tracing.Endpoint != "" {
+		log.Printf("[INFO]  : Tracing - Sending the spans to %v\n", config.Tracing.Endpoint)
+	}
+	if err := initOTLPMetrics(config); err != nil {
+		log.Printf("[ERROR] : OTLP Metrics - %v\n", err)
+	} else if config.OTLPMetrics.Endpoint != "" {
+		log.Printf("[INFO]  : OTLP Metrics - Sending the metrics to %v with %v\n", config.OTLPMetrics.Endpoint, config.OTLPMetrics.Protocol)
+	}
 	stats = getInitStats()
 	promStats = getInitPromStats(config)
+	falcoLabelsLimiter = newLabelsLimiter(config)
 
 	nullClient = &outputs.Client{
 		OutputType:      "null",
@@ -106,6 +129,41 @@ func init() {
 		DogstatsdClient: dogstatsdClient,
 	}
 
+	eventStream = newStreamBroker(config.Stream)
+	if config.Journal.MaxEvents > 0 {
+		var err error
+		if eventJournal, err = newJournal(config.Journal); err != nil {
+			log.Printf("[ERROR] : Journal - %v\n", err)
+		}
+	}
+
+	if len(config.Capture.OutputsList) != 0 {
+		var err error
+		// the requests must not be sent if the capture can't be set up
+		if requestCapturer, err = newCapturer(config.Capture); err != nil {
+			log.Fatalf("[ERROR] : Capture - %v\n", err)
+		}
+		outputs.Capture = requestCapturer.capture
+		log.Printf("[INFO]  : Capture - The requests of the outputs '%v' are captured instead of being sent\n", config.Capture.Outputs)
+	}
+
+	if len(config.Auth.Clients) != 0 {
+		var err error
+		clientAuthenticator, err = newAuthenticator(config.Auth)
+		if err != nil {
+			log.Fatalf("[ERROR] : Auth - %v\n", err)
+		}
+	}
+
+	if config.Admin.AuditLog != "" {
+		audit, err := newAuditLogger(config.Admin.AuditLog)
+		if err != nil {
+			log.Printf("[ERROR] : Admin - %v\n", err)
+		} else {
+			adminAudit = audit
+		}
+	}
+
 	if config.Statsd.Forwarder != "" {
 		var err error
 		statsdClient, err = outputs.NewStatsdClient("StatsD", config, stats)
@@ -128,6 +186,19 @@ func init() {
 		}
 	}
 
+	initOutputs(config)
+
+	log.Printf("[INFO]  : Falco Sidekick version: %s\n", GetVersionInfo().GitVersion)
+	log.Printf("[INFO]  : Enabled Outputs : %s\n", outputs.EnabledOutputs)
+	if clientAuthenticator != nil {
+		log.Printf("[INFO]  : Authentication enabled for %v client(s)\n", len(clientAuthenticator.clients))
+	}
+
+}
+
+// initOutputs creates the clients of the outputs enabled in the configuration, it's also called by the admin API
+// to add outputs at runtime, with a configuration where only their section is set.
+func initOutputs(config *types.Configuration) {
 	if config.Slack.WebhookURL != "" {
 		var err error
 		slackClient, err = outputs.NewClient("Slack", config.Slack.WebhookURL, config.Slack.MutualTLS, config.Slack.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
@@ -312,7 +383,7 @@ func init() {
 				outputs.EnabledOutputs = append(outputs.EnabledOutputs, "AWSKinesis")
 			}
 			if config.AWS.SecurityLake.Bucket != "" && config.AWS.SecurityLake.Region != "" && config.AWS.SecurityLake.AccountID != "" && config.AWS.SecurityLake.Prefix != "" {
-				config.AWS.SecurityLake.Ctx = context.Background()
+				config.AWS.SecurityLake.Ctx = outputsContext
 				config.AWS.SecurityLake.ReadOffset, config.AWS.SecurityLake.WriteOffset = new(memlog.Offset), new(memlog.Offset)
 				config.AWS.SecurityLake.Memlog, err = memlog.New(config.AWS.SecurityLake.Ctx, memlog.WithMaxSegmentSize(10000))
 				if config.AWS.SecurityLake.Interval < 5 {
@@ -730,9 +801,15 @@ func init() {
 		}
 	}
 
-	log.Printf("[INFO]  : Falco Sidekick version: %s\n", GetVersionInfo().GitVersion)
-	log.Printf("[INFO]  : Enabled Outputs : %s\n", outputs.EnabledOutputs)
-
+	if config.Forward.Address != "" {
+		var err error
+		forwardClient, err = outputs.NewForwardClient(config, stats, promStats, statsdClient, dogstatsdClient, GetVersionInfo().GitVersion)
+		if err != nil {
+			config.Forward.Address = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Forward")
+		}
+	}
 }
 
 func main() {
@@ -740,12 +817,67 @@ func main() {
 		log.Printf("[INFO]  : Debug mode : %v", config.Debug)
 	}
 
+	// the inputs stop on SIGINT or SIGTERM, the events in flight are still sent to the outputs during the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if replay != nil {
+		code := runReplayCommand(ctx)
+		stop()
+		os.Exit(code)
+	}
+
 	routes := map[string]http.Handler{
-		"/":        http.HandlerFunc(mainHandler),
-		"/ping":    http.HandlerFunc(pingHandler),
-		"/healthz": http.HandlerFunc(healthHandler),
-		"/test":    http.HandlerFunc(testHandler),
-		"/metrics": promhttp.Handler(),
+		"/":            withTracing("/", withAuth(permissionIngest, http.HandlerFunc(mainHandler))),
+		"/ping":        http.HandlerFunc(pingHandler),
+		"/healthz":     http.HandlerFunc(healthHandler),
+		"/test":        withTracing("/test", withAuth(permissionTest, http.HandlerFunc(testHandler))),
+		"/metrics":     promhttp.Handler(),
+		"/cloudevents": withTracing("/cloudevents", withAuth(permissionIngest, http.HandlerFunc(cloudEventsHandler))),
+		"/forward":     withTracing("/forward", withAuth(permissionIngest, http.HandlerFunc(forwardHandler))),
+		"/stats":       withAuth(permissionAdmin, http.HandlerFunc(statsHandler)),
+	}
+	// the admin API changes the outputs and the other admin endpoints expose the events, they're only served to the
+	// authenticated clients
+	if clientAuthenticator != nil {
+		routes["/stream"] = withAuth(permissionAdmin, http.HandlerFunc(streamHandler))
+		if eventJournal != nil {
+			routes["/events"] = withAuth(permissionAdmin, http.HandlerFunc(journalHandler))
+			routes["/events/"] = withAuth(permissionAdmin, http.HandlerFunc(journalHandler))
+		}
+		if requestCapturer != nil {
+			routes["/captures"] = withAuth(permissionAdmin, http.HandlerFunc(capturesHandler))
+		}
+		routes["/outputs"] = withAuth(permissionAdmin, http.HandlerFunc(outputsHandler))
+		routes["/outputs/"] = withAuth(permissionAdmin, http.HandlerFunc(outputsHandler))
+	}
+
+	go rates.run(ctx)
+	if config.Secrets.RefreshInterval > 0 && len(configSecrets.list) != 0 {
+		go configSecrets.run(ctx, time.Duration(config.Secrets.RefreshInterval)*time.Second)
+	}
+
+	if config.Inputs.Kafka.HostPort != "" && len(config.Inputs.Kafka.TopicsList) != 0 {
+		reader, err := newKafkaInputReader(config)
+		if err != nil {
+			log.Printf("[ERROR] : Kafka Input - %v\n", err)
+		} else {
+			log.Printf("[INFO]  : Kafka Input - Consuming topics %v as group '%v'\n", config.Inputs.Kafka.TopicsList, config.Inputs.Kafka.GroupID)
+			kafkaInputDone = make(chan struct{})
+			go func() {
+				defer close(kafkaInputDone)
+				consumeKafka(ctx, reader, config.Inputs.Kafka.MaxInFlight)
+			}()
+		}
+	}
+
+	if config.Inputs.Nats.HostPort != "" && len(config.Inputs.Nats.SubjectsList) != 0 {
+		var err error
+		if natsInput, err = subscribeNats(config); err != nil {
+			log.Printf("[ERROR] : NATS Input - %v\n", err)
+		} else {
+			log.Printf("[INFO]  : NATS Input - Subscribed to subjects %v as queue group '%v' (JetStream: %v)\n", config.Inputs.Nats.SubjectsList, config.Inputs.Nats.QueueGroup, config.Inputs.Nats.JetStream)
+		}
 	}
 
 	mainServeMux := http.NewServeMux()
@@ -773,6 +905,9 @@ func main() {
 		mainServeMux.Handle(r, handler)
 	}
 
+	// the requests are canceled at the shutdown, the long-lived ones like /stream would block it otherwise
+	baseContext := func(net.Listener) context.Context { return ctx }
+
 	server := &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", config.ListenAddress, config.ListenPort),
 		Handler: mainServeMux,
@@ -781,7 +916,10 @@ func main() {
 		ReadHeaderTimeout: 60 * time.Second,
 		WriteTimeout:      60 * time.Second,
 		IdleTimeout:       60 * time.Second,
+		BaseContext:       baseContext,
 	}
+	servers := []*http.Server{server}
+	errs := make(chan error, 2)
 
 	if config.TLSServer.Deploy {
 		if config.TLSServer.MutualTLS {
@@ -820,19 +958,16 @@ func main() {
 				ReadHeaderTimeout: 60 * time.Second,
 				WriteTimeout:      60 * time.Second,
 				IdleTimeout:       60 * time.Second,
+				BaseContext:       baseContext,
 			}
 			log.Printf("[INFO] : Falco Sidekick is up and listening on %s:%d and %s:%d", config.ListenAddress, config.ListenPort, config.ListenAddress, config.TLSServer.NoTLSPort)
 
-			errs := make(chan error, 1)
-			go serveTLS(server, errs)
+			servers = append(servers, httpServer)
 			go serveHTTP(httpServer, errs)
-			log.Fatal(<-errs)
 		} else {
 			log.Printf("[INFO] : Falco Sidekick is up and listening on %s:%d", config.ListenAddress, config.ListenPort)
-			if err := server.ListenAndServeTLS(config.TLSServer.CertFile, config.TLSServer.KeyFile); err != nil {
-				log.Fatalf("[ERROR] : %v", err.Error())
-			}
 		}
+		go serveTLS(server, errs)
 	} else {
 		if config.Debug {
 			log.Printf("[DEBUG] : running HTTP server")
@@ -847,9 +982,15 @@ func main() {
 		}
 
 		log.Printf("[INFO] : Falco Sidekick is up and listening on %s:%d", config.ListenAddress, config.ListenPort)
-		if err := server.ListenAndServe(); err != nil {
-			log.Fatalf("[ERROR] : %v", err.Error())
-		}
+		go serveHTTP(server, errs)
+	}
+
+	select {
+	case err := <-errs:
+		log.Fatalf("[ERROR] : %v", err.Error())
+	case <-ctx.Done():
+		stop()
+		shutdown(servers...)
 	}
 }
 
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// meter creates the instruments of falcosidekick, their measurements are dropped unless the OTLP export is enabled
+var meter = otel.Meter("github.com/falcosecurity/falcosidekick")
+
+// the instruments matching the Prometheus metrics of the deliveries
+var (
+	otlpOutputLatency, _      = meter.Float64Histogram("falcosidekick.outputs.latency", metric.WithUnit("s"))
+	otlpOutputSendDuration, _ = meter.Float64Histogram("falcosidekick.outputs.send_duration", metric.WithUnit("s"))
+	otlpOutputsInFlight, _    = meter.Int64UpDownCounter("falcosidekick.outputs.in_flight")
+	otlpOutputErrors, _       = meter.Int64Counter("falcosidekick.outputs.errors")
+)
+
+// meterProvider exports the metrics to the collector, it's nil if the export is disabled
+var meterProvider *sdkmetric.MeterProvider
+
+// initOTLPMetrics exports the metrics to the OTLP collector of otlpmetrics.endpoint, every otlpmetrics.interval
+// seconds.
+func initOTLPMetrics(config *types.Configuration) error {
+	if config.OTLPMetrics.Endpoint == "" {
+		return nil
+	}
+
+	endpoint, err := url.Parse(config.OTLPMetrics.Endpoint)
+	if err != nil {
+		return err
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return fmt.Errorf("unsupported endpoint scheme '%v'", endpoint.Scheme)
+	}
+	temporality := sdkmetric.DefaultTemporalitySelector
+	if config.OTLPMetrics.Temporality == "delta" {
+		temporality = deltaTemporality
+	}
+
+	var exporter sdkmetric.Exporter
+	if config.OTLPMetrics.Protocol == "grpc" {
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint.Host), otlpmetricgrpc.WithTemporalitySelector(temporality)}
+		if endpoint.Scheme == "http" {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		exporter, err = otlpmetricgrpc.New(context.Background(), opts...)
+	} else {
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint.Host), otlpmetrichttp.WithTemporalitySelector(temporality)}
+		if endpoint.Scheme == "http" {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if p := strings.TrimSuffix(endpoint.Path, "/"); p != "" {
+			opts = append(opts, otlpmetrichttp.WithURLPath(p+"/v1/metrics"))
+		}
+		exporter, err = otlpmetrichttp.New(context.Background(), opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, metricsResourceAttributes(config)...))
+	if err != nil {
+		return err
+	}
+
+	meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(time.Duration(config.OTLPMetrics.Interval)*time.Second))),
+	)
+	otel.SetMeterProvider(meterProvider)
+	return nil
+}
+
+// metricsResourceAttributes describes the instance of falcosidekick, the attributes of otlpmetrics.resourceattributes
+// are added to the ones of the service.
+func metricsResourceAttributes(config *types.Configuration) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		semconv.ServiceName(config.OTLPMetrics.ServiceName),
+		semconv.ServiceVersion(GetVersionInfo().GitVersion),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attributes = append(attributes, semconv.ServiceInstanceID(hostname), semconv.HostName(hostname))
+	}
+	for i, j := range config.OTLPMetrics.ResourceAttributesList {
+		attributes = append(attributes, attribute.String(i, j))
+	}
+	return attributes
+}
+
+// deltaTemporality exports the counters and the histograms as deltas, the up-down counters stay cumulative as the
+// collectors expect.
+func deltaTemporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case sdkmetric.InstrumentKindUpDownCounter, sdkmetric.InstrumentKindObservableUpDownCounter:
+		return metricdata.CumulativeTemporality
+	default:
+		return metricdata.DeltaTemporality
+	}
+}
+
+// shutdownOTLPMetrics exports the last metrics to the collector
+func shutdownOTLPMetrics(ctx context.Context) error {
+	if meterProvider == nil {
+		return nil
+	}
+	return meterProvider.Shutdown(ctx)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric/noop"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// otlpMetricsCollector is a stand-in of an OTLP/HTTP collector, it keeps the metrics it receives
+type otlpMetricsCollector struct {
+	resources []*metricspb.ResourceMetrics
+	sync.Mutex
+}
+
+func (c *otlpMetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/metrics" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var req collectormetrics.ExportMetricsServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.Lock()
+	c.resources = append(c.resources, req.ResourceMetrics...)
+	c.Unlock()
+	w.Header().Set("Content-Type", "application/x-protobuf")
+}
+
+func TestOTLPMetrics(t *testing.T) {
+	collector := new(otlpMetricsCollector)
+	ts := httptest.NewServer(collector)
+	defer ts.Close()
+
+	config = &types.Configuration{OTLPMetrics: types.OTLPMetricsConfig{
+		Endpoint:               ts.URL,
+		Protocol:               "http/protobuf",
+		Temporality:            "delta",
+		Interval:               60,
+		ServiceName:            "falcosidekick",
+		ResourceAttributesList: map[string]string{"k8s.cluster.name": "edge"},
+	}}
+	require.Nil(t, initOTLPMetrics(config))
+	defer func() {
+		meterProvider = nil
+		otel.SetMeterProvider(noop.NewMeterProvider())
+	}()
+
+	promStats = &types.PromStatistics{
+		OutputLatency:      prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_latency"}, []string{"destination"}),
+		OutputSendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_send_duration"}, []string{"destination", "status"}),
+		OutputErrors:       prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors"}, []string{"destination", "class"}),
+	}
+	client := &outputs.Client{Config: config}
+	client.CountMetric("falco.accepted", 1, []string{"priority:Critical"})
+	client.CountMetric("falco.accepted", 2, []string{"priority:Critical"})
+	observeDelivery("slack", types.FalcoPayload{Time: time.Now()}, time.Now(), outputs.ErrTooManyRequest)
+
+	require.Nil(t, shutdownOTLPMetrics(context.Background()))
+
+	collector.Lock()
+	defer collector.Unlock()
+	require.Len(t, collector.resources, 1)
+	resource := make(map[string]string)
+	for _, i := range collector.resources[0].Resource.Attributes {
+		resource[i.Key] = i.Value.GetStringValue()
+	}
+	require.Equal(t, "falcosidekick", resource["service.name"])
+	require.Equal(t, "edge", resource["k8s.cluster.name"])
+	require.NotEmpty(t, resource["service.instance.id"])
+
+	metrics := make(map[string]*metricspb.Metric)
+	for _, i := range collector.resources[0].ScopeMetrics {
+		for _, j := range i.Metrics {
+			metrics[j.Name] = j
+		}
+	}
+
+	accepted := metrics["falcosidekick.falco.accepted"].GetSum()
+	require.NotNil(t, accepted)
+	require.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, accepted.AggregationTemporality)
+	require.Len(t, accepted.DataPoints, 1)
+	require.Equal(t, int64(3), accepted.DataPoints[0].GetAsInt())
+	require.Equal(t, "priority", accepted.DataPoints[0].Attributes[0].Key)
+	require.Equal(t, "Critical", accepted.DataPoints[0].Attributes[0].Value.GetStringValue())
+
+	errors := metrics["falcosidekick.outputs.errors"].GetSum()
+	require.NotNil(t, errors)
+	attributes := make(map[string]string)
+	for _, i := range errors.DataPoints[0].Attributes {
+		attributes[i.Key] = i.Value.GetStringValue()
+	}
+	require.Equal(t, map[string]string{"destination": "slack", "class": outputs.ClientErrorClass}, attributes)
+
+	duration := metrics["falcosidekick.outputs.send_duration"].GetHistogram()
+	require.NotNil(t, duration)
+	require.Equal(t, uint64(1), duration.DataPoints[0].Count)
+}
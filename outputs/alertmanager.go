@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"strconv"
@@ -118,19 +119,20 @@ func newAlertmanagerPayload(falcopayload types.FalcoPayload, config *types.Confi
 }
 
 // AlertmanagerPost posts event to AlertManager
-func (c *Client) AlertmanagerPost(falcopayload types.FalcoPayload) {
+func (c *Client) AlertmanagerPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Alertmanager.Add(Total, 1)
 
-	err := c.Post(newAlertmanagerPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newAlertmanagerPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:alertmanager", "status:error"})
 		c.Stats.Alertmanager.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "alertmanager", "status": Error}).Inc()
 		log.Printf("[ERROR] : AlertManager - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:alertmanager", "status:ok"})
 	c.Stats.Alertmanager.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "alertmanager", "status": OK}).Inc()
+	return nil
 }
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -27,6 +28,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/google/uuid"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -118,7 +120,7 @@ func NewAWSClient(config *types.Configuration, stats *types.Statistics, promStat
 }
 
 // InvokeLambda invokes a lambda function
-func (c *Client) InvokeLambda(falcopayload types.FalcoPayload) {
+func (c *Client) InvokeLambda(ctx context.Context, falcopayload types.FalcoPayload) error {
 	svc := lambda.New(c.AWSSession)
 
 	f, _ := json.Marshal(falcopayload)
@@ -132,13 +134,13 @@ func (c *Client) InvokeLambda(falcopayload types.FalcoPayload) {
 
 	c.Stats.AWSLambda.Add("total", 1)
 
-	resp, err := svc.Invoke(input)
+	resp, err := svc.InvokeWithContext(ctx, input)
 	if err != nil {
 		go c.CountMetric("outputs", 1, []string{"output:awslambda", "status:error"})
 		c.Stats.AWSLambda.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awslambda", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v Lambda - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	if c.Config.Debug {
@@ -146,14 +148,15 @@ func (c *Client) InvokeLambda(falcopayload types.FalcoPayload) {
 		log.Printf("[DEBUG] : %v Lambda result : %v\n", c.OutputType, string(r))
 	}
 
-	log.Printf("[INFO]  : %v Lambda - Invoke OK (%v)\n", c.OutputType, *resp.StatusCode)
+	logger.ForOutput(c.OutputType+" Lambda").WithContext(ctx).InfoLimited("Invoke OK", logger.F(logger.StatusKey, *resp.StatusCode))
 	go c.CountMetric("outputs", 1, []string{"output:awslambda", "status:ok"})
 	c.Stats.AWSLambda.Add("ok", 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awslambda", "status": "ok"}).Inc()
+	return nil
 }
 
 // SendMessage sends a message to SQS Queue
-func (c *Client) SendMessage(falcopayload types.FalcoPayload) {
+func (c *Client) SendMessage(ctx context.Context, falcopayload types.FalcoPayload) error {
 	svc := sqs.New(c.AWSSession)
 
 	f, _ := json.Marshal(falcopayload)
@@ -165,27 +168,28 @@ func (c *Client) SendMessage(falcopayload types.FalcoPayload) {
 
 	c.Stats.AWSSQS.Add("total", 1)
 
-	resp, err := svc.SendMessage(input)
+	resp, err := svc.SendMessageWithContext(ctx, input)
 	if err != nil {
 		go c.CountMetric("outputs", 1, []string{"output:awssqs", "status:error"})
 		c.Stats.AWSSQS.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awssqs", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v SQS - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	if c.Config.Debug {
 		log.Printf("[DEBUG] : %v SQS - MD5OfMessageBody : %v\n", c.OutputType, *resp.MD5OfMessageBody)
 	}
 
-	log.Printf("[INFO]  : %v SQS - Send Message OK (%v)\n", c.OutputType, *resp.MessageId)
+	logger.ForOutput(c.OutputType+" SQS").WithContext(ctx).InfoLimited("Send Message OK", logger.F("message_id", *resp.MessageId))
 	go c.CountMetric("outputs", 1, []string{"output:awssqs", "status:ok"})
 	c.Stats.AWSSQS.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awssqs", "status": "ok"}).Inc()
+	return nil
 }
 
 // UploadS3 upload payload to S3
-func (c *Client) UploadS3(falcopayload types.FalcoPayload) {
+func (c *Client) UploadS3(ctx context.Context, falcopayload types.FalcoPayload) error {
 	f, _ := json.Marshal(falcopayload)
 
 	prefix := ""
@@ -195,7 +199,7 @@ func (c *Client) UploadS3(falcopayload types.FalcoPayload) {
 	}
 
 	key := fmt.Sprintf("%s/%s/%s.json", prefix, t.Format("2006-01-02"), t.Format(time.RFC3339Nano))
-	resp, err := s3.New(c.AWSSession).PutObject(&s3.PutObjectInput{
+	resp, err := s3.New(c.AWSSession).PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(c.Config.AWS.S3.Bucket),
 		Key:    aws.String(key),
 		Body:   bytes.NewReader(f),
@@ -205,21 +209,22 @@ func (c *Client) UploadS3(falcopayload types.FalcoPayload) {
 		go c.CountMetric("outputs", 1, []string{"output:awss3", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "awss3", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v S3 - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
+	var fields []logger.Field
 	if resp.SSECustomerAlgorithm != nil {
-		log.Printf("[INFO]  : %v S3 - Upload payload OK (%v)\n", c.OutputType, *resp.SSECustomerKeyMD5)
-	} else {
-		log.Printf("[INFO]  : %v S3 - Upload payload OK\n", c.OutputType)
+		fields = append(fields, logger.F("sse_customer_key_md5", *resp.SSECustomerKeyMD5))
 	}
+	logger.ForOutput(c.OutputType+" S3").WithContext(ctx).InfoLimited("Upload payload OK", fields...)
 
 	go c.CountMetric("outputs", 1, []string{"output:awss3", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "awss3", "status": "ok"}).Inc()
+	return nil
 }
 
 // PublishTopic sends a message to a SNS Topic
-func (c *Client) PublishTopic(falcopayload types.FalcoPayload) {
+func (c *Client) PublishTopic(ctx context.Context, falcopayload types.FalcoPayload) error {
 	svc := sns.New(c.AWSSession)
 
 	var msg *sns.PublishInput
@@ -287,23 +292,24 @@ func (c *Client) PublishTopic(falcopayload types.FalcoPayload) {
 	}
 
 	c.Stats.AWSSNS.Add("total", 1)
-	resp, err := svc.Publish(msg)
+	resp, err := svc.PublishWithContext(ctx, msg)
 	if err != nil {
 		go c.CountMetric("outputs", 1, []string{"output:awssns", "status:error"})
 		c.Stats.AWSSNS.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awssns", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v SNS - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : %v SNS - Send to topic OK (%v)\n", c.OutputType, *resp.MessageId)
+	logger.ForOutput(c.OutputType+" SNS").WithContext(ctx).InfoLimited("Send to topic OK", logger.F("message_id", *resp.MessageId))
 	go c.CountMetric("outputs", 1, []string{"output:awssns", "status:ok"})
 	c.Stats.AWSSNS.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awssns", "status": OK}).Inc()
+	return nil
 }
 
 // SendCloudWatchLog sends a message to CloudWatch Log
-func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
+func (c *Client) SendCloudWatchLog(ctx context.Context, falcopayload types.FalcoPayload) error {
 	svc := cloudwatchlogs.New(c.AWSSession)
 
 	f, _ := json.Marshal(falcopayload)
@@ -318,7 +324,7 @@ func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
 			LogStreamName: aws.String(streamName),
 		}
 
-		_, err := svc.CreateLogStream(inputLogStream)
+		_, err := svc.CreateLogStreamWithContext(ctx, inputLogStream)
 		if err != nil {
 			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == cloudwatchlogs.ErrCodeResourceAlreadyExistsException {
 				log.Printf("[INFO]  : %v CloudWatchLogs - Log Stream %s already exist, reusing...\n", c.OutputType, streamName)
@@ -327,7 +333,7 @@ func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
 				c.Stats.AWSCloudWatchLogs.Add(Error, 1)
 				c.PromStats.Outputs.With(map[string]string{"destination": "awscloudwatchlogs", "status": Error}).Inc()
 				log.Printf("[ERROR] : %v CloudWatchLogs - %v\n", c.OutputType, err.Error())
-				return
+				return err
 			}
 		}
 
@@ -346,30 +352,31 @@ func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
 	}
 
 	var err error
-	resp, err := c.putLogEvents(svc, input)
+	resp, err := c.putLogEvents(ctx, svc, input)
 	if err != nil {
 		go c.CountMetric("outputs", 1, []string{"output:awscloudwatchlogs", "status:error"})
 		c.Stats.AWSCloudWatchLogs.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awscloudwatchlogs", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v CloudWatchLogs - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : %v CloudWatchLogs - Send Log OK (%v)\n", c.OutputType, resp.String())
+	logger.ForOutput(c.OutputType+" CloudWatchLogs").WithContext(ctx).InfoLimited("Send Log OK", logger.F("response", resp.String()))
 	go c.CountMetric("outputs", 1, []string{"output:awscloudwatchlogs", "status:ok"})
 	c.Stats.AWSCloudWatchLogs.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awscloudwatchlogs", "status": OK}).Inc()
+	return nil
 }
 
 // PutLogEvents will attempt to execute and handle invalid tokens.
-func (c *Client) putLogEvents(svc *cloudwatchlogs.CloudWatchLogs, input *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
-	resp, err := svc.PutLogEvents(input)
+func (c *Client) putLogEvents(ctx context.Context, svc *cloudwatchlogs.CloudWatchLogs, input *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	resp, err := svc.PutLogEventsWithContext(ctx, input)
 	if err != nil {
 		if exception, ok := err.(*cloudwatchlogs.InvalidSequenceTokenException); ok {
 			log.Printf("[INFO]  : %v Refreshing token for LogGroup: %s LogStream: %s", c.OutputType, *input.LogGroupName, *input.LogStreamName)
 			input.SequenceToken = exception.ExpectedSequenceToken
 
-			return c.putLogEvents(svc, input)
+			return c.putLogEvents(ctx, svc, input)
 		}
 
 		return nil, err
@@ -379,7 +386,7 @@ func (c *Client) putLogEvents(svc *cloudwatchlogs.CloudWatchLogs, input *cloudwa
 }
 
 // PutRecord puts a record in Kinesis
-func (c *Client) PutRecord(falcoPayLoad types.FalcoPayload) {
+func (c *Client) PutRecord(ctx context.Context, falcoPayLoad types.FalcoPayload) error {
 	svc := kinesis.New(c.AWSSession)
 
 	c.Stats.AWSKinesis.Add(Total, 1)
@@ -391,17 +398,18 @@ func (c *Client) PutRecord(falcoPayLoad types.FalcoPayload) {
 		StreamName:   aws.String(c.Config.AWS.Kinesis.StreamName),
 	}
 
-	resp, err := svc.PutRecord(input)
+	resp, err := svc.PutRecordWithContext(ctx, input)
 	if err != nil {
 		go c.CountMetric("outputs", 1, []string{"output:awskinesis", "status:error"})
 		c.Stats.AWSKinesis.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awskinesis", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v Kinesis - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	log.Printf("[INFO] : %v Kinesis - Put Record OK (%v)\n", c.OutputType, resp.SequenceNumber)
 	go c.CountMetric("outputs", 1, []string{"output:awskinesis", "status:ok"})
 	c.Stats.AWSKinesis.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awskinesis", "status": "ok"}).Inc()
+	return nil
 }
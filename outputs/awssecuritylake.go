@@ -195,27 +195,33 @@ func getAWSSecurityLakeSeverity(priority types.PriorityType) (int32, string) {
 // 	return ocsfa
 // }
 
-func (c *Client) EnqueueSecurityLake(falcopayload types.FalcoPayload) {
-	offset, err := c.Config.AWS.SecurityLake.Memlog.Write(c.Config.AWS.SecurityLake.Ctx, []byte(falcopayload.String()))
+func (c *Client) EnqueueSecurityLake(ctx context.Context, falcopayload types.FalcoPayload) error {
+	offset, err := c.Config.AWS.SecurityLake.Memlog.Write(ctx, []byte(falcopayload.String()))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:awssecuritylake.", "status:error"})
 		c.Stats.AWSSecurityLake.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awssecuritylake.", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v SecurityLake - %v\n", c.OutputType, err)
-		return
+		return err
 	}
 	log.Printf("[INFO]  : %v SecurityLake - Event queued (%v)\n", c.OutputType, falcopayload.UUID)
 	*c.Config.AWS.SecurityLake.WriteOffset = offset
+	return nil
 }
 
 func (c *Client) StartSecurityLakeWorker() {
+	ctx := c.Config.AWS.SecurityLake.Ctx
 	for {
 		if err := c.processNextBatch(); errors.Is(err, memlog.ErrOutOfRange) {
 			// don't sleep if we're too slow reading
 			continue
 		}
 
-		time.Sleep(time.Duration(c.Config.AWS.SecurityLake.Interval) * time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(c.Config.AWS.SecurityLake.Interval) * time.Minute):
+		}
 	}
 }
 
@@ -232,8 +238,6 @@ func (c *Client) processNextBatch() error {
 			c.Stats.AWSSecurityLake.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "awssecuritylake.", "status": Error}).Inc()
 			log.Printf("[ERROR] : %v SecurityLake - %v\n", c.OutputType, err)
-			// ctx currently not handled in main
-			// https://github.com/falcosecurity/falcosidekick/pull/390#discussion_r1081690326
 			return err
 		}
 
@@ -268,7 +272,7 @@ func (c *Client) processNextBatch() error {
 	if count > 0 {
 		uid := uuid.New().String()
 
-		if err := c.writeParquet(uid, batch[:count]); err != nil {
+		if err := c.writeParquet(ctx, uid, batch[:count]); err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:awssecuritylake.", "status:error"})
 			c.Stats.AWSSecurityLake.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "awssecuritylake.", "status": Error}).Inc()
@@ -287,11 +291,11 @@ func (c *Client) processNextBatch() error {
 	return nil
 }
 
-func (c *Client) writeParquet(uid string, records []memlog.Record) error {
+func (c *Client) writeParquet(ctx context.Context, uid string, records []memlog.Record) error {
 	fw, err := mem.NewMemFileWriter(uid+".parquet", func(name string, r io.Reader) error {
 		t := time.Now()
 		key := fmt.Sprintf("/%s/region=%s/accountId=%s/eventDay=%s/%s.parquet", c.Config.AWS.SecurityLake.Prefix, c.Config.AWS.SecurityLake.Region, c.Config.AWS.SecurityLake.AccountID, t.Format("20060102"), uid)
-		ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancelFn := context.WithTimeout(ctx, 10*time.Second)
 		defer cancelFn()
 		resp, err := s3.New(c.AWSSession).PutObjectWithContext(ctx, &s3.PutObjectInput{
 			Bucket:      aws.String(c.Config.AWS.SecurityLake.Bucket),
@@ -4,11 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"log"
-	"time"
 
 	eventhub "github.com/Azure/azure-event-hubs-go/v3"
 	"github.com/DataDog/datadog-go/statsd"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -25,7 +25,7 @@ func NewEventHubClient(config *types.Configuration, stats *types.Statistics, pro
 }
 
 // EventHubPost posts event to Azure Event Hub
-func (c *Client) EventHubPost(falcopayload types.FalcoPayload) {
+func (c *Client) EventHubPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.AzureEventHub.Add(Total, 1)
 
 	log.Printf("[INFO] : %v EventHub - Try sending event", c.OutputType)
@@ -33,7 +33,7 @@ func (c *Client) EventHubPost(falcopayload types.FalcoPayload) {
 	if err != nil {
 		c.setEventHubErrorMetrics()
 		log.Printf("[ERROR] : %v EventHub - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : %v EventHub - Hub client created\n", c.OutputType)
@@ -42,24 +42,22 @@ func (c *Client) EventHubPost(falcopayload types.FalcoPayload) {
 	if err != nil {
 		c.setEventHubErrorMetrics()
 		log.Printf("[ERROR] : Cannot marshal payload: %v", err.Error())
-		return
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-
 	err = hub.Send(ctx, eventhub.NewEvent(data))
 	if err != nil {
 		c.setEventHubErrorMetrics()
 		log.Printf("[ERROR] : %v EventHub - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:azureeventhub", "status:ok"})
 	c.Stats.AzureEventHub.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "azureeventhub", "status": OK}).Inc()
-	log.Printf("[INFO]  : %v EventHub - Publish OK", c.OutputType)
+	logger.ForOutput(c.OutputType + " EventHub").WithContext(ctx).InfoLimited("Publish OK")
+	return nil
 }
 
 // setEventHubErrorMetrics set the error stats
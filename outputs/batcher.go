@@ -0,0 +1,211 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/falcosecurity/falcosidekick/logger"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// batch is a group of events sent with a single request, all its events get the result of the request
+type batch struct {
+	events []types.FalcoPayload
+	bytes  int
+	// links are the spans of the deliveries of the events, the span of the batch refers to them
+	links []trace.Link
+	// cancelled are the indexes of the events left out of the batch before it's sent, their posts are cancelled
+	cancelled map[int]bool
+	done      chan struct{}
+	err       error
+}
+
+// batcher groups the events of an output to send them with a single request, a batch is sent once it reaches its
+// maximum number of events or bytes, or at the flush interval.
+type batcher struct {
+	client  *Client
+	output  string
+	config  types.BatchConfig
+	send    func(ctx context.Context, events []types.FalcoPayload) error
+	current *batch
+	// closed is set once the client is closed, the events are then sent without waiting for the batch to be full
+	closed bool
+	stop   chan struct{}
+	sync.Mutex
+}
+
+var (
+	batchers     []*batcher
+	batchersLock sync.Mutex
+)
+
+// getBatcher returns the batcher of the output, it's created with the first event.
+func (c *Client) getBatcher(output string, config types.BatchConfig, send func(ctx context.Context, events []types.FalcoPayload) error) *batcher {
+	c.batcherOnce.Do(func() {
+		batchersLock.Lock()
+		defer batchersLock.Unlock()
+		c.batcher = &batcher{client: c, output: output, config: config, send: send, closed: c.closed, stop: make(chan struct{})}
+		if !c.closed {
+			batchers = append(batchers, c.batcher)
+			go c.batcher.flushPeriodically()
+		}
+	})
+	return c.batcher
+}
+
+// add adds the event to the current batch and waits for the batch to be sent, the error of its request is returned.
+// The wait isn't bounded by the timeout of the post, but by the flush interval and the timeout of the batch, the
+// result is the one of the request. If ctx is cancelled before the batch is sent, the event is left out of it.
+func (b *batcher) add(ctx context.Context, falcopayload types.FalcoPayload) error {
+	var size int
+	if data, err := json.Marshal(falcopayload); err == nil {
+		size = len(data)
+	}
+
+	b.Lock()
+	if b.current != nil && b.config.MaxBytes > 0 && b.current.bytes+size > b.config.MaxBytes {
+		// the event doesn't fit in the current batch
+		b.flush()
+	}
+	if b.current == nil {
+		b.current = &batch{done: make(chan struct{})}
+	}
+	current := b.current
+	index := len(current.events)
+	current.events = append(current.events, falcopayload)
+	current.bytes += size
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		current.links = append(current.links, trace.Link{SpanContext: sc})
+	}
+	b.client.PromStats.OutputQueueDepth.With(map[string]string{"destination": b.output}).Inc()
+	if b.closed || len(current.events) >= b.config.MaxSize || (b.config.MaxBytes > 0 && current.bytes >= b.config.MaxBytes) {
+		b.flush()
+	}
+	b.Unlock()
+
+	select {
+	case <-current.done:
+		return current.err
+	case <-ctx.Done():
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		<-current.done
+		return current.err
+	}
+
+	b.Lock()
+	if b.current != current {
+		// the batch is being sent
+		b.Unlock()
+		<-current.done
+		return current.err
+	}
+	if current.cancelled == nil {
+		current.cancelled = make(map[int]bool)
+	}
+	current.cancelled[index] = true
+	current.bytes -= size
+	b.client.PromStats.OutputQueueDepth.With(map[string]string{"destination": b.output}).Dec()
+	b.Unlock()
+	return ctx.Err()
+}
+
+// flush sends the current batch in the background, the lock of the batcher must be held.
+func (b *batcher) flush() {
+	if b.current == nil {
+		return
+	}
+	go b.sendBatch(b.current)
+	b.current = nil
+}
+
+func (b *batcher) sendBatch(current *batch) {
+	events := current.events
+	if len(current.cancelled) != 0 {
+		events = make([]types.FalcoPayload, 0, len(current.events)-len(current.cancelled))
+		for i, j := range current.events {
+			if !current.cancelled[i] {
+				events = append(events, j)
+			}
+		}
+	}
+	if len(events) == 0 {
+		close(current.done)
+		return
+	}
+
+	// the batch gathers events from different contexts, it has its own one with the timeout of the output
+	ctx := context.Background()
+	if timeout := b.client.Timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ctx, span := tracer.Start(ctx, "batch "+b.client.OutputType, trace.WithLinks(current.links...),
+		trace.WithAttributes(attribute.Int("falcosidekick.batch.size", len(events))))
+	defer span.End()
+
+	start := time.Now()
+	current.err = b.send(ctx, events)
+	// the events get their result once the batch is counted
+	defer close(current.done)
+	b.client.PromStats.OutputQueueDepth.With(map[string]string{"destination": b.output}).Sub(float64(len(events)))
+
+	l := logger.ForOutput(b.client.OutputType).With(logger.F("events", len(events)), logger.Latency(start))
+	status := OK
+	if current.err != nil {
+		status = Error
+		l.Error("Batch not sent", logger.F(logger.ErrorKey, current.err))
+	} else {
+		l.Debug("Batch sent")
+	}
+	go b.client.CountMetric("outputs.batches", 1, []string{"output:" + b.output, "status:" + status})
+	b.client.Stats.Batches.Add(b.output+"."+status, 1)
+	b.client.PromStats.OutputBatches.With(map[string]string{"destination": b.output, "status": status}).Inc()
+	b.client.PromStats.OutputBatchSize.With(map[string]string{"destination": b.output}).Observe(float64(len(events)))
+	otlpBatchSize.Record(ctx, int64(len(events)), metric.WithAttributes(attribute.String("destination", b.output)))
+}
+
+func (b *batcher) flushPeriodically() {
+	ticker := time.NewTicker(time.Duration(b.config.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.Lock()
+			b.flush()
+			b.Unlock()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// close stops the periodic flushes and sends the current batch, the next events are sent right away.
+func (b *batcher) close() {
+	close(b.stop)
+	b.Lock()
+	b.closed = true
+	b.flush()
+	b.Unlock()
+}
+
+// FlushBatches sends the incomplete batches of all the outputs, without waiting for the flush interval. It's
+// called at the shutdown.
+func FlushBatches() {
+	batchersLock.Lock()
+	defer batchersLock.Unlock()
+	for _, i := range batchers {
+		i.Lock()
+		i.flush()
+		i.Unlock()
+	}
+}
@@ -0,0 +1,177 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func newBatcherTestClient() *Client {
+	return &Client{
+		OutputType: "Test",
+		Config:     &types.Configuration{HTTPClient: types.HTTPClientConfig{Timeout: 5}},
+		Stats:      &types.Statistics{Batches: new(expvar.Map).Init()},
+		PromStats: &types.PromStatistics{
+			OutputBatches:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_batches"}, []string{"destination", "status"}),
+			OutputBatchSize:  prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_batch_size"}, []string{"destination"}),
+			OutputQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_queue_depth"}, []string{"destination"}),
+		},
+	}
+}
+
+// addEvents adds the events concurrently and returns once all their batches are sent
+func addEvents(t *testing.T, b *batcher, count int) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		go func() {
+			errs <- b.add(context.Background(), f)
+		}()
+	}
+	for i := 0; i < count; i++ {
+		require.Nil(t, <-errs)
+	}
+}
+
+func TestBatcherMaxSize(t *testing.T) {
+	var lock sync.Mutex
+	var sizes []int
+	nc := newBatcherTestClient()
+	b := nc.getBatcher("test", types.BatchConfig{MaxSize: 5, FlushInterval: 3600}, func(_ context.Context, events []types.FalcoPayload) error {
+		lock.Lock()
+		defer lock.Unlock()
+		sizes = append(sizes, len(events))
+		return nil
+	})
+
+	addEvents(t, b, 10)
+	require.Equal(t, []int{5, 5}, sizes)
+	require.Equal(t, "2", nc.Stats.Batches.Get("test.ok").String())
+}
+
+func TestBatcherMaxBytes(t *testing.T) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	data, err := json.Marshal(f)
+	require.Nil(t, err)
+
+	var lock sync.Mutex
+	var sizes []int
+	nc := newBatcherTestClient()
+	// two events fit in a batch, not three
+	b := nc.getBatcher("test", types.BatchConfig{MaxSize: 100, MaxBytes: 3*len(data) - 1, FlushInterval: 3600}, func(_ context.Context, events []types.FalcoPayload) error {
+		lock.Lock()
+		defer lock.Unlock()
+		sizes = append(sizes, len(events))
+		return nil
+	})
+
+	go func() {
+		// the last event waits for the flush
+		time.Sleep(100 * time.Millisecond)
+		FlushBatches()
+	}()
+	addEvents(t, b, 5)
+	require.Equal(t, []int{2, 2, 1}, sizes)
+}
+
+func TestBatcherError(t *testing.T) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	nc := newBatcherTestClient()
+	b := nc.getBatcher("test", types.BatchConfig{MaxSize: 1, FlushInterval: 3600}, func(_ context.Context, _ []types.FalcoPayload) error {
+		return ErrClientCreation
+	})
+
+	require.Equal(t, ErrClientCreation, b.add(context.Background(), f))
+	require.Equal(t, "1", nc.Stats.Batches.Get("test.error").String())
+}
+
+func TestBatcherCancel(t *testing.T) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	sent := make(chan []types.FalcoPayload, 1)
+	nc := newBatcherTestClient()
+	b := nc.getBatcher("test", types.BatchConfig{MaxSize: 100, FlushInterval: 3600}, func(_ context.Context, events []types.FalcoPayload) error {
+		sent <- events
+		return nil
+	})
+
+	// the result of the batch is returned even if the timeout of the post is shorter than the flush
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+	timedOut := make(chan error, 1)
+	go func() { timedOut <- b.add(timeoutCtx, f) }()
+
+	// the cancelled event is left out of the batch
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	second := f
+	second.Rule = "Cancelled rule"
+	go func() { cancelled <- b.add(cancelledCtx, second) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	require.Equal(t, context.Canceled, <-cancelled)
+
+	FlushBatches()
+	require.Nil(t, <-timedOut)
+	events := <-sent
+	require.Len(t, events, 1)
+	require.Equal(t, "Test rule", events[0].Rule)
+}
+
+func TestBatcherClose(t *testing.T) {
+	var lock sync.Mutex
+	var sizes []int
+	nc := newBatcherTestClient()
+	b := nc.getBatcher("test", types.BatchConfig{MaxSize: 100, FlushInterval: 3600}, func(_ context.Context, events []types.FalcoPayload) error {
+		lock.Lock()
+		defer lock.Unlock()
+		sizes = append(sizes, len(events))
+		return nil
+	})
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		end := nc.BeginPost()
+		go func() {
+			defer end()
+			errs <- b.add(context.Background(), f)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		b.Lock()
+		defer b.Unlock()
+		return b.current != nil && len(b.current.events) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the current batch is sent without waiting for the flush interval, Close returns once the posts have ended
+	nc.Close(context.Background())
+	require.Nil(t, <-errs)
+	require.Nil(t, <-errs)
+	batchersLock.Lock()
+	for _, i := range batchers {
+		require.False(t, i == b)
+	}
+	batchersLock.Unlock()
+
+	// the next events aren't batched anymore
+	require.Nil(t, b.add(context.Background(), f))
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []int{2, 1}, sizes)
+}
@@ -3,8 +3,7 @@ package outputs
 import (
 	"bytes"
 	"compress/gzip"
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -16,6 +15,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	crdClient "github.com/kubernetes-sigs/wg-policy-prototypes/policy-report/kube-bench-adapter/pkg/generated/v1alpha2/clientset/versioned"
 
@@ -34,10 +34,19 @@ import (
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	timescaledb "github.com/jackc/pgx/v5/pgxpool"
 	redis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// tracer creates the spans of the requests to the outputs
+var tracer = otel.Tracer("github.com/falcosecurity/falcosidekick/outputs")
+
 // ErrHeaderMissing = 400
 var ErrHeaderMissing = errors.New("header missing")
 
@@ -70,6 +79,25 @@ var ErrSASLAuthCreation = errors.New("sasl auth: wrong mechanism")
 // EnabledOutputs list all enabled outputs
 var EnabledOutputs []string
 
+// CapturedRequest is a request of an output, rendered but not sent. The requests of the outputs which don't use
+// HTTP have no method, their URL is the one of their destination.
+type CapturedRequest struct {
+	Output  string
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    []byte
+}
+
+// Capture, if set, receives the requests of the outputs before they're sent. The requests it captures, when it
+// returns true, aren't sent and succeed. It's used to preview the payloads of the outputs.
+var Capture func(r CapturedRequest) bool
+
+// captured tells whether the request is captured, it mustn't be sent then
+func captured(r CapturedRequest) bool {
+	return Capture != nil && Capture(r)
+}
+
 // DefaultContentType is the default Content-Type header to send along with the Client's POST Request
 const DefaultContentType = "application/json; charset=utf-8"
 
@@ -88,19 +116,75 @@ const MutualTLSCacertFilename = "/ca.crt"
 const HttpPost = "POST"
 const HttpPut = "PUT"
 
-// Headers to add to the client before sending the request
+// Header is an HTTP header to add to a request
 type Header struct {
 	Key   string
 	Value string
 }
 
+// RequestOption sets an option of a single request, the options are never stored in the Client so an output can
+// send several requests concurrently.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	method          string
+	endpointURL     *url.URL
+	contentType     string
+	headers         []Header
+	responseHandler func(body []byte) error
+}
+
+// WithHeader adds an HTTP Header to the request.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.headers = append(o.headers, Header{Key: key, Value: value})
+	}
+}
+
+// WithBasicAuth adds an HTTP Basic Authentication compliant header to the request.
+func WithBasicAuth(username, password string) RequestOption {
+	// Check out RFC7617 for the specifics on this code.
+	// https://datatracker.ietf.org/doc/html/rfc7617
+	// This might break I18n, but we can cross that bridge when we come to it.
+	userPass := username + ":" + password
+	b64UserPass := base64.StdEncoding.EncodeToString([]byte(userPass))
+	return WithHeader(AuthorizationHeaderKey, "Basic "+b64UserPass)
+}
+
+// WithMethod sets the HTTP method of the request, POST by default.
+func WithMethod(method string) RequestOption {
+	return func(o *requestOptions) {
+		o.method = method
+	}
+}
+
+// WithContentType overrides the Content-Type of the Client for the request.
+func WithContentType(contentType string) RequestOption {
+	return func(o *requestOptions) {
+		o.contentType = contentType
+	}
+}
+
+// withResponseHandler checks the body of a successful response, for the APIs reporting errors in it.
+func withResponseHandler(handler func(body []byte) error) RequestOption {
+	return func(o *requestOptions) {
+		o.responseHandler = handler
+	}
+}
+
+// WithEndpointURL overrides the endpoint of the Client for the request.
+func WithEndpointURL(endpointURL *url.URL) RequestOption {
+	return func(o *requestOptions) {
+		o.endpointURL = endpointURL
+	}
+}
+
 // Client communicates with the different API.
 type Client struct {
 	OutputType              string
 	EndpointURL             *url.URL
 	MutualTLSEnabled        bool
 	CheckCert               bool
-	HeaderList              []Header
 	ContentType             string
 	Config                  *types.Configuration
 	Stats                   *types.Statistics
@@ -110,8 +194,6 @@ type Client struct {
 	DogstatsdClient         *statsd.Client
 	GCPTopicClient          *pubsub.Topic
 	GCPCloudFunctionsClient *gcpfunctions.CloudFunctionsClient
-	// FIXME: this lock requires a per-output usage lock currently if headers are used -- needs to be refactored
-	httpClientLock sync.Mutex
 
 	GCSStorageClient  *storage.Client
 	KafkaProducer     *kafka.Writer
@@ -123,6 +205,16 @@ type Client struct {
 	MQTTClient        mqtt.Client
 	TimescaleDBClient *timescaledb.Pool
 	RedisClient       *redis.Client
+
+	transport   httpTransport
+	forwarder   *forwarder
+	batcher     *batcher
+	batcherOnce sync.Once
+	// closed is set by Close, it's guarded by batchersLock
+	closed             bool
+	closeOnce          sync.Once
+	posts              sync.WaitGroup
+	rabbitmqConnection *amqp.Connection
 }
 
 // NewClient returns a new output.Client for accessing the different API.
@@ -141,25 +233,104 @@ func NewClient(outputType string, defaultEndpointURL string, mutualTLSEnabled bo
 		log.Printf("[ERROR] : %v - %v\n", outputType, err.Error())
 		return nil, ErrClientCreation
 	}
-	return &Client{OutputType: outputType, EndpointURL: endpointURL, MutualTLSEnabled: mutualTLSEnabled, CheckCert: checkCert, HeaderList: []Header{}, ContentType: DefaultContentType, Config: config, Stats: stats, PromStats: promStats, StatsdClient: statsdClient, DogstatsdClient: dogstatsdClient}, nil
+	return &Client{OutputType: outputType, EndpointURL: endpointURL, MutualTLSEnabled: mutualTLSEnabled, CheckCert: checkCert, ContentType: DefaultContentType, Config: config, Stats: stats, PromStats: promStats, StatsdClient: statsdClient, DogstatsdClient: dogstatsdClient}, nil
+}
+
+// BeginPost counts a post of the output, the returned function must be called once the post has ended. Close waits
+// for the posts counted to end before closing the connections.
+func (c *Client) BeginPost() func() {
+	c.posts.Add(1)
+	return c.posts.Done
+}
+
+// Close releases the resources of the output once it's removed or replaced, or at the shutdown: its current batch
+// is sent, the forwarder sends its spool until ctx is done, its goroutines are stopped and, once its posts have
+// ended, its connections are closed. No post must begin after it's called, it can be called several times.
+func (c *Client) Close(ctx context.Context) {
+	c.closeOnce.Do(func() {
+		batchersLock.Lock()
+		c.closed = true
+		b := c.batcher
+		for i, j := range batchers {
+			if j == b {
+				batchers = append(batchers[:i:i], batchers[i+1:]...)
+				break
+			}
+		}
+		batchersLock.Unlock()
+		if b != nil {
+			b.close()
+		}
+
+		c.posts.Wait()
+		if c.forwarder != nil {
+			c.closeForwarder(ctx)
+		}
+
+		if c.KafkaProducer != nil {
+			if err := c.KafkaProducer.Close(); err != nil {
+				log.Printf("[ERROR] : %v - %v\n", c.OutputType, err)
+			}
+		}
+		if c.RabbitmqClient != nil {
+			c.RabbitmqClient.Close()
+		}
+		if c.rabbitmqConnection != nil {
+			c.rabbitmqConnection.Close()
+		}
+		if c.MQTTClient != nil && c.MQTTClient.IsConnected() {
+			c.MQTTClient.Disconnect(100)
+		}
+		if c.RedisClient != nil {
+			c.RedisClient.Close()
+		}
+		if c.TimescaleDBClient != nil {
+			c.TimescaleDBClient.Close()
+		}
+		if c.GCPTopicClient != nil {
+			c.GCPTopicClient.Stop()
+		}
+		if c.GCPCloudFunctionsClient != nil {
+			c.GCPCloudFunctionsClient.Close()
+		}
+		if c.GCSStorageClient != nil {
+			c.GCSStorageClient.Close()
+		}
+		if c.WavefrontSender != nil {
+			(*c.WavefrontSender).Close()
+		}
+		c.transport.Lock()
+		if c.transport.client != nil {
+			c.transport.client.CloseIdleConnections()
+		}
+		c.transport.Unlock()
+	})
+}
+
+type statusCodeKey struct{}
+
+// WithStatusCode returns a context where the requests of the posts set code to the status code of their response
+func WithStatusCode(ctx context.Context, code *int) context.Context {
+	return context.WithValue(ctx, statusCodeKey{}, code)
 }
 
-// Post sends event (payload) to Output with POST http method.
-func (c *Client) Post(payload interface{}) error {
-	return c.sendRequest("POST", payload)
+// Post sends event (payload) to Output with POST http method, unless another one is set with WithMethod.
+func (c *Client) Post(ctx context.Context, payload interface{}, opts ...RequestOption) error {
+	return c.sendRequest(ctx, payload, opts...)
 }
 
 // Put sends event (payload) to Output with PUT http method.
-func (c *Client) Put(payload interface{}) error {
-	return c.sendRequest("PUT", payload)
+func (c *Client) Put(ctx context.Context, payload interface{}, opts ...RequestOption) error {
+	return c.sendRequest(ctx, payload, append(opts, WithMethod(HttpPut))...)
 }
 
-// Post sends event (payload) to Output.
-func (c *Client) sendRequest(method string, payload interface{}) error {
+// sendRequest sends event (payload) to Output, the options only apply to this request.
+func (c *Client) sendRequest(ctx context.Context, payload interface{}, opts ...RequestOption) (err error) {
 	// defer + recover to catch panic if output doesn't respond
 	defer func() {
-		if err := recover(); err != nil {
-			log.Printf("[ERROR] : %v - %s", c.OutputType, err)
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			log.Printf("[ERROR] : %v - %v\n", c.OutputType, err)
 		}
 	}()
 
@@ -170,6 +341,16 @@ func (c *Client) sendRequest(method string, payload interface{}) error {
 		if c.Config.Debug {
 			log.Printf("[DEBUG] : %v payload : %v\n", c.OutputType, body)
 		}
+	case elasticsearchBulkPayload:
+		body.Write(payload.(elasticsearchBulkPayload))
+		if c.Config.Debug {
+			log.Printf("[DEBUG] : %v payload : %v\n", c.OutputType, body)
+		}
+	case forwardBatch:
+		body.Write(payload.(forwardBatch))
+		if c.Config.Debug {
+			log.Printf("[DEBUG] : %v payload : batch of %v bytes\n", c.OutputType, body.Len())
+		}
 	case spyderbatPayload:
 		zipper := gzip.NewWriter(body)
 		if err := json.NewEncoder(zipper).Encode(payload); err != nil {
@@ -191,135 +372,129 @@ func (c *Client) sendRequest(method string, payload interface{}) error {
 		}
 	}
 
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
+	o := requestOptions{method: HttpPost, endpointURL: c.EndpointURL, contentType: c.ContentType}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-	if c.MutualTLSEnabled {
-		// Load client cert
-		var MutualTLSClientCertPath, MutualTLSClientKeyPath, MutualTLSClientCaCertPath string
-		if c.Config.MutualTLSClient.CertFile != "" {
-			MutualTLSClientCertPath = c.Config.MutualTLSClient.CertFile
-		} else {
-			MutualTLSClientCertPath = c.Config.MutualTLSFilesPath + MutualTLSClientCertFilename
+	if Capture != nil {
+		headers := map[string]string{ContentTypeHeaderKey: o.contentType, UserAgentHeaderKey: UserAgentHeaderValue}
+		for _, i := range o.headers {
+			headers[i.Key] = i.Value
 		}
-		if c.Config.MutualTLSClient.KeyFile != "" {
-			MutualTLSClientKeyPath = c.Config.MutualTLSClient.KeyFile
-		} else {
-			MutualTLSClientKeyPath = c.Config.MutualTLSFilesPath + MutualTLSClientKeyFilename
-		}
-		if c.Config.MutualTLSClient.CaCertFile != "" {
-			MutualTLSClientCaCertPath = c.Config.MutualTLSClient.CaCertFile
-		} else {
-			MutualTLSClientCaCertPath = c.Config.MutualTLSFilesPath + MutualTLSCacertFilename
+		captureBody := body.Bytes()
+		if _, ok := payload.(spyderbatPayload); ok {
+			// the compressed body isn't readable
+			captureBody, _ = json.Marshal(payload)
 		}
-		cert, err := tls.LoadX509KeyPair(MutualTLSClientCertPath, MutualTLSClientKeyPath)
-		if err != nil {
-			log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
+		if captured(CapturedRequest{Output: c.OutputType, Method: o.method, URL: o.endpointURL.String(), Headers: headers, Body: captureBody}) {
+			return nil
 		}
+	}
 
-		// Load CA cert
-		caCert, err := ioutil.ReadFile(MutualTLSClientCaCertPath)
+	client, err := c.getHTTPClient()
+	if err != nil {
+		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
+		return err
+	}
+
+	// each request has its own span, the delivery of the event gets its status
+	delivery := trace.SpanFromContext(ctx)
+	ctx, span := tracer.Start(ctx, "HTTP "+o.method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.HTTPMethod(o.method),
+		semconv.ServerAddress(o.endpointURL.Hostname()),
+	))
+	defer span.End()
+	defer func() {
 		if err != nil {
-			log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		customTransport.TLSClientConfig = &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      caCertPool,
-			MinVersion:   tls.VersionTLS12,
-		}
-	} else {
-		// With MutualTLS enabled, the check cert flag is ignored
-		if !c.CheckCert {
-			// #nosec G402 This is only set as a result of explicit configuration
-			customTransport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			delivery.SetStatus(codes.Error, err.Error())
+		} else {
+			delivery.SetStatus(codes.Ok, "")
 		}
-	}
-	client := &http.Client{
-		Transport: customTransport,
-	}
+	}()
 
-	req, err := http.NewRequest(method, c.EndpointURL.String(), body)
+	req, err := http.NewRequestWithContext(ctx, o.method, o.endpointURL.String(), body)
 	if err != nil {
 		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
+		return err
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	req.Header.Add(ContentTypeHeaderKey, c.ContentType)
+	req.Header.Add(ContentTypeHeaderKey, o.contentType)
 	req.Header.Add(UserAgentHeaderKey, UserAgentHeaderValue)
 
-	for _, headerObj := range c.HeaderList {
+	for _, headerObj := range o.headers {
 		req.Header.Add(headerObj.Key, headerObj.Value)
 	}
 
+	l := c.Logger(ctx)
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
+		l.Error(err.Error(), logger.Latency(start))
 		go c.CountMetric("outputs", 1, []string{"output:" + strings.ToLower(c.OutputType), "status:connectionrefused"})
 		return err
 	}
 	defer resp.Body.Close()
+	if code, ok := ctx.Value(statusCodeKey{}).(*int); ok {
+		*code = resp.StatusCode
+	}
+	l = l.With(logger.F(logger.StatusKey, resp.StatusCode), logger.Latency(start))
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
 
-	// Clear out headers - they will be set for the next request.
-	c.HeaderList = []Header{}
 	go c.CountMetric("outputs", 1, []string{"output:" + strings.ToLower(c.OutputType), "status:" + strings.ToLower(http.StatusText(resp.StatusCode))})
 
 	switch resp.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent: //200, 201, 202, 204
-		log.Printf("[INFO]  : %v - Post OK (%v)\n", c.OutputType, resp.StatusCode)
+		l.InfoLimited("Post OK")
 		body, _ := ioutil.ReadAll(resp.Body)
 		if ot := c.OutputType; ot == Kubeless || ot == Openfaas || ot == Fission {
-			log.Printf("[INFO]  : %v - Function Response : %v\n", ot, string(body))
+			l.Info("Function Response : " + string(body))
+		}
+		if o.responseHandler != nil {
+			return o.responseHandler(body)
 		}
 		return nil
 	case http.StatusBadRequest: //400
 		body, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("[ERROR] : %v - %v (%v): %v\n", c.OutputType, ErrHeaderMissing, resp.StatusCode, string(body))
+		l.Error(ErrHeaderMissing.Error(), logger.F("response", string(body)))
 		return ErrHeaderMissing
 	case http.StatusUnauthorized: //401
 		body, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("[ERROR] : %v - %v (%v): %v\n", c.OutputType, ErrClientAuthenticationError, resp.StatusCode, string(body))
+		l.Error(ErrClientAuthenticationError.Error(), logger.F("response", string(body)))
 		return ErrClientAuthenticationError
 	case http.StatusForbidden: //403
 		body, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("[ERROR] : %v - %v (%v): %v\n", c.OutputType, ErrForbidden, resp.StatusCode, string(body))
+		l.Error(ErrForbidden.Error(), logger.F("response", string(body)))
 		return ErrForbidden
 	case http.StatusNotFound: //404
 		body, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("[ERROR] : %v - %v (%v): %v\n", c.OutputType, ErrNotFound, resp.StatusCode, string(body))
+		l.Error(ErrNotFound.Error(), logger.F("response", string(body)))
 		return ErrNotFound
 	case http.StatusUnprocessableEntity: //422
 		body, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("[ERROR] : %v - %v (%v): %v\n", c.OutputType, ErrUnprocessableEntityError, resp.StatusCode, string(body))
+		l.Error(ErrUnprocessableEntityError.Error(), logger.F("response", string(body)))
 		return ErrUnprocessableEntityError
 	case http.StatusTooManyRequests: //429
 		body, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("[ERROR] : %v - %v (%v): %v\n", c.OutputType, ErrTooManyRequest, resp.StatusCode, string(body))
+		l.Error(ErrTooManyRequest.Error(), logger.F("response", string(body)))
 		return ErrTooManyRequest
 	case http.StatusInternalServerError: //500
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrTooManyRequest, resp.StatusCode)
+		l.Error(ErrInternalServer.Error())
 		return ErrInternalServer
 	case http.StatusBadGateway: //502
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrTooManyRequest, resp.StatusCode)
+		l.Error(ErrBadGateway.Error())
 		return ErrBadGateway
 	default:
-		log.Printf("[ERROR] : %v - unexpected Response  (%v)\n", c.OutputType, resp.StatusCode)
-		return errors.New(resp.Status)
+		l.Error("unexpected Response")
+		return &StatusCodeError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 }
 
-// BasicAuth adds an HTTP Basic Authentication compliant header to the Client.
-func (c *Client) BasicAuth(username, password string) {
-	// Check out RFC7617 for the specifics on this code.
-	// https://datatracker.ietf.org/doc/html/rfc7617
-	// This might break I18n, but we can cross that bridge when we come to it.
-	userPass := username + ":" + password
-	b64UserPass := base64.StdEncoding.EncodeToString([]byte(userPass))
-	c.AddHeader(AuthorizationHeaderKey, "Basic "+b64UserPass)
-}
-
-// AddHeader adds an HTTP Header to the Client.
-func (c *Client) AddHeader(key, value string) {
-	c.HeaderList = append(c.HeaderList, Header{Key: key, Value: value})
+// Logger returns the logger of the output, with the fields of the event carried by the context, like its uuid and
+// its rule.
+func (c *Client) Logger(ctx context.Context) *logger.Logger {
+	return logger.ForOutput(c.OutputType).WithContext(ctx)
 }
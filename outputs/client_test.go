@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -16,11 +17,17 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
@@ -34,7 +41,7 @@ func TestNewClient(t *testing.T) {
 	stats := &types.Statistics{}
 	promStats := &types.PromStatistics{}
 
-	testClientOutput := Client{OutputType: "test", EndpointURL: u, MutualTLSEnabled: false, CheckCert: true, HeaderList: []Header{}, ContentType: "application/json; charset=utf-8", Config: config, Stats: stats, PromStats: promStats}
+	testClientOutput := Client{OutputType: "test", EndpointURL: u, MutualTLSEnabled: false, CheckCert: true, ContentType: "application/json; charset=utf-8", Config: config, Stats: stats, PromStats: promStats}
 	_, err := NewClient("test", "localhost/%*$¨^!/:;", false, true, config, stats, promStats, nil, nil)
 	require.NotNil(t, err)
 
@@ -81,12 +88,12 @@ func TestPost(t *testing.T) {
 		require.Nil(t, err)
 		require.NotEmpty(t, nc)
 
-		errPost := nc.Post("")
+		errPost := nc.Post(context.Background(), "")
 		require.Equal(t, errPost, j)
 	}
 }
 
-func TestAddHeader(t *testing.T) {
+func TestWithHeader(t *testing.T) {
 	headerKey, headerVal := "key", "val"
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		passedVal := r.Header.Get(headerKey)
@@ -96,12 +103,10 @@ func TestAddHeader(t *testing.T) {
 	require.Nil(t, err)
 	require.NotEmpty(t, nc)
 
-	nc.AddHeader(headerKey, headerVal)
-
-	nc.Post("")
+	nc.Post(context.Background(), "", WithHeader(headerKey, headerVal))
 }
 
-func TestAddBasicAuth(t *testing.T) {
+func TestWithBasicAuth(t *testing.T) {
 	username, password := "user", "pass"
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// I'm not comfortable using the constants here - seems too easy to fat-finger a change in
@@ -147,29 +152,125 @@ func TestAddBasicAuth(t *testing.T) {
 	require.Nil(t, err)
 	require.NotEmpty(t, nc)
 
-	nc.BasicAuth(username, password)
-
-	nc.Post("")
+	nc.Post(context.Background(), "", WithBasicAuth(username, password))
 }
 
-func TestHeadersResetAfterReq(t *testing.T) {
-	headerKey, headerVal := http.CanonicalHeaderKey("key"), "val"
+func TestHeadersNotSharedBetweenReqs(t *testing.T) {
+	headerKey := http.CanonicalHeaderKey("key")
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		passedList := r.Header[headerKey]
 		require.Equal(t, 1, len(passedList), "Expected %v to have 1 element", passedList)
+		require.Equal(t, r.URL.Query().Get("val"), passedList[0])
 	}))
 
 	nc, err := NewClient("", ts.URL, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
 	require.Nil(t, err)
 	require.NotEmpty(t, nc)
 
-	nc.AddHeader(headerKey, headerVal)
+	// the requests are sent concurrently, each one with its own header
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		val := strconv.Itoa(i)
+		endpointURL, err := url.Parse(ts.URL + "?val=" + val)
+		require.Nil(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, nc.Post(context.Background(), "", WithHeader(headerKey, val), WithEndpointURL(endpointURL)))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, ts.URL, nc.EndpointURL.String())
+}
+
+func TestRequestOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "text/plain", r.Header.Get(ContentTypeHeaderKey))
+	}))
+
+	nc, err := NewClient("", ts.URL, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	require.Nil(t, nc.Post(context.Background(), "", WithMethod(http.MethodPut), WithContentType("text/plain")))
+	require.Equal(t, DefaultContentType, nc.ContentType)
+}
+
+func TestPostCanceled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	nc, err := NewClient("", ts.URL, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = nc.Post(ctx, "")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// panickingPayload panics when it's encoded
+type panickingPayload struct{}
+
+func (panickingPayload) MarshalJSON() ([]byte, error) {
+	panic("unexpected payload")
+}
+
+func TestPostPanic(t *testing.T) {
+	nc, err := NewClient("", "http://localhost", false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	// the recovered panic is returned as the error of the post
+	require.EqualError(t, nc.Post(context.Background(), panickingPayload{}), "panic: unexpected payload")
+}
+
+func TestWithStatusCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	nc, err := NewClient("", ts.URL, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	var code int
+	require.Nil(t, nc.Post(WithStatusCode(context.Background(), &code), ""))
+	require.Equal(t, http.StatusAccepted, code)
+}
 
-	nc.Post("")
+func TestCapture(t *testing.T) {
+	var posts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+	}))
+	defer ts.Close()
 
-	nc.AddHeader(headerKey, headerVal)
+	var requests []CapturedRequest
+	Capture = func(r CapturedRequest) bool {
+		requests = append(requests, r)
+		return r.Method == http.MethodPut
+	}
+	defer func() { Capture = nil }()
 
-	nc.Post("")
+	nc, err := NewClient("Webhook", ts.URL, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, nc.Post(context.Background(), map[string]string{"rule": "Test rule"}, WithMethod(http.MethodPut), WithHeader("X-Token", "my-token")))
+	require.Equal(t, 0, posts)
+	require.Equal(t, []CapturedRequest{{
+		Output:  "Webhook",
+		Method:  http.MethodPut,
+		URL:     ts.URL,
+		Headers: map[string]string{ContentTypeHeaderKey: DefaultContentType, UserAgentHeaderKey: UserAgentHeaderValue, "X-Token": "my-token"},
+		Body:    []byte("{\"rule\":\"Test rule\"}\n"),
+	}}, requests)
+
+	// the requests which aren't captured are sent
+	require.Nil(t, nc.Post(context.Background(), ""))
+	require.Equal(t, 1, posts)
 }
 
 func TestMutualTlsPost(t *testing.T) {
@@ -209,7 +310,7 @@ func TestMutualTlsPost(t *testing.T) {
 	require.Nil(t, err)
 	require.NotEmpty(t, nc)
 
-	errPost := nc.Post("")
+	errPost := nc.Post(context.Background(), "")
 	require.Nil(t, errPost)
 
 }
@@ -374,3 +475,68 @@ func certsetup(config *types.Configuration) (serverTLSConf *tls.Config, err erro
 	}
 	return serverTLSConf, nil
 }
+
+func TestTraceContextInjected(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	provider := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "deliver")
+	defer span.End()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(r.Header))
+		sc := trace.SpanContextFromContext(ctx)
+		// the request has its own span, child of the delivery
+		if sc.TraceID() != span.SpanContext().TraceID() || sc.SpanID() == span.SpanContext().SpanID() {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	nc, err := NewClient("", ts.URL, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, nc.Post(ctx, ""))
+}
+
+func TestClassifyError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/418":
+			w.WriteHeader(http.StatusTeapot)
+		case "/503":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	// a listener closed right away gives an address refusing the connections
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	refused := "http://" + l.Addr().String()
+	l.Close()
+
+	for i, j := range map[string]string{
+		ts.URL + "/418":  ClientErrorClass,
+		ts.URL + "/503":  ServerErrorClass,
+		ts.URL + "/slow": TimeoutErrorClass,
+		refused:          ConnectionRefusedErrorClass,
+	} {
+		nc, err := NewClient("", i, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+		require.Nil(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		errPost := nc.Post(ctx, "")
+		cancel()
+		require.NotNil(t, errPost, i)
+		require.Equal(t, j, ClassifyError(errPost), i)
+	}
+
+	require.Equal(t, ClientErrorClass, ClassifyError(ErrTooManyRequest))
+	require.Equal(t, ServerErrorClass, ClassifyError(ErrInternalServer))
+	require.Equal(t, OtherErrorClass, ClassifyError(ErrClientCreation))
+}
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 
@@ -159,23 +160,21 @@ func newCliqPayload(falcopayload types.FalcoPayload, config *types.Configuration
 }
 
 // CliqPost posts event to cliq
-func (c *Client) CliqPost(falcopayload types.FalcoPayload) {
+func (c *Client) CliqPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Cliq.Add(Total, 1)
 
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
-	c.AddHeader(ContentTypeHeaderKey, "application/json")
-	err := c.Post(newCliqPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newCliqPayload(falcopayload, c.Config), WithContentType("application/json"))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:cliq", "status:error"})
 		c.Stats.Cliq.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "cliq", "status": Error}).Inc()
 		log.Printf("[ERROR] : Cliq - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:cliq", "status:ok"})
 	c.Stats.Cliq.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "cliq", "status": OK}).Inc()
+	return nil
 }
@@ -10,7 +10,7 @@ import (
 )
 
 // CloudEventsSend produces a CloudEvent and sends to the CloudEvents consumers.
-func (c *Client) CloudEventsSend(falcopayload types.FalcoPayload) {
+func (c *Client) CloudEventsSend(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.CloudEvents.Add(Total, 1)
 
 	if c.CloudEventsClient == nil {
@@ -18,12 +18,12 @@ func (c *Client) CloudEventsSend(falcopayload types.FalcoPayload) {
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:cloudevents", "status:error"})
 			log.Printf("[ERROR] : CloudEvents - NewDefaultClient : %v\n", err)
-			return
+			return err
 		}
 		c.CloudEventsClient = client
 	}
 
-	ctx := cloudevents.ContextWithTarget(context.Background(), c.EndpointURL.String())
+	ctx = cloudevents.ContextWithTarget(ctx, c.EndpointURL.String())
 
 	event := cloudevents.NewEvent()
 	event.SetTime(falcopayload.Time)
@@ -51,12 +51,13 @@ func (c *Client) CloudEventsSend(falcopayload types.FalcoPayload) {
 		c.Stats.CloudEvents.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "cloudevents", "status": Error}).Inc()
 		log.Printf("[ERROR] : CloudEvents - %v\n", result)
-		return
+		return result
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:cloudevents", "status:ok"})
 	c.Stats.CloudEvents.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "cloudevents", "status": OK}).Inc()
-	log.Printf("[INFO]  : CloudEvents - Send OK\n")
+	c.Logger(ctx).InfoLimited("Send OK")
+	return nil
 }
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -98,20 +99,21 @@ func newDiscordPayload(falcopayload types.FalcoPayload, config *types.Configurat
 }
 
 // DiscordPost posts events to discord
-func (c *Client) DiscordPost(falcopayload types.FalcoPayload) {
+func (c *Client) DiscordPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Discord.Add(Total, 1)
 
-	err := c.Post(newDiscordPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newDiscordPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:discord", "status:error"})
 		c.Stats.Discord.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "discord", "status": Error}).Inc()
 		log.Printf("[ERROR] : Discord - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:discord", "status:ok"})
 	c.Stats.Discord.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "discord", "status": OK}).Inc()
+	return nil
 }
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"log"
 	"regexp"
 	"time"
@@ -100,25 +101,23 @@ func newDynatracePayload(falcopayload types.FalcoPayload) dtPayload {
 	return dtPayload{Payload: []dtLogMessage{message}}
 }
 
-func (c *Client) DynatracePost(falcopayload types.FalcoPayload) {
+func (c *Client) DynatracePost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Dynatrace.Add(Total, 1)
 
-	c.ContentType = DynatraceContentType
-
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
-	c.AddHeader("Authorization", "Api-Token "+c.Config.Dynatrace.APIToken)
-
-	err := c.Post(newDynatracePayload(falcopayload).Payload)
+	err := c.Post(ctx, newDynatracePayload(falcopayload).Payload,
+		WithContentType(DynatraceContentType),
+		WithHeader("Authorization", "Api-Token "+c.Config.Dynatrace.APIToken),
+	)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:dynatrace", "status:error"})
 		c.Stats.Dynatrace.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "dynatrace", "status": Error}).Inc()
 		log.Printf("[ERROR] : Dynatrace - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:dynatrace", "status:ok"})
 	c.Stats.Dynatrace.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "dynatrace", "status": OK}).Inc()
+	return nil
 }
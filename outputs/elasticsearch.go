@@ -1,6 +1,10 @@
 package outputs
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/url"
 	"time"
@@ -8,52 +12,126 @@ import (
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// elasticsearchBulkPayload is the NDJSON body of a request to the _bulk API
+type elasticsearchBulkPayload []byte
+
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
 // ElasticsearchPost posts event to Elasticsearch
-func (c *Client) ElasticsearchPost(falcopayload types.FalcoPayload) {
+func (c *Client) ElasticsearchPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Elasticsearch.Add(Total, 1)
 
-	current := time.Now()
-	var eURL string
+	var err error
+	if c.Config.Elasticsearch.Batch.MaxSize > 1 {
+		err = c.getBatcher("elasticsearch", c.Config.Elasticsearch.Batch, c.elasticsearchBulkPost).add(ctx, falcopayload)
+	} else {
+		err = c.elasticsearchPost(ctx, falcopayload)
+	}
+	if err != nil {
+		c.setElasticSearchErrorMetrics()
+		log.Printf("[ERROR] : ElasticSearch - %v\n", err)
+		return err
+	}
+
+	// Setting the success status
+	go c.CountMetric(Outputs, 1, []string{"output:elasticsearch", "status:ok"})
+	c.Stats.Elasticsearch.Add(OK, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "elasticsearch", "status": OK}).Inc()
+	return nil
+}
+
+func (c *Client) elasticsearchPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	endpointURL, err := url.Parse(c.Config.Elasticsearch.HostPort + "/" + c.elasticsearchIndex(time.Now()) + "/" + c.Config.Elasticsearch.Type)
+	if err != nil {
+		return err
+	}
+
+	return c.Post(ctx, falcopayload, append(c.elasticsearchOptions(), WithEndpointURL(endpointURL))...)
+}
+
+// elasticsearchBulkPost sends a batch of events with the _bulk API, the batch fails if any of its events is refused.
+func (c *Client) elasticsearchBulkPost(ctx context.Context, events []types.FalcoPayload) error {
+	endpointURL, err := url.Parse(c.Config.Elasticsearch.HostPort + "/_bulk")
+	if err != nil {
+		return err
+	}
+
+	action := map[string]string{"_index": c.elasticsearchIndex(time.Now())}
+	if t := c.Config.Elasticsearch.Type; t != "" && t != "_doc" {
+		action["_type"] = t
+	}
+	body := new(bytes.Buffer)
+	encoder := json.NewEncoder(body)
+	for _, i := range events {
+		if err := encoder.Encode(map[string]interface{}{"index": action}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(i); err != nil {
+			return err
+		}
+	}
+
+	opts := append(c.elasticsearchOptions(),
+		WithEndpointURL(endpointURL),
+		WithContentType("application/x-ndjson"),
+		withResponseHandler(checkElasticsearchBulkResponse),
+	)
+	return c.Post(ctx, elasticsearchBulkPayload(body.Bytes()), opts...)
+}
+
+// checkElasticsearchBulkResponse returns an error if some events of the batch have been refused, the _bulk API
+// answers with a 200 anyway.
+func checkElasticsearchBulkResponse(body []byte) error {
+	var resp elasticsearchBulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil || !resp.Errors {
+		return nil
+	}
+	var refused int
+	var firstError json.RawMessage
+	for _, i := range resp.Items {
+		for _, j := range i {
+			if j.Status < 300 {
+				continue
+			}
+			refused++
+			if firstError == nil {
+				firstError = j.Error
+			}
+		}
+	}
+	return fmt.Errorf("%v of the %v events have been refused, first error: %s", refused, len(resp.Items), firstError)
+}
+
+// elasticsearchIndex returns the name of the index, with its date suffix
+func (c *Client) elasticsearchIndex(current time.Time) string {
 	switch c.Config.Elasticsearch.Suffix {
 	case "none":
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "/" + c.Config.Elasticsearch.Type
+		return c.Config.Elasticsearch.Index
 	case "monthly":
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "-" + current.Format("2006.01") + "/" + c.Config.Elasticsearch.Type
+		return c.Config.Elasticsearch.Index + "-" + current.Format("2006.01")
 	case "annually":
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "-" + current.Format("2006") + "/" + c.Config.Elasticsearch.Type
+		return c.Config.Elasticsearch.Index + "-" + current.Format("2006")
 	default:
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "-" + current.Format("2006.01.02") + "/" + c.Config.Elasticsearch.Type
-	}
-
-	endpointURL, err := url.Parse(eURL)
-	if err != nil {
-		c.setElasticSearchErrorMetrics()
-		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
-		return
+		return c.Config.Elasticsearch.Index + "-" + current.Format("2006.01.02")
 	}
+}
 
-	c.EndpointURL = endpointURL
+func (c *Client) elasticsearchOptions() []RequestOption {
+	var opts []RequestOption
 	if c.Config.Elasticsearch.Username != "" && c.Config.Elasticsearch.Password != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.BasicAuth(c.Config.Elasticsearch.Username, c.Config.Elasticsearch.Password)
+		opts = append(opts, WithBasicAuth(c.Config.Elasticsearch.Username, c.Config.Elasticsearch.Password))
 	}
 
 	for i, j := range c.Config.Elasticsearch.CustomHeaders {
-		c.AddHeader(i, j)
-	}
-
-	err = c.Post(falcopayload)
-	if err != nil {
-		c.setElasticSearchErrorMetrics()
-		log.Printf("[ERROR] : ElasticSearch - %v\n", err)
-		return
+		opts = append(opts, WithHeader(i, j))
 	}
-
-	// Setting the success status
-	go c.CountMetric(Outputs, 1, []string{"output:elasticsearch", "status:ok"})
-	c.Stats.Elasticsearch.Add(OK, 1)
-	c.PromStats.Outputs.With(map[string]string{"destination": "elasticsearch", "status": OK}).Inc()
+	return opts
 }
 
 // setElasticSearchErrorMetrics set the error stats
@@ -0,0 +1,60 @@
+package outputs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestElasticsearchBulkPost(t *testing.T) {
+	var refused atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_bulk", r.URL.Path)
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			require.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		require.Len(t, lines, 4)
+		require.Equal(t, map[string]interface{}{"index": map[string]interface{}{"_index": "falco"}}, lines[0])
+		require.Equal(t, "Test rule", lines[1]["rule"])
+
+		if refused.Load() {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}}]}`))
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Elasticsearch.HostPort = ts.URL
+	config.Elasticsearch.Index = "falco"
+	config.Elasticsearch.Type = "_doc"
+	config.Elasticsearch.Suffix = "none"
+	nc, err := NewClient("Elasticsearch", ts.URL, false, true, config, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	events := []types.FalcoPayload{f, f}
+
+	require.Nil(t, nc.elasticsearchBulkPost(context.Background(), events))
+
+	refused.Store(true)
+	err = nc.elasticsearchBulkPost(context.Background(), events)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "1 of the 2 events have been refused")
+}
@@ -0,0 +1,50 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Classes of the errors of the outputs, used as the class label of the error metrics
+const (
+	TimeoutErrorClass           = "timeout"
+	ClientErrorClass            = "4xx"
+	ServerErrorClass            = "5xx"
+	ConnectionRefusedErrorClass = "connectionrefused"
+	OtherErrorClass             = "other"
+)
+
+// StatusCodeError is returned for the responses with an unexpected status code
+type StatusCodeError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusCodeError) Error() string {
+	return e.Status
+}
+
+// ClassifyError returns the class of the error of a post: timeout, 4xx, 5xx, connectionrefused or other.
+func ClassifyError(err error) string {
+	var netErr net.Error
+	var statusErr *StatusCodeError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), os.IsTimeout(err), errors.As(err, &netErr) && netErr.Timeout():
+		return TimeoutErrorClass
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnectionRefusedErrorClass
+	case errors.Is(err, ErrHeaderMissing), errors.Is(err, ErrClientAuthenticationError), errors.Is(err, ErrForbidden),
+		errors.Is(err, ErrNotFound), errors.Is(err, ErrUnprocessableEntityError), errors.Is(err, ErrTooManyRequest):
+		return ClientErrorClass
+	case errors.Is(err, ErrInternalServer), errors.Is(err, ErrBadGateway):
+		return ServerErrorClass
+	case errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500:
+		return ClientErrorClass
+	case errors.As(err, &statusErr) && statusErr.StatusCode >= 500:
+		return ServerErrorClass
+	}
+	return OtherErrorClass
+}
@@ -11,6 +11,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -57,7 +58,7 @@ func NewFissionClient(config *types.Configuration, stats *types.Statistics, prom
 }
 
 // FissionCall .
-func (c *Client) FissionCall(falcopayload types.FalcoPayload) {
+func (c *Client) FissionCall(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Fission.Add(Total, 1)
 
 	if c.Config.Fission.KubeConfig != "" {
@@ -70,33 +71,32 @@ func (c *Client) FissionCall(falcopayload types.FalcoPayload) {
 		req.SetHeader(ContentTypeHeaderKey, FissionContentType)
 		req.SetHeader(UserAgentHeaderKey, UserAgentHeaderValue)
 
-		res := req.Do(context.TODO())
+		res := req.Do(ctx)
 		rawbody, err := res.Raw()
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:Fission", "status:error"})
 			c.Stats.Fission.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "Fission", "status": Error}).Inc()
 			log.Printf("[ERROR] : %s - %v\n", Fission, err.Error())
-			return
+			return err
 		}
 		log.Printf("[INFO]  : %s - Function Response : %v\n", Fission, string(rawbody))
 	} else {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.AddHeader(FissionEventIDKey, uuid.New().String())
-		c.ContentType = FissionContentType
-
-		err := c.Post(falcopayload)
+		err := c.Post(ctx, falcopayload,
+			WithHeader(FissionEventIDKey, uuid.New().String()),
+			WithContentType(FissionContentType),
+		)
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:Fission", "status:error"})
 			c.Stats.Fission.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "Fission", "status": Error}).Inc()
 			log.Printf("[ERROR] : %s - %v\n", Fission, err.Error())
-			return
+			return err
 		}
 	}
-	log.Printf("[INFO]  : %s - Call Function \"%v\" OK\n", Fission, c.Config.Fission.Function)
+	c.Logger(ctx).InfoLimited("Call Function OK", logger.F("function", c.Config.Fission.Function))
 	go c.CountMetric(Outputs, 1, []string{"output:Fission", "status:ok"})
 	c.Stats.Fission.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "Fission", "status": OK}).Inc()
+	return nil
 }
@@ -0,0 +1,421 @@
+package outputs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Output fields added by the Forward output to the events
+const (
+	ForwardClusterField = "falcosidekick.cluster"
+	ForwardVersionField = "falcosidekick.version"
+)
+
+const forwardSpoolFileSuffix = ".json.gz"
+
+// forwardBatchOverhead is the size of the brackets and of the new line around the events of a batch
+const forwardBatchOverhead = 3
+
+// forwardBatch is a batch of events, encoded in JSON and compressed with gzip
+type forwardBatch []byte
+
+// forwardSpoolEntry is a batch waiting to be acknowledged by the other falcosidekick, it's stored in a file of the
+// spool directory, or in memory if no directory is set
+type forwardSpoolEntry struct {
+	seq    uint64
+	path   string
+	data   forwardBatch
+	events int
+}
+
+// forwarder groups the events into batches and sends them in order, a batch is removed from the spool once it
+// has been acknowledged.
+type forwarder struct {
+	config  types.ForwardOutputConfig
+	version string
+	token   string
+
+	batch []types.FalcoPayload
+	// batchBytes is the size of the current batch encoded in JSON, before its compression
+	batchBytes int
+	spool      []forwardSpoolEntry
+	seq        uint64
+	ready      chan struct{}
+	stop       chan struct{}
+	// ctx is the context of the posts of the batches, it's canceled once the forwarder is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+	sync.Mutex
+}
+
+// NewForwardClient returns a new output.Client for forwarding the events to another falcosidekick, the batches left
+// in the spool directory by a previous run are sent first.
+func NewForwardClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client, version string) (*Client, error) {
+	endpointURL, err := url.JoinPath(config.Forward.Address, "forward")
+	if err != nil {
+		log.Printf("[ERROR] : Forward - %v\n", err)
+		return nil, ErrClientCreation
+	}
+	c, err := NewClient("Forward", endpointURL, config.Forward.MutualTLS, config.Forward.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &forwarder{config: config.Forward, version: version, ready: make(chan struct{}, 1), stop: make(chan struct{})}
+	if config.Forward.TokenFile != "" {
+		token, err := os.ReadFile(config.Forward.TokenFile)
+		if err != nil {
+			log.Printf("[ERROR] : Forward - %v\n", err)
+			return nil, ErrClientCreation
+		}
+		f.token = strings.TrimSpace(string(token))
+	}
+	if config.Forward.SpoolDir != "" {
+		if err := f.loadSpool(); err != nil {
+			log.Printf("[ERROR] : Forward - %v\n", err)
+			return nil, ErrClientCreation
+		}
+		if len(f.spool) != 0 {
+			log.Printf("[INFO]  : Forward - %v batches found in the spool directory\n", len(f.spool))
+		}
+	}
+	f.ctx, f.cancel = context.WithCancel(context.Background())
+	c.forwarder = f
+	c.setForwardQueueDepth()
+
+	go c.flushForwardBatches()
+	go c.sendForwardBatches()
+	return c, nil
+}
+
+// ForwardPost adds the event to the current batch, with the metadata of this falcosidekick.
+func (c *Client) ForwardPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.Forward.Add(Total, 1)
+
+	// the output fields are shared with the other outputs
+	outputFields := make(map[string]interface{}, len(falcopayload.OutputFields)+2)
+	for k, v := range falcopayload.OutputFields {
+		outputFields[k] = v
+	}
+	if _, ok := outputFields[ForwardClusterField]; !ok && c.forwarder.config.ClusterName != "" {
+		outputFields[ForwardClusterField] = c.forwarder.config.ClusterName
+	}
+	if _, ok := outputFields[ForwardVersionField]; !ok {
+		outputFields[ForwardVersionField] = c.forwarder.version
+	}
+	falcopayload.OutputFields = outputFields
+	// the event is followed by a comma in the JSON array of the batch
+	var size int
+	if data, err := json.Marshal(falcopayload); err == nil {
+		size = len(data) + 1
+	}
+
+	c.forwarder.Lock()
+	defer c.forwarder.Unlock()
+	maxBytes := c.forwarder.config.MaxBytes
+	if len(c.forwarder.batch) != 0 && maxBytes > 0 && forwardBatchOverhead+c.forwarder.batchBytes+size > maxBytes {
+		// the event doesn't fit in the current batch
+		c.spoolForwardBatch()
+	}
+	c.forwarder.batch = append(c.forwarder.batch, falcopayload)
+	c.forwarder.batchBytes += size
+	if len(c.forwarder.batch) >= c.forwarder.config.BatchSize || (maxBytes > 0 && forwardBatchOverhead+c.forwarder.batchBytes >= maxBytes) {
+		c.spoolForwardBatch()
+	}
+	c.setForwardQueueDepth()
+	return nil
+}
+
+// flushForwardBatches spools the current batch at every flush interval, even if it isn't complete.
+func (c *Client) flushForwardBatches() {
+	ticker := time.NewTicker(time.Duration(c.forwarder.config.FlushInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.forwarder.Lock()
+			if len(c.forwarder.batch) != 0 {
+				c.spoolForwardBatch()
+			}
+			c.setForwardQueueDepth()
+			c.forwarder.Unlock()
+		case <-c.forwarder.stop:
+			return
+		}
+	}
+}
+
+// FlushForward spools the current batch of the forward output and waits for the spool to be sent, until ctx is
+// done. It returns the number of events left in the spool.
+func (c *Client) FlushForward(ctx context.Context) int {
+	f := c.forwarder
+	f.Lock()
+	if len(f.batch) != 0 {
+		c.spoolForwardBatch()
+	}
+	c.setForwardQueueDepth()
+	f.Unlock()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		f.Lock()
+		var events int
+		for _, i := range f.spool {
+			events += i.events
+		}
+		f.Unlock()
+		if events == 0 {
+			return 0
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return events
+		}
+	}
+}
+
+// closeForwarder sends the spool until ctx is done, then cancels the post in progress and stops the goroutines of
+// the forwarder. The batches left in the spool directory are sent at the next start, the ones in memory are dropped.
+func (c *Client) closeForwarder(ctx context.Context) {
+	left := c.FlushForward(ctx)
+	c.forwarder.cancel()
+	close(c.forwarder.stop)
+	switch {
+	case left == 0:
+	case c.forwarder.config.SpoolDir != "":
+		log.Printf("[WARN] : Forward - %v events not sent are kept in the spool directory\n", left)
+	default:
+		log.Printf("[ERROR] : Forward - %v events not sent are dropped\n", left)
+	}
+}
+
+// stopped tells whether the forwarder is closed
+func (f *forwarder) stopped() bool {
+	select {
+	case <-f.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// spoolForwardBatch moves the current batch to the spool, the lock of the forwarder must be held.
+func (c *Client) spoolForwardBatch() {
+	f := c.forwarder
+	events := len(f.batch)
+	data, err := newForwardBatch(f.batch)
+	f.batch = nil
+	f.batchBytes = 0
+	if err == nil {
+		err = f.push(data, events)
+	}
+	if err != nil {
+		c.setForwardMetrics(Error, events)
+		log.Printf("[ERROR] : Forward - Can't spool a batch of %v events: %v\n", events, err)
+		return
+	}
+
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// sendForwardBatches sends the spooled batches in order, a batch is retried until it's acknowledged or refused as
+// invalid or too large by the other falcosidekick.
+func (c *Client) sendForwardBatches() {
+	f := c.forwarder
+	for {
+		if f.stopped() {
+			return
+		}
+		f.Lock()
+		if len(f.spool) == 0 {
+			f.Unlock()
+			select {
+			case <-f.ready:
+			case <-f.stop:
+				return
+			}
+			continue
+		}
+		entry := f.spool[0]
+		f.Unlock()
+
+		data := entry.data
+		if entry.path != "" {
+			var err error
+			data, err = os.ReadFile(entry.path)
+			if err != nil {
+				log.Printf("[ERROR] : Forward - Can't read the spooled batch %v: %v\n", entry.path, err)
+				c.removeForwardBatch(entry)
+				continue
+			}
+		}
+
+		opts := []RequestOption{WithHeader("Content-Encoding", "gzip")}
+		if f.token != "" {
+			opts = append(opts, WithHeader(AuthorizationHeaderKey, "Bearer "+f.token))
+		}
+		// the batch is kept in the spool until it's acknowledged, it doesn't depend on the context of the events, its
+		// post is only canceled once the forwarder is closed
+		err := c.Post(f.ctx, forwardBatch(data), opts...)
+
+		switch {
+		case err == nil:
+			c.setForwardMetrics(OK, entry.events)
+			c.removeForwardBatch(entry)
+		case isForwardBatchRefused(err):
+			// the batch will never be accepted
+			c.setForwardMetrics(Error, entry.events)
+			log.Printf("[ERROR] : Forward - A batch of %v events has been refused, it's dropped: %v\n", entry.events, err)
+			c.removeForwardBatch(entry)
+		default:
+			c.setForwardMetrics(Error, entry.events)
+			if f.stopped() {
+				return
+			}
+			log.Printf("[ERROR] : Forward - %v, retrying in %vs\n", err, f.config.RetryInterval)
+			select {
+			case <-time.After(time.Duration(f.config.RetryInterval) * time.Second):
+			case <-f.stop:
+			}
+		}
+	}
+}
+
+// isForwardBatchRefused tells whether the other falcosidekick refused the batch as invalid or too large, sending it
+// again would get the same answer
+func isForwardBatchRefused(err error) bool {
+	if errors.Is(err, ErrHeaderMissing) || errors.Is(err, ErrUnprocessableEntityError) {
+		return true
+	}
+	var statusErr *StatusCodeError
+	return errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusRequestEntityTooLarge || statusErr.StatusCode == http.StatusUnsupportedMediaType)
+}
+
+func (c *Client) setForwardMetrics(status string, events int) {
+	go c.CountMetric(Outputs, int64(events), []string{"output:forward", "status:" + status})
+	c.Stats.Forward.Add(status, int64(events))
+	c.PromStats.Outputs.With(map[string]string{"destination": "forward", "status": status}).Add(float64(events))
+}
+
+// removeForwardBatch deletes a batch from the spool and updates the queue depth
+func (c *Client) removeForwardBatch(entry forwardSpoolEntry) {
+	c.forwarder.remove(entry)
+	c.forwarder.Lock()
+	c.setForwardQueueDepth()
+	c.forwarder.Unlock()
+}
+
+// setForwardQueueDepth sets the number of events of the current batch and of the spool, the lock of the forwarder
+// must be held.
+func (c *Client) setForwardQueueDepth() {
+	depth := len(c.forwarder.batch)
+	for _, i := range c.forwarder.spool {
+		depth += i.events
+	}
+	c.PromStats.OutputQueueDepth.With(map[string]string{"destination": "forward"}).Set(float64(depth))
+}
+
+func newForwardBatch(events []types.FalcoPayload) (forwardBatch, error) {
+	body := new(bytes.Buffer)
+	zipper := gzip.NewWriter(body)
+	if err := json.NewEncoder(zipper).Encode(events); err != nil {
+		return nil, err
+	}
+	if err := zipper.Close(); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// push adds a batch at the end of the spool, the oldest batch is dropped if the spool is full.
+func (f *forwarder) push(data forwardBatch, events int) error {
+	f.seq++
+	entry := forwardSpoolEntry{seq: f.seq, data: data, events: events}
+	if f.config.SpoolDir != "" {
+		entry = forwardSpoolEntry{seq: f.seq, path: filepath.Join(f.config.SpoolDir, fmt.Sprintf("%020d-%d%v", f.seq, events, forwardSpoolFileSuffix)), events: events}
+		// the file is renamed once written, to never send a partial batch after a crash
+		if err := os.WriteFile(entry.path+".tmp", data, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(entry.path+".tmp", entry.path); err != nil {
+			return err
+		}
+	}
+
+	f.spool = append(f.spool, entry)
+	if len(f.spool) > f.config.MaxSpooledBatches {
+		dropped := f.spool[0]
+		f.spool = f.spool[1:]
+		if dropped.path != "" {
+			os.Remove(dropped.path)
+		}
+		log.Printf("[ERROR] : Forward - The spool is full, the oldest batch of %v events is dropped\n", dropped.events)
+	}
+	return nil
+}
+
+// remove deletes a batch from the spool, if it's still there.
+func (f *forwarder) remove(entry forwardSpoolEntry) {
+	f.Lock()
+	defer f.Unlock()
+	if len(f.spool) == 0 || f.spool[0].seq != entry.seq {
+		return
+	}
+	f.spool = f.spool[1:]
+	if entry.path != "" {
+		if err := os.Remove(entry.path); err != nil {
+			log.Printf("[ERROR] : Forward - Can't remove the spooled batch %v: %v\n", entry.path, err)
+		}
+	}
+}
+
+// loadSpool reads the batches left in the spool directory, named <sequence>-<number of events>.json.gz.
+func (f *forwarder) loadSpool() error {
+	if err := os.MkdirAll(f.config.SpoolDir, 0700); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(f.config.SpoolDir, "*"+forwardSpoolFileSuffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, i := range files {
+		name := strings.SplitN(strings.TrimSuffix(filepath.Base(i), forwardSpoolFileSuffix), "-", 2)
+		if len(name) != 2 {
+			continue
+		}
+		seq, err := strconv.ParseUint(name[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		events, _ := strconv.Atoi(name[1])
+		f.spool = append(f.spool, forwardSpoolEntry{seq: seq, path: i, events: events})
+		if seq > f.seq {
+			f.seq = seq
+		}
+	}
+	return nil
+}
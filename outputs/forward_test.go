@@ -0,0 +1,252 @@
+package outputs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestForwardPost(t *testing.T) {
+	var lock sync.Mutex
+	var attempts int
+	var received []types.FalcoPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/forward", r.URL.Path)
+		require.Equal(t, "Bearer my-token", r.Header.Get(AuthorizationHeaderKey))
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		lock.Lock()
+		defer lock.Unlock()
+		attempts++
+		// the first attempt fails, the batch must be sent again
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		require.Nil(t, err)
+		var batch []types.FalcoPayload
+		require.Nil(t, json.NewDecoder(zr).Decode(&batch))
+		received = append(received, batch...)
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.Nil(t, os.WriteFile(tokenFile, []byte("my-token\n"), 0600))
+	spoolDir := filepath.Join(dir, "spool")
+
+	config := &types.Configuration{
+		Forward: types.ForwardOutputConfig{
+			Address:           ts.URL,
+			TokenFile:         tokenFile,
+			ClusterName:       "edge",
+			BatchSize:         2,
+			FlushInterval:     60,
+			SpoolDir:          spoolDir,
+			MaxSpooledBatches: 10,
+			RetryInterval:     1,
+			CheckCert:         true,
+		},
+	}
+	stats := &types.Statistics{Forward: new(expvar.Map).Init()}
+	promStats := &types.PromStatistics{
+		Outputs:          prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, []string{"destination", "status"}),
+		OutputQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_queue_depth"}, []string{"destination"}),
+	}
+
+	client, err := NewForwardClient(config, stats, promStats, nil, nil, "1.0.0")
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	client.ForwardPost(context.Background(), f)
+	client.ForwardPost(context.Background(), f)
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, "edge", received[0].OutputFields[ForwardClusterField])
+	require.Equal(t, "1.0.0", received[0].OutputFields[ForwardVersionField])
+	require.Equal(t, "falcosidekick", received[0].OutputFields["proc.name"])
+	// the event given to the other outputs isn't modified
+	require.Nil(t, f.OutputFields[ForwardClusterField])
+
+	require.Eventually(t, func() bool {
+		files, _ := filepath.Glob(filepath.Join(spoolDir, "*"))
+		return len(files) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestForwarderSpool(t *testing.T) {
+	dir := t.TempDir()
+	f := &forwarder{config: types.ForwardOutputConfig{SpoolDir: dir, MaxSpooledBatches: 2}}
+	require.Nil(t, f.loadSpool())
+
+	for i := 1; i <= 3; i++ {
+		require.Nil(t, f.push(forwardBatch("batch"), i))
+	}
+	// the oldest batch has been dropped
+	require.Len(t, f.spool, 2)
+	require.Equal(t, 2, f.spool[0].events)
+
+	// the batches are loaded in order after a restart
+	g := &forwarder{config: f.config}
+	require.Nil(t, g.loadSpool())
+	require.Equal(t, f.spool, g.spool)
+	require.Equal(t, uint64(3), g.seq)
+
+	g.remove(g.spool[0])
+	require.Len(t, g.spool, 1)
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	require.Len(t, files, 1)
+}
+
+func TestForwardPostRefusedBatch(t *testing.T) {
+	var lock sync.Mutex
+	var sizes []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		require.Nil(t, err)
+		var batch []types.FalcoPayload
+		require.Nil(t, json.NewDecoder(zr).Decode(&batch))
+		lock.Lock()
+		defer lock.Unlock()
+		sizes = append(sizes, len(batch))
+		// the batches of a single event are too large for the hub
+		if len(batch) == 1 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+	defer ts.Close()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields[ForwardVersionField] = "1.0.0"
+	data, err := json.Marshal(f)
+	require.Nil(t, err)
+
+	// two events fit in a batch, not three
+	config := &types.Configuration{
+		Forward: types.ForwardOutputConfig{Address: ts.URL, BatchSize: 100, MaxBytes: forwardBatchOverhead + 3*(len(data)+1) - 1, FlushInterval: 60, MaxSpooledBatches: 10, RetryInterval: 60, CheckCert: true},
+	}
+	stats := &types.Statistics{Forward: new(expvar.Map).Init()}
+	promStats := &types.PromStatistics{
+		Outputs:          prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, []string{"destination", "status"}),
+		OutputQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_queue_depth"}, []string{"destination"}),
+	}
+	client, err := NewForwardClient(config, stats, promStats, nil, nil, "1.0.0")
+	require.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.Nil(t, client.ForwardPost(context.Background(), f))
+	}
+	client.forwarder.Lock()
+	client.spoolForwardBatch()
+	client.forwarder.Unlock()
+
+	// the refused batch is dropped instead of being retried
+	require.Eventually(t, func() bool {
+		client.forwarder.Lock()
+		defer client.forwarder.Unlock()
+		return len(client.forwarder.spool) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []int{2, 2, 1}, sizes)
+	require.Equal(t, "4", stats.Forward.Get(OK).String())
+	require.Equal(t, "1", stats.Forward.Get(Error).String())
+}
+
+func TestForwardClientClose(t *testing.T) {
+	var lock sync.Mutex
+	var received int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		require.Nil(t, err)
+		var batch []types.FalcoPayload
+		require.Nil(t, json.NewDecoder(zr).Decode(&batch))
+		lock.Lock()
+		defer lock.Unlock()
+		received += len(batch)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{
+		Forward: types.ForwardOutputConfig{Address: ts.URL, BatchSize: 100, FlushInterval: 3600, MaxSpooledBatches: 10, RetryInterval: 60, CheckCert: true},
+	}
+	stats := &types.Statistics{Forward: new(expvar.Map).Init()}
+	promStats := &types.PromStatistics{
+		Outputs:          prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, []string{"destination", "status"}),
+		OutputQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_queue_depth"}, []string{"destination"}),
+	}
+	client, err := NewForwardClient(config, stats, promStats, nil, nil, "1.0.0")
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	for i := 0; i < 3; i++ {
+		require.Nil(t, client.ForwardPost(context.Background(), f))
+	}
+
+	// the current batch is sent without waiting for the flush interval, before Close returns
+	client.Close(context.Background())
+	client.Close(context.Background())
+	lock.Lock()
+	require.Equal(t, 3, received)
+	lock.Unlock()
+	require.True(t, client.forwarder.stopped())
+}
+
+func TestForwardClientCloseTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the hub hangs until the post is canceled
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{
+		Forward: types.ForwardOutputConfig{Address: ts.URL, BatchSize: 100, FlushInterval: 3600, MaxSpooledBatches: 10, RetryInterval: 60, CheckCert: true},
+	}
+	stats := &types.Statistics{Forward: new(expvar.Map).Init()}
+	promStats := &types.PromStatistics{
+		Outputs:          prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, []string{"destination", "status"}),
+		OutputQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_queue_depth"}, []string{"destination"}),
+	}
+	client, err := NewForwardClient(config, stats, promStats, nil, nil, "1.0.0")
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Nil(t, client.ForwardPost(context.Background(), f))
+
+	// the spool isn't sent before the timeout, the hanging post is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	require.Equal(t, 1, client.FlushForward(ctx))
+	client.Close(ctx)
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the post isn't canceled")
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -106,13 +107,13 @@ func NewGCPClient(config *types.Configuration, stats *types.Statistics, promStat
 }
 
 // GCPCallCloudFunction calls the given Cloud Function
-func (c *Client) GCPCallCloudFunction(falcopayload types.FalcoPayload) {
+func (c *Client) GCPCallCloudFunction(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPCloudFunctions.Add(Total, 1)
 
 	payload, _ := json.Marshal(falcopayload)
 	data := string(payload)
 
-	result, err := c.GCPCloudFunctionsClient.CallFunction(context.Background(), &gcpfunctionspb.CallFunctionRequest{
+	result, err := c.GCPCloudFunctionsClient.CallFunction(ctx, &gcpfunctionspb.CallFunctionRequest{
 		Name: c.Config.GCP.CloudFunctions.Name,
 		Data: data,
 	}, gax.WithGRPCOptions())
@@ -123,17 +124,17 @@ func (c *Client) GCPCallCloudFunction(falcopayload types.FalcoPayload) {
 		go c.CountMetric("outputs", 1, []string{"output:gcpcloudfunctions", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "gcpcloudfunctions", "status": Error}).Inc()
 
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : GCPCloudFunctions - Call CloudFunction OK (%v)\n", result.ExecutionId)
+	logger.ForOutput("GCPCloudFunctions").WithContext(ctx).InfoLimited("Call CloudFunction OK", logger.F("execution_id", result.ExecutionId))
 	c.Stats.GCPCloudFunctions.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:gcpcloudfunctions", "status:ok"})
-
+	return nil
 }
 
 // GCPPublishTopic sends a message to a GCP PubSub Topic
-func (c *Client) GCPPublishTopic(falcopayload types.FalcoPayload) {
+func (c *Client) GCPPublishTopic(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPPubSub.Add(Total, 1)
 
 	payload, _ := json.Marshal(falcopayload)
@@ -142,25 +143,26 @@ func (c *Client) GCPPublishTopic(falcopayload types.FalcoPayload) {
 		Attributes: c.Config.GCP.PubSub.CustomAttributes,
 	}
 
-	result := c.GCPTopicClient.Publish(context.Background(), message)
-	id, err := result.Get(context.Background())
+	result := c.GCPTopicClient.Publish(ctx, message)
+	id, err := result.Get(ctx)
 	if err != nil {
 		log.Printf("[ERROR] : GCPPubSub - %v - %v\n", "Error while publishing message", err.Error())
 		c.Stats.GCPPubSub.Add(Error, 1)
 		go c.CountMetric("outputs", 1, []string{"output:gcppubsub", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "gcppubsub", "status": Error}).Inc()
 
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : GCPPubSub - Send to topic OK (%v)\n", id)
+	logger.ForOutput("GCPPubSub").WithContext(ctx).InfoLimited("Send to topic OK", logger.F("id", id))
 	c.Stats.GCPPubSub.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:gcppubsub", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "gcppubsub", "status": OK}).Inc()
+	return nil
 }
 
 // UploadGCS upload payload to
-func (c *Client) UploadGCS(falcopayload types.FalcoPayload) {
+func (c *Client) UploadGCS(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPStorage.Add(Total, 1)
 
 	payload, _ := json.Marshal(falcopayload)
@@ -172,7 +174,7 @@ func (c *Client) UploadGCS(falcopayload types.FalcoPayload) {
 	}
 
 	key := fmt.Sprintf("%s/%s/%s.json", prefix, t.Format("2006-01-02"), t.Format(time.RFC3339Nano))
-	bucketWriter := c.GCSStorageClient.Bucket(c.Config.GCP.Storage.Bucket).Object(key).NewWriter(context.Background())
+	bucketWriter := c.GCSStorageClient.Bucket(c.Config.GCP.Storage.Bucket).Object(key).NewWriter(ctx)
 	defer bucketWriter.Close()
 	_, err := bucketWriter.Write(payload)
 	if err != nil {
@@ -180,11 +182,12 @@ func (c *Client) UploadGCS(falcopayload types.FalcoPayload) {
 		c.Stats.GCPStorage.Add(Error, 1)
 		go c.CountMetric("outputs", 1, []string{"output:gcpstorage", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "gcpstorage", "status": Error}).Inc()
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : GCPStorage - Upload to bucket OK \n")
+	logger.ForOutput("GCPStorage").WithContext(ctx).InfoLimited("Upload to bucket OK")
 	c.Stats.GCPStorage.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:gcpstorage", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "gcpstorage", "status": OK}).Inc()
+	return nil
 }
@@ -1,32 +1,33 @@
 package outputs
 
 import (
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // CloudRunFunctionPost call Cloud Function
-func (c *Client) CloudRunFunctionPost(falcopayload types.FalcoPayload) {
+func (c *Client) CloudRunFunctionPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPCloudRun.Add(Total, 1)
 
+	var opts []RequestOption
 	if c.Config.GCP.CloudRun.JWT != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.AddHeader(AuthorizationHeaderKey, "Bearer "+c.Config.GCP.CloudRun.JWT)
+		opts = append(opts, WithHeader(AuthorizationHeaderKey, "Bearer "+c.Config.GCP.CloudRun.JWT))
 	}
 
-	err := c.Post(falcopayload)
+	err := c.Post(ctx, falcopayload, opts...)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:gcpcloudrun", "status:error"})
 		c.Stats.GCPCloudRun.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "gcpcloudrun", "status": Error}).Inc()
 		log.Printf("[ERROR] : GCPCloudRun - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:gcpcloudrun", "status:ok"})
 	c.Stats.GCPCloudRun.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "gcpcloudrun", "status": OK}).Inc()
+	return nil
 }
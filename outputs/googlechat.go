@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"log"
 	"strings"
 
@@ -96,19 +97,20 @@ func newGooglechatPayload(falcopayload types.FalcoPayload, config *types.Configu
 }
 
 // GooglechatPost posts event to Google Chat
-func (c *Client) GooglechatPost(falcopayload types.FalcoPayload) {
+func (c *Client) GooglechatPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GoogleChat.Add(Total, 1)
 
-	err := c.Post(newGooglechatPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newGooglechatPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:googlechat", "status:error"})
 		c.Stats.GoogleChat.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "googlechat", "status": Error}).Inc()
 		log.Printf("[ERROR] : GoogleChat - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:googlechat", "status:ok"})
 	c.Stats.GoogleChat.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "googlechat", "status": OK}).Inc()
+	return nil
 }
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log"
 	"strings"
@@ -88,26 +89,26 @@ func newGotifyPayload(falcopayload types.FalcoPayload, config *types.Configurati
 }
 
 // GotifyPost posts event to Gotify
-func (c *Client) GotifyPost(falcopayload types.FalcoPayload) {
+func (c *Client) GotifyPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Gotify.Add(Total, 1)
 
+	var opts []RequestOption
 	if c.Config.Gotify.Token != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.AddHeader("X-Gotify-Key", c.Config.Gotify.Token)
+		opts = append(opts, WithHeader("X-Gotify-Key", c.Config.Gotify.Token))
 	}
 
-	err := c.Post(newGotifyPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newGotifyPayload(falcopayload, c.Config), opts...)
 	if err != nil {
 		c.setGotifyErrorMetrics()
 		log.Printf("[ERROR] : Gotify - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:gotify", "status:ok"})
 	c.Stats.Gotify.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "gotify", "status": OK}).Inc()
+	return nil
 }
 
 // setGotifyErrorMetrics set the error stats
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -73,50 +74,50 @@ func newGrafanaOnCallPayload(falcopayload types.FalcoPayload, config *types.Conf
 }
 
 // GrafanaPost posts event to grafana
-func (c *Client) GrafanaPost(falcopayload types.FalcoPayload) {
+func (c *Client) GrafanaPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Grafana.Add(Total, 1)
-	c.ContentType = GrafanaContentType
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
-	c.AddHeader("Authorization", "Bearer "+c.Config.Grafana.APIKey)
+	opts := []RequestOption{
+		WithContentType(GrafanaContentType),
+		WithHeader("Authorization", "Bearer "+c.Config.Grafana.APIKey),
+	}
 	for i, j := range c.Config.Grafana.CustomHeaders {
-		c.AddHeader(i, j)
+		opts = append(opts, WithHeader(i, j))
 	}
 
-	err := c.Post(newGrafanaPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newGrafanaPayload(falcopayload, c.Config), opts...)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:grafana", "status:error"})
 		c.Stats.Grafana.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "grafana", "status": Error}).Inc()
 		log.Printf("[ERROR] : Grafana - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:grafana", "status:ok"})
 	c.Stats.Grafana.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "grafana", "status": OK}).Inc()
+	return nil
 }
 
 // GrafanaOnCallPost posts event to grafana onCall
-func (c *Client) GrafanaOnCallPost(falcopayload types.FalcoPayload) {
+func (c *Client) GrafanaOnCallPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GrafanaOnCall.Add(Total, 1)
-	c.ContentType = GrafanaContentType
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
+	opts := []RequestOption{WithContentType(GrafanaContentType)}
 	for i, j := range c.Config.GrafanaOnCall.CustomHeaders {
-		c.AddHeader(i, j)
+		opts = append(opts, WithHeader(i, j))
 	}
 
-	err := c.Post(newGrafanaOnCallPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newGrafanaOnCallPayload(falcopayload, c.Config), opts...)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:grafanaoncall", "status:error"})
 		c.Stats.Grafana.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "grafanaoncall", "status": Error}).Inc()
 		log.Printf("[ERROR] : Grafana OnCall - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:grafanaoncall", "status:ok"})
 	c.Stats.Grafana.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "grafanaoncall", "status": OK}).Inc()
+	return nil
 }
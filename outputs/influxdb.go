@@ -1,14 +1,39 @@
 package outputs
 
 import (
+	"context"
 	"log"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 type influxdbPayload string
 
+// influxdbFieldReplacer escapes the string field values of the line protocol
+var influxdbFieldReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// influxdbTimestamp returns the timestamp of the event in the precision of the writes, the points with the same
+// measurement and tags would overwrite each other with the time of their write
+func influxdbTimestamp(t time.Time, precision string) int64 {
+	switch precision {
+	case "u", "us":
+		return t.UnixMicro()
+	case "ms":
+		return t.UnixMilli()
+	case "s":
+		return t.Unix()
+	case "m":
+		return t.Unix() / 60
+	case "h":
+		return t.Unix() / 3600
+	default:
+		return t.UnixNano()
+	}
+}
+
 func newInfluxdbPayload(falcopayload types.FalcoPayload, config *types.Configuration) influxdbPayload {
 	s := "events,rule=" + strings.Replace(falcopayload.Rule, " ", "_", -1) + ",priority=" + falcopayload.Priority.String() + ",source=" + falcopayload.Source
 
@@ -29,34 +54,53 @@ func newInfluxdbPayload(falcopayload types.FalcoPayload, config *types.Configura
 		s += ",tags=" + strings.Join(falcopayload.Tags, "_")
 	}
 
-	s += " value=\"" + falcopayload.Output + "\""
+	s += " value=\"" + influxdbFieldReplacer.Replace(falcopayload.Output) + "\""
+
+	if !falcopayload.Time.IsZero() {
+		s += " " + strconv.FormatInt(influxdbTimestamp(falcopayload.Time, config.Influxdb.Precision), 10)
+	}
 
 	return influxdbPayload(s)
 }
 
+// newInfluxdbBatchPayload writes the events of a batch at once, one line per event.
+func newInfluxdbBatchPayload(events []types.FalcoPayload, config *types.Configuration) influxdbPayload {
+	lines := make([]string, 0, len(events))
+	for _, i := range events {
+		lines = append(lines, string(newInfluxdbPayload(i, config)))
+	}
+	return influxdbPayload(strings.Join(lines, "\n"))
+}
+
 // InfluxdbPost posts event to InfluxDB
-func (c *Client) InfluxdbPost(falcopayload types.FalcoPayload) {
+func (c *Client) InfluxdbPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Influxdb.Add(Total, 1)
 
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
-	c.AddHeader("Accept", "application/json")
+	opts := []RequestOption{WithHeader("Accept", "application/json")}
 
 	if c.Config.Influxdb.Token != "" {
-		c.AddHeader("Authorization", "Token "+c.Config.Influxdb.Token)
+		opts = append(opts, WithHeader("Authorization", "Token "+c.Config.Influxdb.Token))
 	}
 
-	err := c.Post(newInfluxdbPayload(falcopayload, c.Config))
+	var err error
+	if c.Config.Influxdb.Batch.MaxSize > 1 {
+		err = c.getBatcher("influxdb", c.Config.Influxdb.Batch, func(ctx context.Context, events []types.FalcoPayload) error {
+			return c.Post(ctx, newInfluxdbBatchPayload(events, c.Config), opts...)
+		}).add(ctx, falcopayload)
+	} else {
+		err = c.Post(ctx, newInfluxdbPayload(falcopayload, c.Config), opts...)
+	}
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:influxdb", "status:error"})
 		c.Stats.Influxdb.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "influxdb", "status": Error}).Inc()
 		log.Printf("[ERROR] : InfluxDB - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:influxdb", "status:ok"})
 	c.Stats.Influxdb.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "influxdb", "status": OK}).Inc()
+	return nil
 }
@@ -3,6 +3,7 @@ package outputs
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -10,7 +11,7 @@ import (
 )
 
 func TestNewInfluxdbPayload(t *testing.T) {
-	expectedOutput := `"events,rule=Test_rule,priority=Debug,source=syscalls,proc.name=falcosidekick,hostname=test-host,tags=test_example value=\"This is a test from falcosidekick\""`
+	expectedOutput := `"events,rule=Test_rule,priority=Debug,source=syscalls,proc.name=falcosidekick,hostname=test-host,tags=test_example value=\"This is a test from falcosidekick\" 978311400000000000"`
 	var f types.FalcoPayload
 	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
 
@@ -19,3 +20,22 @@ func TestNewInfluxdbPayload(t *testing.T) {
 
 	require.Equal(t, string(influxdbPayload), expectedOutput)
 }
+
+func TestNewInfluxdbBatchPayload(t *testing.T) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields = nil
+	f.Tags = nil
+	second := f
+	second.Output = "A \"quoted\" C:\\path\nand a new line"
+	second.Time = f.Time.Add(time.Second)
+
+	// the events with the same tags are distinct points, with their own timestamps
+	payload := newInfluxdbBatchPayload([]types.FalcoPayload{f, second}, &types.Configuration{})
+	require.Equal(t, influxdbPayload(`events,rule=Test_rule,priority=Debug,source=syscalls,hostname=test-host value="This is a test from falcosidekick" 978311400000000000
+events,rule=Test_rule,priority=Debug,source=syscalls,hostname=test-host value="A \"quoted\" C:\\path\nand a new line" 978311401000000000`), payload)
+
+	c := &types.Configuration{}
+	c.Influxdb.Precision = "s"
+	require.Equal(t, influxdbPayload(`events,rule=Test_rule,priority=Debug,source=syscalls,hostname=test-host value="This is a test from falcosidekick" 978311400`), newInfluxdbPayload(f, c))
+}
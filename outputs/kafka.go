@@ -13,9 +13,11 @@ import (
 
 	"github.com/DataDog/datadog-go/statsd"
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
 	"github.com/segmentio/kafka-go/sasl/plain"
 	"github.com/segmentio/kafka-go/sasl/scram"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -31,49 +33,17 @@ func NewKafkaClient(config *types.Configuration, stats *types.Statistics, promSt
 	}
 
 	if config.Kafka.TLS {
-		caCertPool, err := x509.SystemCertPool()
-
-		if err != nil {
-			log.Printf("[ERROR] : Kafka - failed to initialize root CAs: %v", err)
-		}
-
-		transport.TLS = &tls.Config{
-			RootCAs:    caCertPool,
-			MinVersion: tls.VersionTLS12,
-		}
+		transport.TLS = NewKafkaTLSConfig()
 	}
 
-	var err error
-
 	if config.Kafka.SASL != "" {
-		saslMode := strings.ToUpper(config.Kafka.SASL)
-		switch {
-		case saslMode == "PLAIN":
-			transport.SASL = plain.Mechanism{
-				Username: config.Kafka.Username,
-				Password: config.Kafka.Password,
-			}
-		case strings.HasPrefix(saslMode, "SCRAM_"):
-			algo := strings.TrimPrefix(config.Kafka.SASL, "SCRAM_")
-			switch algo {
-			case "SHA256":
-				transport.SASL, err = scram.Mechanism(scram.SHA256, config.Kafka.Username, config.Kafka.Password)
-			case "SHA512":
-				transport.SASL, err = scram.Mechanism(scram.SHA512, config.Kafka.Username, config.Kafka.Password)
-			default:
-				err = fmt.Errorf("unsupported SASL SCRAM algorithm %q", algo)
-			}
-			if err != nil {
-				err = fmt.Errorf("failed to initialize SASL SCRAM %q: %w", algo, err)
-			}
-		default:
-			err = fmt.Errorf("unsupported SASL mode: %q", config.Kafka.SASL)
+		var err error
+		transport.SASL, err = NewKafkaSASLMechanism(config.Kafka.SASL, config.Kafka.Username, config.Kafka.Password)
+		if err != nil {
+			log.Printf("[ERROR] : Kafka - %v\n", err)
+			return nil, err
 		}
 	}
-	if err != nil {
-		log.Printf("[ERROR] : Kafka - %v\n", err)
-		return nil, err
-	}
 
 	kafkaWriter := &kafka.Writer{
 		Addr:                   kafka.TCP(strings.Split(config.Kafka.HostPort, ",")...),
@@ -142,15 +112,60 @@ func NewKafkaClient(config *types.Configuration, stats *types.Statistics, promSt
 	return client, nil
 }
 
+// NewKafkaTLSConfig returns the TLS configuration to connect to the Kafka brokers, using the system CAs.
+func NewKafkaTLSConfig() *tls.Config {
+	caCertPool, err := x509.SystemCertPool()
+	if err != nil {
+		log.Printf("[ERROR] : Kafka - failed to initialize root CAs: %v", err)
+	}
+
+	return &tls.Config{
+		RootCAs:    caCertPool,
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// NewKafkaSASLMechanism returns the SASL mechanism to authenticate to the Kafka brokers (PLAIN, SCRAM_SHA256 or SCRAM_SHA512).
+func NewKafkaSASLMechanism(mode, username, password string) (sasl.Mechanism, error) {
+	var mechanism sasl.Mechanism
+	var err error
+
+	saslMode := strings.ToUpper(mode)
+	switch {
+	case saslMode == "PLAIN":
+		mechanism = plain.Mechanism{
+			Username: username,
+			Password: password,
+		}
+	case strings.HasPrefix(saslMode, "SCRAM_"):
+		algo := strings.TrimPrefix(mode, "SCRAM_")
+		switch algo {
+		case "SHA256":
+			mechanism, err = scram.Mechanism(scram.SHA256, username, password)
+		case "SHA512":
+			mechanism, err = scram.Mechanism(scram.SHA512, username, password)
+		default:
+			err = fmt.Errorf("unsupported SASL SCRAM algorithm %q", algo)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to initialize SASL SCRAM %q: %w", algo, err)
+		}
+	default:
+		err = fmt.Errorf("unsupported SASL mode: %q", mode)
+	}
+
+	return mechanism, err
+}
+
 // KafkaProduce sends a message to a Apach Kafka Topic
-func (c *Client) KafkaProduce(falcopayload types.FalcoPayload) {
+func (c *Client) KafkaProduce(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Kafka.Add(Total, 1)
 
 	falcoMsg, err := json.Marshal(falcopayload)
 	if err != nil {
 		c.incrKafkaErrorMetrics(1)
 		log.Printf("[ERROR] : Kafka - %v - %v\n", "failed to marshalling message", err.Error())
-		return
+		return err
 	}
 
 	kafkaMsg := kafka.Message{
@@ -158,15 +173,16 @@ func (c *Client) KafkaProduce(falcopayload types.FalcoPayload) {
 	}
 
 	// Errors are logged/captured via handleKafkaCompletion function, ignore here
-	err = c.KafkaProducer.WriteMessages(context.Background(), kafkaMsg)
+	err = c.KafkaProducer.WriteMessages(ctx, kafkaMsg)
 	if err != nil {
 		c.incrKafkaErrorMetrics(1)
 		log.Printf("[ERROR] : Kafka - %v\n", err.Error())
-		return
-	} else {
-		c.incrKafkaSuccessMetrics(1)
-		log.Printf("[INFO]  : Kafka - Publish OK\n")
+		return err
 	}
+
+	c.incrKafkaSuccessMetrics(1)
+	c.Logger(ctx).InfoLimited("Publish OK")
+	return nil
 }
 
 // handleKafkaCompletion is called when a message is produced
@@ -176,7 +192,7 @@ func (c *Client) handleKafkaCompletion(messages []kafka.Message, err error) {
 		log.Printf("[ERROR] : Kafka (%d) - %v\n", len(messages), err)
 	} else {
 		c.incrKafkaSuccessMetrics(len(messages))
-		log.Printf("[INFO]  : Kafka (%d) - Publish OK\n", len(messages))
+		logger.ForOutput(c.OutputType).InfoLimited("Publish OK", logger.F("messages", len(messages)))
 	}
 }
 
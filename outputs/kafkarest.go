@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -20,7 +21,7 @@ type KafkaRestPayload struct {
 }
 
 // KafkaRestPost posts event the Kafka Rest Proxy
-func (c *Client) KafkaRestPost(falcopayload types.FalcoPayload) {
+func (c *Client) KafkaRestPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.KafkaRest.Add(Total, 1)
 
 	var version int
@@ -37,28 +38,27 @@ func (c *Client) KafkaRestPost(falcopayload types.FalcoPayload) {
 		c.Stats.KafkaRest.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "kafkarest", "status": Error}).Inc()
 		log.Printf("[ERROR] : Kafka Rest - %v - %v\n", "failed to marshalling message", err.Error())
-		return
+		return err
 	}
 
-	c.ContentType = fmt.Sprintf("application/vnd.kafka.binary.v%d+json", version)
-
 	payload := KafkaRestPayload{
 		Records: []Records{{
 			Value: base64.StdEncoding.EncodeToString(falcoMsg),
 		}},
 	}
 
-	err = c.Post(payload)
+	err = c.Post(ctx, payload, WithContentType(fmt.Sprintf("application/vnd.kafka.binary.v%d+json", version)))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:kafkarest", "status:error"})
 		c.Stats.KafkaRest.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "kafkarest", "status": Error}).Inc()
 		log.Printf("[ERROR] : Kafka Rest - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:kafkarest", "status:ok"})
 	c.Stats.KafkaRest.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "kafkarest", "status": OK}).Inc()
+	return nil
 }
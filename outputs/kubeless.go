@@ -11,6 +11,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -57,7 +58,7 @@ func NewKubelessClient(config *types.Configuration, stats *types.Statistics, pro
 }
 
 // KubelessCall .
-func (c *Client) KubelessCall(falcopayload types.FalcoPayload) {
+func (c *Client) KubelessCall(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Kubeless.Add(Total, 1)
 
 	if c.Config.Kubeless.Kubeconfig != "" {
@@ -69,35 +70,34 @@ func (c *Client) KubelessCall(falcopayload types.FalcoPayload) {
 		req.SetHeader(KubelessEventTypeKey, KubelessEventTypeValue)
 		req.SetHeader(KubelessEventNamespaceKey, c.Config.Kubeless.Namespace)
 
-		res := req.Do(context.TODO())
+		res := req.Do(ctx)
 		rawbody, err := res.Raw()
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:kubeless", "status:error"})
 			c.Stats.Kubeless.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "kubeless", "status": Error}).Inc()
 			log.Printf("[ERROR] : Kubeless - %v\n", err)
-			return
+			return err
 		}
 		log.Printf("[INFO]  : Kubeless - Function Response : %v\n", string(rawbody))
 	} else {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.AddHeader(KubelessEventIDKey, uuid.New().String())
-		c.AddHeader(KubelessEventTypeKey, KubelessEventTypeValue)
-		c.AddHeader(KubelessEventNamespaceKey, c.Config.Kubeless.Namespace)
-		c.ContentType = KubelessContentType
-
-		err := c.Post(falcopayload)
+		err := c.Post(ctx, falcopayload,
+			WithHeader(KubelessEventIDKey, uuid.New().String()),
+			WithHeader(KubelessEventTypeKey, KubelessEventTypeValue),
+			WithHeader(KubelessEventNamespaceKey, c.Config.Kubeless.Namespace),
+			WithContentType(KubelessContentType),
+		)
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:kubeless", "status:error"})
 			c.Stats.Kubeless.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "kubeless", "status": Error}).Inc()
 			log.Printf("[ERROR] : Kubeless - %v\n", err)
-			return
+			return err
 		}
 	}
-	log.Printf("[INFO]  : Kubeless - Call Function \"%v\" OK\n", c.Config.Kubeless.Function)
+	c.Logger(ctx).InfoLimited("Call Function OK", logger.F("function", c.Config.Kubeless.Function))
 	go c.CountMetric(Outputs, 1, []string{"output:kubeless", "status:ok"})
 	c.Stats.Kubeless.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "kubeless", "status": OK}).Inc()
+	return nil
 }
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -62,36 +63,62 @@ func newLokiPayload(falcopayload types.FalcoPayload, config *types.Configuration
 	}}
 }
 
-// LokiPost posts event to Loki
-func (c *Client) LokiPost(falcopayload types.FalcoPayload) {
-	c.Stats.Loki.Add(Total, 1)
-	c.ContentType = LokiContentType
-	if c.Config.Loki.Tenant != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.AddHeader("X-Scope-OrgID", c.Config.Loki.Tenant)
+// newLokiBatchPayload pushes the events of a batch at once, the events with the same labels share a stream.
+func newLokiBatchPayload(events []types.FalcoPayload, config *types.Configuration) lokiPayload {
+	var payload lokiPayload
+	streams := make(map[string]int)
+	for _, i := range events {
+		stream := newLokiPayload(i, config).Streams[0]
+		// the keys of the maps are printed sorted
+		labels := fmt.Sprint(stream.Stream)
+		if j, ok := streams[labels]; ok {
+			payload.Streams[j].Values = append(payload.Streams[j].Values, stream.Values...)
+			continue
+		}
+		streams[labels] = len(payload.Streams)
+		payload.Streams = append(payload.Streams, stream)
 	}
+	return payload
+}
 
-	if c.Config.Loki.User != "" && c.Config.Loki.APIKey != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.BasicAuth(c.Config.Loki.User, c.Config.Loki.APIKey)
-	}
+// LokiPost posts event to Loki
+func (c *Client) LokiPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.Loki.Add(Total, 1)
 
-	for i, j := range c.Config.Loki.CustomHeaders {
-		c.AddHeader(i, j)
+	var err error
+	if c.Config.Loki.Batch.MaxSize > 1 {
+		err = c.getBatcher("loki", c.Config.Loki.Batch, func(ctx context.Context, events []types.FalcoPayload) error {
+			return c.Post(ctx, newLokiBatchPayload(events, c.Config), c.lokiOptions()...)
+		}).add(ctx, falcopayload)
+	} else {
+		err = c.Post(ctx, newLokiPayload(falcopayload, c.Config), c.lokiOptions()...)
 	}
-
-	err := c.Post(newLokiPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:loki", "status:error"})
 		c.Stats.Loki.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "loki", "status": Error}).Inc()
 		log.Printf("[ERROR] : Loki - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:loki", "status:ok"})
 	c.Stats.Loki.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "loki", "status": OK}).Inc()
+	return nil
+}
+
+func (c *Client) lokiOptions() []RequestOption {
+	opts := []RequestOption{WithContentType(LokiContentType)}
+	if c.Config.Loki.Tenant != "" {
+		opts = append(opts, WithHeader("X-Scope-OrgID", c.Config.Loki.Tenant))
+	}
+
+	if c.Config.Loki.User != "" && c.Config.Loki.APIKey != "" {
+		opts = append(opts, WithBasicAuth(c.Config.Loki.User, c.Config.Loki.APIKey))
+	}
+
+	for i, j := range c.Config.Loki.CustomHeaders {
+		opts = append(opts, WithHeader(i, j))
+	}
+	return opts
 }
@@ -31,3 +31,19 @@ func TestNewLokiPayload(t *testing.T) {
 
 	require.Equal(t, output, expectedOutput)
 }
+
+func TestNewLokiBatchPayload(t *testing.T) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	g := f
+	g.Rule = "Other rule"
+
+	output := newLokiBatchPayload([]types.FalcoPayload{f, g, f}, &types.Configuration{})
+
+	// the events with the same labels share a stream
+	require.Len(t, output.Streams, 2)
+	require.Equal(t, "Test rule", output.Streams[0].Stream["rule"])
+	require.Len(t, output.Streams[0].Values, 2)
+	require.Equal(t, "Other rule", output.Streams[1].Stream["rule"])
+	require.Len(t, output.Streams[1].Values, 1)
+}
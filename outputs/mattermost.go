@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"log"
 	"strings"
 
@@ -119,20 +120,21 @@ func newMattermostPayload(falcopayload types.FalcoPayload, config *types.Configu
 }
 
 // MattermostPost posts event to Mattermost
-func (c *Client) MattermostPost(falcopayload types.FalcoPayload) {
+func (c *Client) MattermostPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Mattermost.Add(Total, 1)
 
-	err := c.Post(newMattermostPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newMattermostPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:mattermost", "status:error"})
 		c.Stats.Mattermost.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "mattermost", "status": Error}).Inc()
 		log.Printf("[ERROR] : Mattermost - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:mattermost", "status:ok"})
 	c.Stats.Mattermost.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "mattermost", "status": OK}).Inc()
+	return nil
 }
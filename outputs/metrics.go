@@ -0,0 +1,46 @@
+package outputs
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meter creates the instruments of the outputs, their measurements are dropped unless the OTLP export is enabled
+var meter = otel.Meter("github.com/falcosecurity/falcosidekick/outputs")
+
+var (
+	// otlpCounters are the counters of the metrics of CountMetric, by name
+	otlpCounters     = make(map[string]metric.Int64Counter)
+	otlpCountersLock sync.Mutex
+
+	otlpBatchSize, _ = meter.Int64Histogram("falcosidekick.outputs.batch_size")
+)
+
+// countOTLPMetric adds the value to the OTLP counter of the metric, the tags "key:value" become its attributes.
+func countOTLPMetric(metricName string, value int64, tags []string) {
+	name := "falcosidekick." + metricName
+	otlpCountersLock.Lock()
+	counter, ok := otlpCounters[name]
+	if !ok {
+		var err error
+		counter, err = meter.Int64Counter(name)
+		if err != nil {
+			otlpCountersLock.Unlock()
+			return
+		}
+		otlpCounters[name] = counter
+	}
+	otlpCountersLock.Unlock()
+
+	attributes := make([]attribute.KeyValue, 0, len(tags))
+	for _, i := range tags {
+		key, value, _ := strings.Cut(i, ":")
+		attributes = append(attributes, attribute.String(key, value))
+	}
+	counter.Add(context.Background(), value, metric.WithAttributes(attributes...))
+}
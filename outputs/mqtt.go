@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"crypto/tls"
 	"log"
 
@@ -46,7 +47,7 @@ func NewMQTTClient(config *types.Configuration, stats *types.Statistics, promSta
 }
 
 // MQTTPublish .
-func (c *Client) MQTTPublish(falcopayload types.FalcoPayload) {
+func (c *Client) MQTTPublish(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.MQTT.Add(Total, 1)
 
 	t := c.MQTTClient.Connect()
@@ -56,7 +57,7 @@ func (c *Client) MQTTPublish(falcopayload types.FalcoPayload) {
 		c.Stats.MQTT.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "mqtt", "status": err.Error()}).Inc()
 		log.Printf("[ERROR] : %s - %v\n", MQTT, err.Error())
-		return
+		return err
 	}
 	defer c.MQTTClient.Disconnect(100)
 	if err := c.MQTTClient.Publish(c.Config.MQTT.Topic, byte(c.Config.MQTT.QOS), c.Config.MQTT.Retained, falcopayload.String()).Error(); err != nil {
@@ -64,11 +65,12 @@ func (c *Client) MQTTPublish(falcopayload types.FalcoPayload) {
 		c.Stats.MQTT.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "mqtt", "status": Error}).Inc()
 		log.Printf("[ERROR] : %s - %v\n", MQTT, err.Error())
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : %s - Message published\n", MQTT)
 	go c.CountMetric(Outputs, 1, []string{"output:mqtt", "status:ok"})
 	c.Stats.MQTT.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "mqtt", "status": OK}).Inc()
+	return nil
 }
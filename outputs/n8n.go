@@ -1,38 +1,37 @@
 package outputs
 
 import (
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // N8NPost posts event to an URL
-func (c *Client) N8NPost(falcopayload types.FalcoPayload) {
+func (c *Client) N8NPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.N8N.Add(Total, 1)
 
+	var opts []RequestOption
 	if c.Config.N8N.User != "" && c.Config.N8N.Password != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.BasicAuth(c.Config.N8N.User, c.Config.N8N.Password)
+		opts = append(opts, WithBasicAuth(c.Config.N8N.User, c.Config.N8N.Password))
 	}
 
 	if c.Config.N8N.HeaderAuthName != "" && c.Config.N8N.HeaderAuthValue != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.AddHeader(c.Config.N8N.HeaderAuthName, c.Config.N8N.HeaderAuthValue)
+		opts = append(opts, WithHeader(c.Config.N8N.HeaderAuthName, c.Config.N8N.HeaderAuthValue))
 	}
 
-	err := c.Post(falcopayload)
+	err := c.Post(ctx, falcopayload, opts...)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:n8n", "status:error"})
 		c.Stats.N8N.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "n8n", "status": Error}).Inc()
 		log.Printf("[ERROR] : N8N - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:n8n", "status:ok"})
 	c.Stats.N8N.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "n8n", "status": OK}).Inc()
+	return nil
 }
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"regexp"
@@ -14,14 +15,14 @@ import (
 var slugRegularExpression = regexp.MustCompile("[^a-z0-9]+")
 
 // NatsPublish publishes event to NATS
-func (c *Client) NatsPublish(falcopayload types.FalcoPayload) {
+func (c *Client) NatsPublish(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Nats.Add(Total, 1)
 
 	nc, err := nats.Connect(c.EndpointURL.String())
 	if err != nil {
 		c.setNatsErrorMetrics()
 		log.Printf("[ERROR] : NATS - %v\n", err)
-		return
+		return err
 	}
 	defer nc.Flush()
 	defer nc.Close()
@@ -31,20 +32,21 @@ func (c *Client) NatsPublish(falcopayload types.FalcoPayload) {
 	if err != nil {
 		c.setStanErrorMetrics()
 		log.Printf("[ERROR] : STAN - %v\n", err.Error())
-		return
+		return err
 	}
 
 	err = nc.Publish("falco."+strings.ToLower(falcopayload.Priority.String())+"."+r, j)
 	if err != nil {
 		c.setNatsErrorMetrics()
 		log.Printf("[ERROR] : NATS - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric("outputs", 1, []string{"output:nats", "status:ok"})
 	c.Stats.Nats.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "nats", "status": OK}).Inc()
-	log.Printf("[INFO]  : NATS - Publish OK\n")
+	c.Logger(ctx).InfoLimited("Publish OK")
+	return nil
 }
 
 // setNatsErrorMetrics set the error stats
@@ -1,39 +1,37 @@
 package outputs
 
 import (
-	"encoding/base64"
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // NodeRedPost posts event to Slack
-func (c *Client) NodeRedPost(falcopayload types.FalcoPayload) {
+func (c *Client) NodeRedPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.NodeRed.Add(Total, 1)
 
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
+	var opts []RequestOption
 	if c.Config.NodeRed.User != "" && c.Config.NodeRed.Password != "" {
-		c.AddHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.Config.NodeRed.User+":"+c.Config.NodeRed.Password)))
+		opts = append(opts, WithBasicAuth(c.Config.NodeRed.User, c.Config.NodeRed.Password))
 	}
 
-	if len(c.Config.NodeRed.CustomHeaders) != 0 {
-		for i, j := range c.Config.NodeRed.CustomHeaders {
-			c.AddHeader(i, j)
-		}
+	for i, j := range c.Config.NodeRed.CustomHeaders {
+		opts = append(opts, WithHeader(i, j))
 	}
 
-	err := c.Post(falcopayload)
+	err := c.Post(ctx, falcopayload, opts...)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:nodered", "status:error"})
 		c.Stats.NodeRed.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "nodered", "status": Error}).Inc()
 		log.Printf("[ERROR] : NodeRed - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:nodered", "status:ok"})
 	c.Stats.NodeRed.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "nodered", "status": OK}).Inc()
+	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -49,7 +50,7 @@ func NewOpenfaasClient(config *types.Configuration, stats *types.Statistics, pro
 }
 
 // OpenfaasCall .
-func (c *Client) OpenfaasCall(falcopayload types.FalcoPayload) {
+func (c *Client) OpenfaasCall(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Openfaas.Add(Total, 1)
 
 	if c.Config.Openfaas.Kubeconfig != "" {
@@ -59,28 +60,29 @@ func (c *Client) OpenfaasCall(falcopayload types.FalcoPayload) {
 		req.SetHeader("Content-Type", "application/json")
 		req.SetHeader("User-Agent", "Falcosidekick")
 
-		res := req.Do(context.TODO())
+		res := req.Do(ctx)
 		rawbody, err := res.Raw()
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:openfaas", "status:error"})
 			c.Stats.Openfaas.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "openfaas", "status": Error}).Inc()
 			log.Printf("[ERROR] : %v - %v\n", Openfaas, err)
-			return
+			return err
 		}
 		log.Printf("[INFO]  : %v - Function Response : %v\n", Openfaas, string(rawbody))
 	} else {
-		err := c.Post(falcopayload)
+		err := c.Post(ctx, falcopayload)
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:openfaas", "status:error"})
 			c.Stats.Openfaas.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "openfaas", "status": Error}).Inc()
 			log.Printf("[ERROR] : %v - %v\n", Openfaas, err)
-			return
+			return err
 		}
 	}
-	log.Printf("[INFO]  : %v - Call Function \"%v\" OK\n", Openfaas, c.Config.Openfaas.FunctionName+"."+c.Config.Openfaas.FunctionNamespace)
+	c.Logger(ctx).InfoLimited("Call Function OK", logger.F("function", c.Config.Openfaas.FunctionName+"."+c.Config.Openfaas.FunctionNamespace))
 	go c.CountMetric(Outputs, 1, []string{"output:openfaas", "status:ok"})
 	c.Stats.Openfaas.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "openfaas", "status": OK}).Inc()
+	return nil
 }
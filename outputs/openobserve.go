@@ -1,35 +1,36 @@
 package outputs
 
 import (
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // OpenObservePost posts event to OpenObserve
-func (c *Client) OpenObservePost(falcopayload types.FalcoPayload) {
+func (c *Client) OpenObservePost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.OpenObserve.Add(Total, 1)
 
+	var opts []RequestOption
 	if c.Config.OpenObserve.Username != "" && c.Config.OpenObserve.Password != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.BasicAuth(c.Config.OpenObserve.Username, c.Config.OpenObserve.Password)
+		opts = append(opts, WithBasicAuth(c.Config.OpenObserve.Username, c.Config.OpenObserve.Password))
 	}
 
 	for i, j := range c.Config.OpenObserve.CustomHeaders {
-		c.AddHeader(i, j)
+		opts = append(opts, WithHeader(i, j))
 	}
 
-	if err := c.Post(falcopayload); err != nil {
+	if err := c.Post(ctx, falcopayload, opts...); err != nil {
 		c.setOpenObserveErrorMetrics()
 		log.Printf("[ERROR] : OpenObserve - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:openobserve", "status:ok"})
 	c.Stats.OpenObserve.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "openobserve", "status": OK}).Inc()
+	return nil
 }
 
 // setOpenObserveErrorMetrics set the error stats
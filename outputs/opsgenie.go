@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"log"
 	"strings"
 
@@ -60,23 +61,21 @@ func newOpsgeniePayload(falcopayload types.FalcoPayload, config *types.Configura
 }
 
 // OpsgeniePost posts event to OpsGenie
-func (c *Client) OpsgeniePost(falcopayload types.FalcoPayload) {
+func (c *Client) OpsgeniePost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Opsgenie.Add(Total, 1)
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
-	c.AddHeader(AuthorizationHeaderKey, "GenieKey "+c.Config.Opsgenie.APIKey)
 
-	err := c.Post(newOpsgeniePayload(falcopayload, c.Config))
+	err := c.Post(ctx, newOpsgeniePayload(falcopayload, c.Config), WithHeader(AuthorizationHeaderKey, "GenieKey "+c.Config.Opsgenie.APIKey))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:opsgenie", "status:error"})
 		c.Stats.Opsgenie.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "opsgenie", "status": Error}).Inc()
 		log.Printf("[ERROR] : OpsGenie - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:opsgenie", "status:ok"})
 	c.Stats.Opsgenie.Add("ok", 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "opsgenie", "status": OK}).Inc()
+	return nil
 }
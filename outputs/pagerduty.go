@@ -17,7 +17,7 @@ const (
 )
 
 // PagerdutyPost posts alert event to Pagerduty
-func (c *Client) PagerdutyPost(falcopayload types.FalcoPayload) {
+func (c *Client) PagerdutyPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Pagerduty.Add(Total, 1)
 
 	event := createPagerdutyEvent(falcopayload, c.Config.Pagerduty)
@@ -28,18 +28,19 @@ func (c *Client) PagerdutyPost(falcopayload types.FalcoPayload) {
 		pagerduty.WithV2EventsAPIEndpoint(USEndpoint)
 	}
 
-	if _, err := pagerduty.ManageEventWithContext(context.Background(), event); err != nil {
+	if _, err := pagerduty.ManageEventWithContext(ctx, event); err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:pagerduty", "status:error"})
 		c.Stats.Pagerduty.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": Error}).Inc()
 		log.Printf("[ERROR] : PagerDuty - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:pagerduty", "status:ok"})
 	c.Stats.Pagerduty.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": OK}).Inc()
-	log.Printf("[INFO]  : Pagerduty - Create Incident OK\n")
+	c.Logger(ctx).InfoLimited("Create Incident OK")
+	return nil
 }
 
 func createPagerdutyEvent(falcopayload types.FalcoPayload, config types.PagerdutyConfig) pagerduty.V2Event {
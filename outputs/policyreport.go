@@ -131,7 +131,7 @@ func NewPolicyReportClient(config *types.Configuration, stats *types.Statistics,
 }
 
 // UpdateOrCreatePolicyReport creates/updates PolicyReport/ClusterPolicyReport Resource in Kubernetes
-func (c *Client) UpdateOrCreatePolicyReport(falcopayload types.FalcoPayload) {
+func (c *Client) UpdateOrCreatePolicyReport(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.PolicyReport.Add(Total, 1)
 
 	event, namespace := newResult(falcopayload)
@@ -139,9 +139,9 @@ func (c *Client) UpdateOrCreatePolicyReport(falcopayload types.FalcoPayload) {
 	var err error
 	if namespace != "" {
 		// case where the event is namespace specific
-		err = updatePolicyReports(c, namespace, event)
+		err = updatePolicyReports(ctx, c, namespace, event)
 	} else {
-		err = updateClusterPolicyReport(c, event)
+		err = updateClusterPolicyReport(ctx, c, event)
 	}
 	if err == nil {
 		go c.CountMetric(Outputs, 1, []string{"output:policyreport", "status:ok"})
@@ -152,6 +152,7 @@ func (c *Client) UpdateOrCreatePolicyReport(falcopayload types.FalcoPayload) {
 		c.Stats.PolicyReport.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "policyreport", "status": Error}).Inc()
 	}
+	return err
 }
 
 // newResult creates a new entry for Reports
@@ -206,7 +207,7 @@ func updatePolicyReportSummary(rep *wgpolicy.PolicyReport, event *wgpolicy.Polic
 	}
 }
 
-func updatePolicyReports(c *Client, namespace string, event *wgpolicy.PolicyReportResult) error {
+func updatePolicyReports(ctx context.Context, c *Client, namespace string, event *wgpolicy.PolicyReportResult) error {
 	//policyReport to be created
 	if policyReports[namespace] == nil {
 		policyReports[namespace] = &wgpolicy.PolicyReport{
@@ -245,9 +246,9 @@ func updatePolicyReports(c *Client, namespace string, event *wgpolicy.PolicyRepo
 		}
 	}
 	policyReports[namespace].Results = append(policyReports[namespace].Results, event)
-	_, getErr := policyr.Get(context.Background(), policyReports[namespace].Name, metav1.GetOptions{})
+	_, getErr := policyr.Get(ctx, policyReports[namespace].Name, metav1.GetOptions{})
 	if errors.IsNotFound(getErr) {
-		result, err := policyr.Create(context.TODO(), policyReports[namespace], metav1.CreateOptions{})
+		result, err := policyr.Create(ctx, policyReports[namespace], metav1.CreateOptions{})
 		if err != nil {
 			log.Printf("[ERROR] : PolicyReport - Can't create Policy Report %v in namespace %v\n", err, namespace)
 			return err
@@ -257,7 +258,7 @@ func updatePolicyReports(c *Client, namespace string, event *wgpolicy.PolicyRepo
 	} else {
 		// Update existing Policy Report
 		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-			result, err := policyr.Get(context.Background(), policyReports[namespace].GetName(), metav1.GetOptions{})
+			result, err := policyr.Get(ctx, policyReports[namespace].GetName(), metav1.GetOptions{})
 			if errors.IsNotFound(err) {
 				// This doesnt ever happen even if it is already deleted or not found
 				log.Printf("[ERROR] : PolicyReport - Policy Report %v not found in namespace %v\n", policyReports[namespace].GetName(), namespace)
@@ -268,7 +269,7 @@ func updatePolicyReports(c *Client, namespace string, event *wgpolicy.PolicyRepo
 				return err
 			}
 			policyReports[namespace].SetResourceVersion(result.GetResourceVersion())
-			_, updateErr := policyr.Update(context.Background(), policyReports[namespace], metav1.UpdateOptions{})
+			_, updateErr := policyr.Update(ctx, policyReports[namespace], metav1.UpdateOptions{})
 			return updateErr
 		})
 		if retryErr != nil {
@@ -280,7 +281,7 @@ func updatePolicyReports(c *Client, namespace string, event *wgpolicy.PolicyRepo
 	return nil
 }
 
-func updateClusterPolicyReport(c *Client, event *wgpolicy.PolicyReportResult) error {
+func updateClusterPolicyReport(ctx context.Context, c *Client, event *wgpolicy.PolicyReportResult) error {
 	updateClusterPolicyReportSummary(event)
 	//clusterpolicyreport to be created
 	clusterpr := c.Crdclient.Wgpolicyk8sV1alpha2().ClusterPolicyReports()
@@ -298,9 +299,9 @@ func updateClusterPolicyReport(c *Client, event *wgpolicy.PolicyReportResult) er
 
 	clusterPolicyReport.Results = append(clusterPolicyReport.Results, event)
 
-	_, getErr := clusterpr.Get(context.Background(), clusterPolicyReport.Name, metav1.GetOptions{})
+	_, getErr := clusterpr.Get(ctx, clusterPolicyReport.Name, metav1.GetOptions{})
 	if errors.IsNotFound(getErr) {
-		result, err := clusterpr.Create(context.TODO(), clusterPolicyReport, metav1.CreateOptions{})
+		result, err := clusterpr.Create(ctx, clusterPolicyReport, metav1.CreateOptions{})
 		if err != nil {
 			log.Printf("[ERROR] : PolicyReport - %v\n", err)
 			return err
@@ -309,7 +310,7 @@ func updateClusterPolicyReport(c *Client, event *wgpolicy.PolicyReportResult) er
 	} else {
 		// Update existing Cluster Policy Report
 		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-			result, err := clusterpr.Get(context.Background(), clusterPolicyReport.GetName(), metav1.GetOptions{})
+			result, err := clusterpr.Get(ctx, clusterPolicyReport.GetName(), metav1.GetOptions{})
 			if errors.IsNotFound(err) {
 				// This doesnt ever happen even if it is already deleted or not found
 				log.Printf("[ERROR] : PolicyReport - Cluster Policy Report %v not found\n", clusterPolicyReport.GetName())
@@ -320,7 +321,7 @@ func updateClusterPolicyReport(c *Client, event *wgpolicy.PolicyReportResult) er
 				return err
 			}
 			clusterPolicyReport.SetResourceVersion(result.GetResourceVersion())
-			_, updateErr := clusterpr.Update(context.Background(), clusterPolicyReport, metav1.UpdateOptions{})
+			_, updateErr := clusterpr.Update(ctx, clusterPolicyReport, metav1.UpdateOptions{})
 			return updateErr
 		})
 		if retryErr != nil {
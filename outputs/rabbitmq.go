@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
@@ -13,7 +14,10 @@ import (
 // NewRabbitmqClient returns a new output.Client for accessing the RabbitmMQ API.
 func NewRabbitmqClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
 
-	var channel *amqp.Channel
+	var (
+		channel    *amqp.Channel
+		connection *amqp.Connection
+	)
 	if config.Rabbitmq.URL != "" && config.Rabbitmq.Queue != "" {
 		conn, err := amqp.Dial(config.Rabbitmq.URL)
 		if err != nil {
@@ -22,25 +26,27 @@ func NewRabbitmqClient(config *types.Configuration, stats *types.Statistics, pro
 		}
 		ch, err := conn.Channel()
 		if err != nil {
+			conn.Close()
 			log.Printf("[ERROR] : Rabbitmq Channel - %v\n", "Error while creating rabbitmq channel")
 			return nil, errors.New("error while creating rabbitmq channel")
 		}
-		channel = ch
+		channel, connection = ch, conn
 	}
 
 	return &Client{
-		OutputType:      "RabbitMQ",
-		Config:          config,
-		RabbitmqClient:  channel,
-		Stats:           stats,
-		PromStats:       promStats,
-		StatsdClient:    statsdClient,
-		DogstatsdClient: dogstatsdClient,
+		OutputType:         "RabbitMQ",
+		Config:             config,
+		RabbitmqClient:     channel,
+		rabbitmqConnection: connection,
+		Stats:              stats,
+		PromStats:          promStats,
+		StatsdClient:       statsdClient,
+		DogstatsdClient:    dogstatsdClient,
 	}, nil
 }
 
 // Publish sends a message to a Rabbitmq
-func (c *Client) Publish(falcopayload types.FalcoPayload) {
+func (c *Client) Publish(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Rabbitmq.Add(Total, 1)
 
 	payload, _ := json.Marshal(falcopayload)
@@ -56,11 +62,12 @@ func (c *Client) Publish(falcopayload types.FalcoPayload) {
 		go c.CountMetric("outputs", 1, []string{"output:rabbitmq", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "rabbitmq", "status": Error}).Inc()
 
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : RabbitMQ - Send to message OK \n")
+	c.Logger(ctx).InfoLimited("Send to message OK")
 	c.Stats.Rabbitmq.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:rabbitmq", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "rabbitmq", "status": OK}).Inc()
+	return nil
 }
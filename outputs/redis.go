@@ -45,18 +45,20 @@ func NewRedisClient(config *types.Configuration, stats *types.Statistics, promSt
 	}, nil
 }
 
-func (c *Client) RedisPost(falcopayload types.FalcoPayload) {
+func (c *Client) RedisPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Redis.Add(Total, 1)
 	redisPayload, _ := json.Marshal(falcopayload)
 	if strings.ToLower(c.Config.Redis.StorageType) == "hashmap" {
-		_, err := c.RedisClient.HSet(context.Background(), c.Config.Redis.Key, falcopayload.UUID, redisPayload).Result()
+		_, err := c.RedisClient.HSet(ctx, c.Config.Redis.Key, falcopayload.UUID, redisPayload).Result()
 		if err != nil {
 			c.ReportError(err)
+			return err
 		}
 	} else {
-		_, err := c.RedisClient.RPush(context.Background(), c.Config.Redis.Key, redisPayload).Result()
+		_, err := c.RedisClient.RPush(ctx, c.Config.Redis.Key, redisPayload).Result()
 		if err != nil {
 			c.ReportError(err)
+			return err
 		}
 	}
 
@@ -64,4 +66,5 @@ func (c *Client) RedisPost(falcopayload types.FalcoPayload) {
 	go c.CountMetric(Outputs, 1, []string{"output:redis", "status:ok"})
 	c.Stats.Redis.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "redis", "status": OK}).Inc()
+	return nil
 }
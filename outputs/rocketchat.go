@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"log"
 	"strings"
 
@@ -115,20 +116,21 @@ func newRocketchatPayload(falcopayload types.FalcoPayload, config *types.Configu
 }
 
 // RocketchatPost posts event to Rocketchat
-func (c *Client) RocketchatPost(falcopayload types.FalcoPayload) {
+func (c *Client) RocketchatPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Rocketchat.Add(Total, 1)
 
-	err := c.Post(newRocketchatPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newRocketchatPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:rocketchat", "status:error"})
 		c.Stats.Rocketchat.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "rocketchat", "status": Error}).Inc()
 		log.Printf("[ERROR] : RocketChat - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:rocketchat", "status:ok"})
 	c.Stats.Rocketchat.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "rocketchat", "status": OK}).Inc()
+	return nil
 }
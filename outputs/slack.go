@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"log"
 	"strings"
 
@@ -142,20 +143,21 @@ func newSlackPayload(falcopayload types.FalcoPayload, config *types.Configuratio
 }
 
 // SlackPost posts event to Slack
-func (c *Client) SlackPost(falcopayload types.FalcoPayload) {
+func (c *Client) SlackPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Slack.Add(Total, 1)
 
-	err := c.Post(newSlackPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newSlackPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:slack", "status:error"})
 		c.Stats.Slack.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "slack", "status": Error}).Inc()
 		log.Printf("[ERROR] : Slack - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:slack", "status:ok"})
 	c.Stats.Slack.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "slack", "status": OK}).Inc()
+	return nil
 }
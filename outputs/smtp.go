@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	htmlTemplate "html/template"
 	"log"
@@ -125,7 +126,7 @@ func (c *Client) GetAuth() (sasl.Client, error) {
 }
 
 // SendMail sends email to SMTP server
-func (c *Client) SendMail(falcopayload types.FalcoPayload) {
+func (c *Client) SendMail(ctx context.Context, falcopayload types.FalcoPayload) error {
 	sp := newSMTPPayload(falcopayload, c.Config)
 
 	to := strings.Split(strings.ReplaceAll(c.Config.SMTP.To, " ", ""), ",")
@@ -133,7 +134,7 @@ func (c *Client) SendMail(falcopayload types.FalcoPayload) {
 	smtpClient, err := smtp.Dial(c.Config.SMTP.HostPort)
 	if err != nil {
 		c.ReportErr("Client error", err)
-		return
+		return err
 	}
 	if c.Config.SMTP.TLS {
 		tlsCfg := &tls.Config{
@@ -142,14 +143,14 @@ func (c *Client) SendMail(falcopayload types.FalcoPayload) {
 		}
 		if err := smtpClient.StartTLS(tlsCfg); err != nil {
 			c.ReportErr("TLS error", err)
-			return
+			return err
 		}
 	}
 	if c.Config.SMTP.AuthMechanism != "none" {
 		auth, err := c.GetAuth()
 		if err != nil {
 			c.ReportErr("SASL Authentication mechanisms", err)
-			return
+			return err
 		}
 		smtpClient.Auth(auth)
 	}
@@ -159,7 +160,7 @@ func (c *Client) SendMail(falcopayload types.FalcoPayload) {
 	if c.Config.Debug {
 		log.Printf("[DEBUG] : SMTP payload : \nServer: %v\n%v\n%v\nSubject: %v\n", c.Config.SMTP.HostPort, sp.From, sp.To, sp.Subject)
 		if c.Config.SMTP.AuthMechanism != "" {
-			log.Printf("[DEBUG] : SMTP - SASL Auth : \nMechanisms: %v\nUser: %v\nToken: %v\nIdentity: %v\nTrace: %v\n", c.Config.SMTP.AuthMechanism, c.Config.SMTP.User, c.Config.SMTP.Token, c.Config.SMTP.Identity, c.Config.SMTP.Trace)
+			log.Printf("[DEBUG] : SMTP - SASL Auth : \nMechanisms: %v\nUser: %v\nIdentity: %v\nTrace: %v\n", c.Config.SMTP.AuthMechanism, c.Config.SMTP.User, c.Config.SMTP.Identity, c.Config.SMTP.Trace)
 		} else {
 			log.Printf("[DEBUG] : SMTP - SASL Auth : Disabled\n")
 		}
@@ -169,10 +170,11 @@ func (c *Client) SendMail(falcopayload types.FalcoPayload) {
 	err = smtpClient.SendMail(c.Config.SMTP.From, to, strings.NewReader(body))
 	if err != nil {
 		c.ReportErr("Send Mail failure", err)
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : SMTP - Sent OK\n")
+	c.Logger(ctx).InfoLimited("Sent OK")
 	go c.CountMetric("outputs", 1, []string{"output:smtp", "status:ok"})
 	c.Stats.SMTP.Add(OK, 1)
+	return nil
 }
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -219,27 +220,26 @@ func NewSpyderbatClient(config *types.Configuration, stats *types.Statistics, pr
 	}, nil
 }
 
-func (c *Client) SpyderbatPost(falcopayload types.FalcoPayload) {
+func (c *Client) SpyderbatPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Spyderbat.Add(Total, 1)
 
-	c.httpClientLock.Lock()
-	defer c.httpClientLock.Unlock()
-	c.AddHeader("Authorization", "Bearer "+c.Config.Spyderbat.APIKey)
-	c.AddHeader("Content-Encoding", "gzip")
-
 	payload, err := newSpyderbatPayload(falcopayload)
 	if err == nil {
-		err = c.Post(payload)
+		err = c.Post(ctx, payload,
+			WithHeader("Authorization", "Bearer "+c.Config.Spyderbat.APIKey),
+			WithHeader("Content-Encoding", "gzip"),
+		)
 	}
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:spyderbat", "status:error"})
 		c.Stats.Spyderbat.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "spyderbat", "status": Error}).Inc()
 		log.Printf("[ERROR] : Spyderbat - %v\n", err.Error())
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:spyderbat", "status:ok"})
 	c.Stats.Spyderbat.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "spyderbat", "status": OK}).Inc()
+	return nil
 }
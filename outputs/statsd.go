@@ -6,6 +6,7 @@ import (
 
 	"github.com/DataDog/datadog-go/statsd"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -30,8 +31,10 @@ func NewStatsdClient(outputType string, config *types.Configuration, stats *type
 	return statsdClient, nil
 }
 
-// CountMetric sends metrics to StatsD/DogStatsD.
+// CountMetric sends metrics to StatsD/DogStatsD and to the OTLP collector.
 func (c *Client) CountMetric(metric string, value int64, tags []string) {
+	countOTLPMetric(metric, value, tags)
+
 	if c.StatsdClient != nil {
 		c.Stats.Statsd.Add("total", 1)
 		t := ""
@@ -52,7 +55,7 @@ func (c *Client) CountMetric(metric string, value int64, tags []string) {
 
 		c.Stats.Statsd.Add(OK, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "statsd", "status": OK}).Inc()
-		log.Printf("[INFO]  : StatsD - Send Metric OK (%v%v%v)\n", c.Config.Statsd.Namespace, metric, t)
+		logger.ForOutput("StatsD").InfoLimited("Send Metric OK", logger.F("metric", c.Config.Statsd.Namespace+metric+t))
 	}
 
 	if c.DogstatsdClient != nil {
@@ -67,6 +70,6 @@ func (c *Client) CountMetric(metric string, value int64, tags []string) {
 
 		c.Stats.Dogstatsd.Add(OK, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "dogstatsd", "status": OK}).Inc()
-		log.Printf("[INFO]  : DogStatsD - Send Metric OK (%v%v %v)\n", c.Config.Statsd.Namespace, metric, tags)
+		logger.ForOutput("DogStatsD").InfoLimited("Send Metric OK", logger.F("metric", c.Config.Statsd.Namespace+metric), logger.F("tags", strings.Join(tags, ",")))
 	}
 }
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -55,7 +56,7 @@ func getCEFSeverity(priority types.PriorityType) string {
 	}
 }
 
-func (c *Client) SyslogPost(falcopayload types.FalcoPayload) {
+func (c *Client) SyslogPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Syslog.Add(Total, 1)
 	endpoint := fmt.Sprintf("%s:%s", c.Config.Syslog.Host, c.Config.Syslog.Port)
 
@@ -79,15 +80,6 @@ func (c *Client) SyslogPost(falcopayload types.FalcoPayload) {
 		priority = syslog.LOG_DEBUG
 	}
 
-	sysLog, err := syslog.Dial(c.Config.Syslog.Protocol, endpoint, priority, Falco)
-	if err != nil {
-		go c.CountMetric(Outputs, 1, []string{"output:syslog", "status:error"})
-		c.Stats.Syslog.Add(Error, 1)
-		c.PromStats.Outputs.With(map[string]string{"destination": "syslog", "status": Error}).Inc()
-		log.Printf("[ERROR] : Syslog - %v\n", err)
-		return
-	}
-
 	var payload []byte
 
 	if c.Config.Syslog.Format == "cef" {
@@ -115,16 +107,30 @@ func (c *Client) SyslogPost(falcopayload types.FalcoPayload) {
 		payload, _ = json.Marshal(falcopayload)
 	}
 
+	if captured(CapturedRequest{Output: c.OutputType, URL: strings.ToLower(c.Config.Syslog.Protocol) + "://" + endpoint, Body: payload}) {
+		return nil
+	}
+
+	sysLog, err := syslog.Dial(c.Config.Syslog.Protocol, endpoint, priority, Falco)
+	if err != nil {
+		go c.CountMetric(Outputs, 1, []string{"output:syslog", "status:error"})
+		c.Stats.Syslog.Add(Error, 1)
+		c.PromStats.Outputs.With(map[string]string{"destination": "syslog", "status": Error}).Inc()
+		log.Printf("[ERROR] : Syslog - %v\n", err)
+		return err
+	}
+
 	_, err = sysLog.Write(payload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:syslog", "status:error"})
 		c.Stats.Syslog.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "syslog", "status": Error}).Inc()
 		log.Printf("[ERROR] : Syslog - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:syslog", "status:ok"})
 	c.Stats.Syslog.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "syslog", "status": OK}).Inc()
+	return nil
 }
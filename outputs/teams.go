@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"log"
 	"strings"
 
@@ -116,20 +117,21 @@ func newTeamsPayload(falcopayload types.FalcoPayload, config *types.Configuratio
 }
 
 // TeamsPost posts event to Teams
-func (c *Client) TeamsPost(falcopayload types.FalcoPayload) {
+func (c *Client) TeamsPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Teams.Add(Total, 1)
 
-	err := c.Post(newTeamsPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newTeamsPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:teams", "status:error"})
 		c.Stats.Teams.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "teams", "status": Error}).Inc()
 		log.Printf("[ERROR] : Teams - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:teams", "status:ok"})
 	c.Stats.Teams.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "teams", "status": OK}).Inc()
+	return nil
 }
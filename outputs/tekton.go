@@ -1,26 +1,28 @@
 package outputs
 
 import (
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // TektonPost posts event to EventListner
-func (c *Client) TektonPost(falcopayload types.FalcoPayload) {
+func (c *Client) TektonPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Tekton.Add(Total, 1)
 
-	err := c.Post(falcopayload)
+	err := c.Post(ctx, falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:tekton", "status:error"})
 		c.Stats.Tekton.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "tekton", "status": Error}).Inc()
 		log.Printf("[ERROR] : Tekton - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:tekton", "status:ok"})
 	c.Stats.Tekton.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "tekton", "status": OK}).Inc()
+	return nil
 }
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -70,20 +71,21 @@ func newTelegramPayload(falcopayload types.FalcoPayload, config *types.Configura
 }
 
 // TelegramPost posts event to Telegram
-func (c *Client) TelegramPost(falcopayload types.FalcoPayload) {
+func (c *Client) TelegramPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Telegram.Add(Total, 1)
 
-	err := c.Post(newTelegramPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newTelegramPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:telegram", "status:error"})
 		c.Stats.Telegram.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "telegram", "status": Error}).Inc()
 		log.Printf("[ERROR] : Telegram - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:telegram", "status:ok"})
 	c.Stats.Telegram.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "telegram", "status": OK}).Inc()
+	return nil
 }
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -108,18 +109,24 @@ func newTimescaleDBPayload(falcopayload types.FalcoPayload, config *types.Config
 	return timescaledbPayload{SQL: sql, Values: retVals}
 }
 
-func (c *Client) TimescaleDBPost(falcopayload types.FalcoPayload) {
+func (c *Client) TimescaleDBPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.TimescaleDB.Add(Total, 1)
 
-	var ctx = context.Background()
 	tsdbPayload := newTimescaleDBPayload(falcopayload, c.Config)
+	if Capture != nil {
+		body, _ := json.Marshal(tsdbPayload)
+		endpoint := fmt.Sprintf("postgres://%s:%s/%s", c.Config.TimescaleDB.Host, c.Config.TimescaleDB.Port, c.Config.TimescaleDB.Database)
+		if captured(CapturedRequest{Output: c.OutputType, URL: endpoint, Body: body}) {
+			return nil
+		}
+	}
 	_, err := c.TimescaleDBClient.Exec(ctx, tsdbPayload.SQL, tsdbPayload.Values...)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:timescaledb", "status:error"})
 		c.Stats.TimescaleDB.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "timescaledb", "status": Error}).Inc()
 		log.Printf("[ERROR] : TimescaleDB - %v\n", err)
-		return
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:timescaledb", "status:ok"})
@@ -129,4 +136,5 @@ func (c *Client) TimescaleDBPost(falcopayload types.FalcoPayload) {
 	if c.Config.Debug {
 		log.Printf("[DEBUG] : TimescaleDB payload : %v\n", tsdbPayload)
 	}
+	return nil
 }
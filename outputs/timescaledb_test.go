@@ -1,7 +1,9 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
 	"regexp"
 	"strings"
 	"testing"
@@ -60,3 +62,25 @@ func TestNewTimescaleDBPayload(t *testing.T) {
 		}
 	}
 }
+
+func TestTimescaleDBPostCaptured(t *testing.T) {
+	var requests []CapturedRequest
+	Capture = func(r CapturedRequest) bool {
+		requests = append(requests, r)
+		return true
+	}
+	defer func() { Capture = nil }()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	config := &types.Configuration{TimescaleDB: types.TimescaleDBConfig{Host: "localhost", Port: "5432", Database: "falco", Password: "s3cr3t", HypertableName: "falco_events"}}
+	// the client has no connection, the request isn't sent
+	c := &Client{OutputType: "TimescaleDB", Config: config, Stats: &types.Statistics{TimescaleDB: new(expvar.Map).Init()}}
+	require.Nil(t, c.TimescaleDBPost(context.Background(), f))
+	require.Len(t, requests, 1)
+	require.Equal(t, "postgres://localhost:5432/falco", requests[0].URL)
+	var payload timescaledbPayload
+	require.Nil(t, json.Unmarshal(requests[0].Body, &payload))
+	require.True(t, strings.HasPrefix(payload.SQL, "INSERT INTO falco_events ("))
+	require.Len(t, payload.Values, 7)
+}
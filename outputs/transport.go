@@ -0,0 +1,217 @@
+package outputs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// NoProxy disables the proxy of an output, even if the HTTP_PROXY env vars are set
+const NoProxy = "none"
+
+// mutualTLSFilesCheckInterval is the minimum delay between two checks of the mutual TLS files
+const mutualTLSFilesCheckInterval = 10 * time.Second
+
+// httpTransport is the HTTP client of an output, it's built once to reuse the connections, and built again only
+// when the mutual TLS files change.
+type httpTransport struct {
+	client    *http.Client
+	files     map[string]time.Time
+	lastCheck time.Time
+	sync.Mutex
+}
+
+// getHTTPClient returns the HTTP client of the output, it's built at the first call.
+func (c *Client) getHTTPClient() (*http.Client, error) {
+	t := &c.transport
+	t.Lock()
+	defer t.Unlock()
+
+	if t.client != nil {
+		if len(t.files) == 0 || time.Since(t.lastCheck) < mutualTLSFilesCheckInterval {
+			return t.client, nil
+		}
+		t.lastCheck = time.Now()
+		if !filesChanged(t.files) {
+			return t.client, nil
+		}
+		log.Printf("[INFO]  : %v - Mutual TLS files have changed, reloading them\n", c.OutputType)
+	}
+
+	client, files, err := c.newHTTPClient()
+	if err != nil {
+		if t.client != nil {
+			// the files may be in the middle of a rotation, the previous ones are kept until the next check
+			log.Printf("[ERROR] : %v - Can't reload the mutual TLS files: %v\n", c.OutputType, err)
+			return t.client, nil
+		}
+		return nil, err
+	}
+	if t.client != nil {
+		t.client.CloseIdleConnections()
+	}
+	t.client, t.files, t.lastCheck = client, files, time.Now()
+	return client, nil
+}
+
+// newHTTPClient builds an HTTP client with the settings of the output, it returns the mutual TLS files with their
+// modification time.
+func (c *Client) newHTTPClient() (*http.Client, map[string]time.Time, error) {
+	settings := GetHTTPClientSettings(c.Config, c.OutputType)
+
+	proxy, err := NewProxyFunc(settings.Proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(settings.ConnectTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   settings.MaxIdleConnsPerHost,
+		IdleConnTimeout:       time.Duration(settings.IdleConnTimeout) * time.Second,
+		TLSHandshakeTimeout:   time.Duration(settings.TLSHandshakeTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(settings.ResponseHeaderTimeout) * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	var files map[string]time.Time
+	if c.MutualTLSEnabled {
+		certFile, keyFile, caCertFile := c.mutualTLSFiles()
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		caCert, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		transport.TLSClientConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      caCertPool,
+			MinVersion:   tls.VersionTLS12,
+		}
+
+		files = make(map[string]time.Time)
+		for _, i := range []string{certFile, keyFile, caCertFile} {
+			if info, err := os.Stat(i); err == nil {
+				files[i] = info.ModTime()
+			}
+		}
+	} else if !c.CheckCert {
+		// With MutualTLS enabled, the check cert flag is ignored
+		// #nosec G402 This is only set as a result of explicit configuration
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(settings.Timeout) * time.Second,
+	}, files, nil
+}
+
+// mutualTLSFiles returns the paths of the client cert, key and CA cert, mutualtlsclient takes priority over
+// mutualtlsfilespath.
+func (c *Client) mutualTLSFiles() (certFile, keyFile, caCertFile string) {
+	certFile = c.Config.MutualTLSFilesPath + MutualTLSClientCertFilename
+	if c.Config.MutualTLSClient.CertFile != "" {
+		certFile = c.Config.MutualTLSClient.CertFile
+	}
+	keyFile = c.Config.MutualTLSFilesPath + MutualTLSClientKeyFilename
+	if c.Config.MutualTLSClient.KeyFile != "" {
+		keyFile = c.Config.MutualTLSClient.KeyFile
+	}
+	caCertFile = c.Config.MutualTLSFilesPath + MutualTLSCacertFilename
+	if c.Config.MutualTLSClient.CaCertFile != "" {
+		caCertFile = c.Config.MutualTLSClient.CaCertFile
+	}
+	return certFile, keyFile, caCertFile
+}
+
+func filesChanged(files map[string]time.Time) bool {
+	for path, modTime := range files {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// Timeout returns the maximum duration of a post to the output, whatever its protocol, 0 means no limit.
+func (c *Client) Timeout() time.Duration {
+	return time.Duration(GetHTTPClientSettings(c.Config, c.OutputType).Timeout) * time.Second
+}
+
+// GetHTTPClientSettings returns the HTTP client settings of an output, the ones set in httpclient.outputs.<output>
+// override the global ones.
+func GetHTTPClientSettings(config *types.Configuration, outputType string) types.HTTPClientConfig {
+	settings := config.HTTPClient
+	settings.Outputs = nil
+
+	o, ok := config.HTTPClient.Outputs[strings.ToLower(outputType)]
+	if !ok {
+		return settings
+	}
+	if o.Proxy != "" {
+		settings.Proxy = o.Proxy
+	}
+	if o.ConnectTimeout != 0 {
+		settings.ConnectTimeout = o.ConnectTimeout
+	}
+	if o.TLSHandshakeTimeout != 0 {
+		settings.TLSHandshakeTimeout = o.TLSHandshakeTimeout
+	}
+	if o.ResponseHeaderTimeout != 0 {
+		settings.ResponseHeaderTimeout = o.ResponseHeaderTimeout
+	}
+	if o.Timeout != 0 {
+		settings.Timeout = o.Timeout
+	}
+	return settings
+}
+
+// NewProxyFunc returns the proxy function for a http(s):// or socks5:// proxy url, the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY env vars are used if the url is empty.
+func NewProxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	switch proxy {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case NoProxy:
+		return nil, nil
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%v'", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("proxy url without host")
+	}
+	return http.ProxyURL(u), nil
+}
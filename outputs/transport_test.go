@@ -0,0 +1,107 @@
+package outputs
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestGetHTTPClientSettings(t *testing.T) {
+	config := &types.Configuration{
+		HTTPClient: types.HTTPClientConfig{
+			ConnectTimeout: 5,
+			Timeout:        60,
+			Proxy:          "http://proxy:3128",
+			Outputs: map[string]types.HTTPClientOutputConfig{
+				"slack": {Timeout: 10, Proxy: "socks5://proxy:1080"},
+			},
+		},
+	}
+
+	settings := GetHTTPClientSettings(config, "Slack")
+	require.Equal(t, 5, settings.ConnectTimeout)
+	require.Equal(t, 10, settings.Timeout)
+	require.Equal(t, "socks5://proxy:1080", settings.Proxy)
+
+	settings = GetHTTPClientSettings(config, "Loki")
+	require.Equal(t, 60, settings.Timeout)
+	require.Equal(t, "http://proxy:3128", settings.Proxy)
+}
+
+func TestNewProxyFunc(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "https://example.com", nil)
+
+	proxy, err := NewProxyFunc("socks5://proxy:1080")
+	require.Nil(t, err)
+	u, err := proxy(r)
+	require.Nil(t, err)
+	require.Equal(t, "socks5://proxy:1080", u.String())
+
+	proxy, err = NewProxyFunc(NoProxy)
+	require.Nil(t, err)
+	require.Nil(t, proxy)
+
+	for _, i := range []string{"ftp://proxy", "http://", "://"} {
+		_, err = NewProxyFunc(i)
+		require.NotNil(t, err, i)
+	}
+}
+
+func TestHTTPClientIsReused(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	config := &types.Configuration{HTTPClient: types.HTTPClientConfig{MaxIdleConnsPerHost: 10, Timeout: 5}}
+	nc, err := NewClient("test", ts.URL, false, true, config, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.Nil(t, nc.Post(context.Background(), ""))
+	}
+	// the connection is kept alive between the requests
+	require.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
+
+func TestHTTPClientReloadsMutualTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := &types.Configuration{}
+	config.MutualTLSClient.CertFile = dir + "/client.crt"
+	config.MutualTLSClient.KeyFile = dir + "/client.key"
+	config.MutualTLSClient.CaCertFile = dir + "/ca.crt"
+	config.MutualTLSFilesPath = dir
+	_, err := certsetup(config)
+	require.Nil(t, err)
+
+	nc, err := NewClient("test", "https://localhost", true, true, config, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	client, err := nc.getHTTPClient()
+	require.Nil(t, err)
+	same, err := nc.getHTTPClient()
+	require.Nil(t, err)
+	require.Same(t, client, same)
+
+	// the files are checked again once the interval has passed
+	later := time.Now().Add(time.Minute)
+	require.Nil(t, os.Chtimes(config.MutualTLSClient.CertFile, later, later))
+	nc.transport.lastCheck = time.Time{}
+	reloaded, err := nc.getHTTPClient()
+	require.Nil(t, err)
+	require.NotSame(t, client, reloaded)
+}
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -60,7 +61,7 @@ func NewWavefrontClient(config *types.Configuration, stats *types.Statistics, pr
 }
 
 // WavefrontPost sends metrics to WaveFront.
-func (c *Client) WavefrontPost(falcopayload types.FalcoPayload) {
+func (c *Client) WavefrontPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 
 	tags := make(map[string]string)
 	tags["severity"] = falcopayload.Priority.String()
@@ -94,16 +95,17 @@ func (c *Client) WavefrontPost(falcopayload types.FalcoPayload) {
 			c.Stats.Wavefront.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "wavefront", "status": Error}).Inc()
 			log.Printf("[ERROR] : Wavefront - Unable to send event %s: %s\n", falcopayload.Rule, err)
-			return
+			return err
 		}
 		if err := sender.Flush(); err != nil {
 			c.Stats.Wavefront.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "wavefront", "status": Error}).Inc()
 			log.Printf("[ERROR] : Wavefront - Unable to flush event %s: %s\n", falcopayload.Rule, err)
-			return
+			return err
 		}
 		c.Stats.Wavefront.Add(OK, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "wavefront", "status": OK}).Inc()
-		log.Printf("[INFO]  : Wavefront - Send Event OK %s\n", falcopayload.Rule)
+		c.Logger(ctx).InfoLimited("Send Event OK")
 	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"log"
 	"strings"
 
@@ -8,33 +9,30 @@ import (
 )
 
 // WebhookPost posts event to an URL
-func (c *Client) WebhookPost(falcopayload types.FalcoPayload) {
+func (c *Client) WebhookPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Webhook.Add(Total, 1)
 
-	if len(c.Config.Webhook.CustomHeaders) != 0 {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		for i, j := range c.Config.Webhook.CustomHeaders {
-			c.AddHeader(i, j)
-		}
+	var opts []RequestOption
+	for i, j := range c.Config.Webhook.CustomHeaders {
+		opts = append(opts, WithHeader(i, j))
 	}
-	var err error
 	if strings.ToUpper(c.Config.Webhook.Method) == HttpPut {
-		err = c.Put(falcopayload)
-	} else {
-		err = c.Post(falcopayload)
+		opts = append(opts, WithMethod(HttpPut))
 	}
 
+	err := c.Post(ctx, falcopayload, opts...)
+
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:webhook", "status:error"})
 		c.Stats.Webhook.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "webhook", "status": Error}).Inc()
 		log.Printf("[ERROR] : WebHook - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:webhook", "status:ok"})
 	c.Stats.Webhook.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "webhook", "status": OK}).Inc()
+	return nil
 }
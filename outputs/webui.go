@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -19,20 +20,21 @@ func newWebUIPayload(falcopayload types.FalcoPayload, config *types.Configuratio
 }
 
 // WebUIPost posts event to Slack
-func (c *Client) WebUIPost(falcopayload types.FalcoPayload) {
+func (c *Client) WebUIPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.WebUI.Add(Total, 1)
 
-	err := c.Post(newWebUIPayload(falcopayload, c.Config))
+	err := c.Post(ctx, newWebUIPayload(falcopayload, c.Config))
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:webui", "status:error"})
 		c.Stats.WebUI.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "webui", "status": Error}).Inc()
 		log.Printf("[ERROR] : WebUI - %v\n", err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:webui", "status:ok"})
 	c.Stats.WebUI.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "webui", "status": OK}).Inc()
+	return nil
 }
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -62,7 +64,7 @@ func NewYandexClient(config *types.Configuration, stats *types.Statistics, promS
 }
 
 // UploadYandexS3 uploads payload to Yandex S3
-func (c *Client) UploadYandexS3(falcopayload types.FalcoPayload) {
+func (c *Client) UploadYandexS3(ctx context.Context, falcopayload types.FalcoPayload) error {
 	f, _ := json.Marshal(falcopayload)
 	prefix := ""
 	t := time.Now()
@@ -70,7 +72,7 @@ func (c *Client) UploadYandexS3(falcopayload types.FalcoPayload) {
 		prefix = c.Config.Yandex.S3.Prefix
 	}
 	key := fmt.Sprintf("%s/%s/%s.json", prefix, t.Format("2006-01-02"), t.Format(time.RFC3339Nano))
-	_, err := s3.New(c.AWSSession).PutObject(&s3.PutObjectInput{
+	_, err := s3.New(c.AWSSession).PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(c.Config.Yandex.S3.Bucket),
 		Key:    aws.String(key),
 		Body:   bytes.NewReader(f),
@@ -79,17 +81,18 @@ func (c *Client) UploadYandexS3(falcopayload types.FalcoPayload) {
 		go c.CountMetric("outputs", 1, []string{"output:yandexs3", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "yandexs3", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v S3 - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
-	log.Printf("[INFO]  : %v S3 - Upload payload OK\n", c.OutputType)
+	logger.ForOutput(c.OutputType + " S3").WithContext(ctx).InfoLimited("Upload payload OK")
 
 	go c.CountMetric("outputs", 1, []string{"output:yandexs3", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "yandexs3", "status": "ok"}).Inc()
+	return nil
 }
 
 // UploadYandexDataStreams uploads payload to Yandex Data Streams
-func (c *Client) UploadYandexDataStreams(falcoPayLoad types.FalcoPayload) {
+func (c *Client) UploadYandexDataStreams(ctx context.Context, falcoPayLoad types.FalcoPayload) error {
 	svc := kinesis.New(c.AWSSession)
 
 	f, _ := json.Marshal(falcoPayLoad)
@@ -99,16 +102,17 @@ func (c *Client) UploadYandexDataStreams(falcoPayLoad types.FalcoPayload) {
 		StreamName:   aws.String(c.Config.Yandex.DataStreams.StreamName),
 	}
 
-	resp, err := svc.PutRecord(input)
+	resp, err := svc.PutRecordWithContext(ctx, input)
 	if err != nil {
 		go c.CountMetric("outputs", 1, []string{"output:yandexdatastreams", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "yandexdatastreams", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v Data Streams - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	log.Printf("[INFO] : %v Data Streams - Put Record OK (%v)\n", c.OutputType, resp.SequenceNumber)
 	go c.CountMetric("outputs", 1, []string{"output:yandexdatastreams", "status:ok"})
 	c.Stats.YandexDataStreams.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "yandexdatastreams", "status": "ok"}).Inc()
+	return nil
 }
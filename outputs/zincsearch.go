@@ -1,34 +1,33 @@
 package outputs
 
 import (
-	"fmt"
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // ZincsearchPost posts event to Zincsearch
-func (c *Client) ZincsearchPost(falcopayload types.FalcoPayload) {
+func (c *Client) ZincsearchPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Zincsearch.Add(Total, 1)
 
+	var opts []RequestOption
 	if c.Config.Zincsearch.Username != "" && c.Config.Zincsearch.Password != "" {
-		c.httpClientLock.Lock()
-		defer c.httpClientLock.Unlock()
-		c.BasicAuth(c.Config.Zincsearch.Username, c.Config.Zincsearch.Password)
+		opts = append(opts, WithBasicAuth(c.Config.Zincsearch.Username, c.Config.Zincsearch.Password))
 	}
 
-	fmt.Println(c.EndpointURL)
-	err := c.Post(falcopayload)
+	err := c.Post(ctx, falcopayload, opts...)
 	if err != nil {
 		c.setZincsearchErrorMetrics()
 		log.Printf("[ERROR] : Zincsearch - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:zincsearch", "status:ok"})
 	c.Stats.Zincsearch.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "zincsearch", "status": OK}).Inc()
+	return nil
 }
 
 // setZincsearchErrorMetrics set the error stats
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateInterval is the interval of the updates of the moving averages of the rates
+const rateInterval = 5 * time.Second
+
+// rateWindows are the windows of the moving averages of the rates, like the load averages
+var rateWindows = []struct {
+	name   string
+	window time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+}
+
+// eventsRate is the name of the rate of the events received by all the inputs
+const eventsRate = "events"
+
+// outputRate returns the name of the rate of the posts to an output
+func outputRate(destination string) string {
+	return "outputs." + destination
+}
+
+// outputErrorRate returns the name of the rate of the failed posts to an output
+func outputErrorRate(destination string) string {
+	return "outputs." + destination + ".errors"
+}
+
+// rateMeter measures a rate per second with exponentially weighted moving averages over the last 1, 5 and 15 minutes
+type rateMeter struct {
+	// uncounted is the number of events since the last update
+	uncounted int64
+	rates     []float64
+	started   bool
+}
+
+// update adds the events since the last update to the moving averages, the first update sets them
+func (m *rateMeter) update() {
+	instant := float64(m.uncounted) / rateInterval.Seconds()
+	m.uncounted = 0
+	if !m.started {
+		m.rates = make([]float64, len(rateWindows))
+		for i := range m.rates {
+			m.rates[i] = instant
+		}
+		m.started = true
+		return
+	}
+	for i, j := range rateWindows {
+		alpha := 1 - math.Exp(-rateInterval.Seconds()/j.window.Seconds())
+		m.rates[i] += alpha * (instant - m.rates[i])
+	}
+}
+
+// rateMeters keeps the meters of the rates by name, they're updated every rateInterval
+type rateMeters struct {
+	meters map[string]*rateMeter
+	sync.Mutex
+}
+
+var rates = &rateMeters{meters: make(map[string]*rateMeter)}
+
+// mark counts an event for the rate
+func (r *rateMeters) mark(name string) {
+	r.Lock()
+	defer r.Unlock()
+	m, ok := r.meters[name]
+	if !ok {
+		m = new(rateMeter)
+		r.meters[name] = m
+	}
+	m.uncounted++
+}
+
+// update updates the moving averages of all the rates
+func (r *rateMeters) update() {
+	r.Lock()
+	defer r.Unlock()
+	for _, i := range r.meters {
+		i.update()
+	}
+}
+
+// get returns the rates per second of name by window, they're 0 until its first update
+func (r *rateMeters) get(name string) map[string]float64 {
+	r.Lock()
+	defer r.Unlock()
+	values := make(map[string]float64, len(rateWindows))
+	m := r.meters[name]
+	for i, j := range rateWindows {
+		values[j.name] = 0
+		if m != nil && m.started {
+			values[j.name] = m.rates[i]
+		}
+	}
+	return values
+}
+
+// run updates the rates every rateInterval until ctx is done
+func (r *rateMeters) run(ctx context.Context) {
+	ticker := time.NewTicker(rateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.update()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
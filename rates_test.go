@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateMeters(t *testing.T) {
+	r := &rateMeters{meters: make(map[string]*rateMeter)}
+	require.Equal(t, map[string]float64{"1m": 0, "5m": 0, "15m": 0}, r.get("events"))
+
+	for i := 0; i < 50; i++ {
+		r.mark("events")
+	}
+	r.update()
+	require.Equal(t, map[string]float64{"1m": 10, "5m": 10, "15m": 10}, r.get("events"))
+
+	// without events, the shortest window decays the fastest
+	r.update()
+	values := r.get("events")
+	require.InDelta(t, 10*math.Exp(-5.0/60), values["1m"], 1e-9)
+	require.InDelta(t, 10*math.Exp(-5.0/900), values["15m"], 1e-9)
+	require.Less(t, values["1m"], values["5m"])
+}
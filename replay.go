@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+)
+
+// replayOptions are the flags of the replay command
+type replayOptions struct {
+	// file is the file of the events, one JSON event per line, - for the standard input
+	file string
+	// rate is the maximum number of events sent per second, 0 for no limit
+	rate float64
+	// maxInFlight is the maximum number of events sent concurrently
+	maxInFlight int
+	// outputs are the selected outputs, all the enabled ones if empty
+	outputs []string
+	dryRun  bool
+}
+
+// replay is set by the replay command, falcosidekick then sends the events of the file instead of serving
+var replay *replayOptions
+
+// replaySummary counts the events of a replay
+type replaySummary struct {
+	events  int
+	invalid int
+	failed  int
+}
+
+// replayDestinations returns the outputs receiving the replayed events, the selected ones or all the enabled ones.
+// For a dry run, the outputs with requests which can't be printed are skipped.
+func replayDestinations(opts *replayOptions) (map[string]bool, error) {
+	outputsLock.RLock()
+	defer outputsLock.RUnlock()
+	for _, i := range opts.outputs {
+		if !isEnabled(i) {
+			return nil, fmt.Errorf("unknown output '%v'", i)
+		}
+	}
+	destinations := make(map[string]bool)
+	for destination := range adminOutputs {
+		if !isEnabled(destination) || (len(opts.outputs) != 0 && !contains(opts.outputs, destination)) {
+			continue
+		}
+		if opts.dryRun && !capturableOutputs[destination] {
+			log.Printf("[WARN] : Replay - The requests of '%v' can't be printed, it's skipped\n", destination)
+			continue
+		}
+		destinations[destination] = true
+	}
+	if len(destinations) == 0 {
+		return nil, errors.New("no output to send the events to")
+	}
+	return destinations, nil
+}
+
+// runReplay sends the events read from r through the outputs, like the events received by the inputs. The
+// requests of a dry run are written to w instead of being sent. It stops at the end of r or once ctx is done, the
+// events already read are still sent.
+func runReplay(ctx context.Context, opts *replayOptions, r io.Reader, w io.Writer) (replaySummary, error) {
+	var summary replaySummary
+	destinations, err := replayDestinations(opts)
+	if err != nil {
+		return summary, err
+	}
+	if opts.dryRun {
+		var lock sync.Mutex
+		encoder := json.NewEncoder(w)
+		outputs.Capture = func(r outputs.CapturedRequest) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			if err := encoder.Encode(newCapturedRequest(r)); err != nil {
+				log.Printf("[ERROR] : Replay - %v\n", err)
+			}
+			return true
+		}
+		defer func() { outputs.Capture = nil }()
+	}
+
+	var tick <-chan time.Time
+	if opts.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	maxInFlight := opts.maxInFlight
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	inFlight := make(chan struct{}, maxInFlight)
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	scanner := bufio.NewScanner(r)
+	maxLineSize := 1048576
+	if config.MaxRequestSize > 0 {
+		maxLineSize = int(config.MaxRequestSize)
+	}
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		message := scanner.Bytes()
+		if len(strings.TrimSpace(string(message))) == 0 {
+			continue
+		}
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		summary.events++
+		// the events are only sent to the selected outputs, which return their results
+		delivery := &testDelivery{outputs: destinations, results: make(map[string]testResult)}
+		posts, err := forwardInputMessage(newTestDeliveryContext(detachContext(ctx), delivery), "replay", stats.ReplayInput, message)
+		if err != nil {
+			log.Printf("[ERROR] : Replay - Invalid event at line %v: %v\n", line, err)
+			summary.invalid++
+			continue
+		}
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go func(line int) {
+			defer wg.Done()
+			_ = posts.Wait()
+			<-inFlight
+			lock.Lock()
+			defer lock.Unlock()
+			for _, i := range delivery.results {
+				if i.Status == outputs.Error {
+					log.Printf("[ERROR] : Replay - The event at line %v isn't sent to '%v': %v\n", line, i.Output, i.Error)
+					summary.failed++
+				}
+			}
+		}(line)
+	}
+	err = scanner.Err()
+
+	// the last events don't wait for the flush interval of their batch
+	outputs.FlushBatches()
+	wg.Wait()
+	lock.Lock()
+	defer lock.Unlock()
+	// the forward output only queues the events, they're sent once its spool is
+	if destinations["forward"] && !opts.dryRun {
+		outputsLock.RLock()
+		client := forwardClient
+		outputsLock.RUnlock()
+		flushCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout)*time.Second)
+		defer cancel()
+		if left := client.FlushForward(flushCtx); left != 0 {
+			log.Printf("[ERROR] : Replay - %v event(s) aren't sent to 'forward' before the timeout\n", left)
+			summary.failed += left
+		}
+	}
+	return summary, err
+}
+
+// runReplayCommand replays the file of the replay command and returns the exit code, it's not 0 if an event is
+// invalid or can't be sent.
+func runReplayCommand(ctx context.Context) int {
+	r := os.Stdin
+	if replay.file != "-" {
+		f, err := os.Open(replay.file)
+		if err != nil {
+			log.Printf("[ERROR] : Replay - %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+
+	summary, err := runReplay(ctx, replay, r, os.Stdout)
+	shutdown()
+	if err != nil {
+		log.Printf("[ERROR] : Replay - %v\n", err)
+		return 1
+	}
+	log.Printf("[INFO]  : Replay - %v event(s) read, %v invalid, %v failed post(s)\n", summary.events, summary.invalid, summary.failed)
+	if summary.invalid != 0 || summary.failed != 0 {
+		return 1
+	}
+	return 0
+}
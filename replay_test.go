@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestRunReplay(t *testing.T) {
+	var (
+		posts []string
+		lock  sync.Mutex
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload types.FalcoPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+		lock.Lock()
+		posts = append(posts, payload.Rule)
+		lock.Unlock()
+		if payload.Rule == "Failing rule" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	config = &types.Configuration{}
+	config.Webhook.Address = ts.URL
+	config.Webhook.MinimumPriority = "warning"
+	config.Webhook.CustomHeaders = make(map[string]string)
+	config.Slack.WebhookURL = "https://hooks.slack.com/services/XXX"
+	config.Kafka.HostPort = "localhost:9092"
+	stats = &types.Statistics{Falco: new(expvar.Map).Init(), ReplayInput: new(expvar.Map).Init(), Webhook: new(expvar.Map).Init(), Slack: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		Falco:              prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_falco"}, []string{"hostname", "rule", "priority", "k8s_ns_name", "k8s_pod_name"}),
+		Inputs:             prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_inputs"}, []string{"source", "status"}),
+		Outputs:            prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_outputs"}, []string{"destination", "status"}),
+		OutputLatency:      prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_latency"}, []string{"destination"}),
+		OutputSendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_send_duration"}, []string{"destination", "status"}),
+		OutputsInFlight:    prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_in_flight"}, []string{"destination"}),
+		OutputErrors:       prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors"}, []string{"destination", "class"}),
+	}
+	nullClient = &outputs.Client{Config: config, Stats: stats, PromStats: promStats}
+	outputs.EnabledOutputs = []string{"Kafka"}
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	defer func() { outputs.EnabledOutputs = nil }()
+	var err error
+	webhookClient, err = outputs.NewClient("Webhook", config.Webhook.Address, false, true, config, stats, promStats, nil, nil)
+	require.Nil(t, err)
+	slackClient, err = outputs.NewClient("Slack", config.Slack.WebhookURL, false, true, config, stats, promStats, nil, nil)
+	require.Nil(t, err)
+	outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Webhook", "Slack")
+	configureLogs(config)
+
+	events := strings.Join([]string{
+		`{"output":"Critical event","priority":"Critical","rule":"Critical rule","time":"2001-01-01T01:10:00Z","output_fields":{"proc.name":"falco"}}`,
+		"",
+		`{"output":"Debug event","priority":"Debug","rule":"Debug rule","time":"2001-01-01T01:10:00Z","output_fields":{"proc.name":"falco"}}`,
+		`{"output":"invalid"}`,
+		`{"output":"Failing event","priority":"Error","rule":"Failing rule","time":"2001-01-01T01:10:00Z","output_fields":{"proc.name":"falco"}}`,
+	}, "\n")
+
+	summary, err := runReplay(context.Background(), &replayOptions{outputs: []string{"webhook"}, maxInFlight: 1}, strings.NewReader(events), nil)
+	require.Nil(t, err)
+	require.Equal(t, replaySummary{events: 4, invalid: 1, failed: 1}, summary)
+	// the minimum priority of the output is used
+	require.Equal(t, []string{"Critical rule", "Failing rule"}, posts)
+	require.Equal(t, "1", stats.ReplayInput.Get(outputs.Rejected).String())
+
+	// the requests of a dry run are printed, the outputs which don't send them with the http client are skipped
+	var b bytes.Buffer
+	summary, err = runReplay(context.Background(), &replayOptions{dryRun: true, rate: 100, maxInFlight: 10}, strings.NewReader(events), &b)
+	require.Nil(t, err)
+	require.Equal(t, replaySummary{events: 4, invalid: 1}, summary)
+	require.Len(t, posts, 2)
+	require.Nil(t, outputs.Capture)
+	var requests []capturedRequest
+	d := json.NewDecoder(&b)
+	for d.More() {
+		var r capturedRequest
+		require.Nil(t, d.Decode(&r))
+		requests = append(requests, r)
+	}
+	require.Len(t, requests, 5)
+	for _, i := range requests {
+		if i.Output == "slack" {
+			// the webhook url is a secret
+			require.NotContains(t, i.URL, "XXX")
+			continue
+		}
+		require.Equal(t, ts.URL, i.URL)
+		require.Contains(t, []string{"Critical rule", "Failing rule"}, i.Body.(map[string]interface{})["rule"])
+	}
+
+	_, err = runReplay(context.Background(), &replayOptions{outputs: []string{"unknown"}}, strings.NewReader(events), nil)
+	require.EqualError(t, err, "unknown output 'unknown'")
+	_, err = runReplay(context.Background(), &replayOptions{outputs: []string{"kafka"}, dryRun: true}, strings.NewReader(events), nil)
+	require.EqualError(t, err, "no output to send the events to")
+}
+
+func TestRunReplayForward(t *testing.T) {
+	var (
+		received int
+		lock     sync.Mutex
+	)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		require.Nil(t, err)
+		var batch []types.FalcoPayload
+		require.Nil(t, json.NewDecoder(zr).Decode(&batch))
+		lock.Lock()
+		defer lock.Unlock()
+		received += len(batch)
+	}))
+	defer hub.Close()
+
+	config = &types.Configuration{ShutdownTimeout: 5}
+	config.Forward = types.ForwardOutputConfig{Address: hub.URL, MinimumPriority: "debug", BatchSize: 100, FlushInterval: 3600, MaxSpooledBatches: 10, RetryInterval: 60, CheckCert: true}
+	stats = &types.Statistics{Falco: new(expvar.Map).Init(), ReplayInput: new(expvar.Map).Init(), Forward: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		Falco:              prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_falco"}, []string{"hostname", "rule", "priority", "k8s_ns_name", "k8s_pod_name"}),
+		Inputs:             prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_inputs"}, []string{"source", "status"}),
+		Outputs:            prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_outputs"}, []string{"destination", "status"}),
+		OutputLatency:      prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_latency"}, []string{"destination"}),
+		OutputSendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_send_duration"}, []string{"destination", "status"}),
+		OutputsInFlight:    prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_in_flight"}, []string{"destination"}),
+		OutputErrors:       prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors"}, []string{"destination", "class"}),
+		OutputQueueDepth:   prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_queue_depth"}, []string{"destination"}),
+	}
+	nullClient = &outputs.Client{Config: config, Stats: stats, PromStats: promStats}
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	var err error
+	forwardClient, err = outputs.NewForwardClient(config, stats, promStats, nil, nil, "1.0.0")
+	require.Nil(t, err)
+	outputs.EnabledOutputs = []string{"Forward"}
+	defer func() { outputs.EnabledOutputs = nil }()
+
+	// the events queued by the forward output are sent before the summary
+	events := strings.Repeat(`{"output":"Critical event","priority":"Critical","rule":"Critical rule","time":"2001-01-01T01:10:00Z","output_fields":{"proc.name":"falco"}}`+"\n", 3)
+	summary, err := runReplay(context.Background(), &replayOptions{maxInFlight: 1}, strings.NewReader(events), nil)
+	require.Nil(t, err)
+	require.Equal(t, replaySummary{events: 3}, summary)
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, 3, received)
+}
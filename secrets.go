@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// secretReferenceRegex matches the references to secrets in the settings, like ${file:/path} or ${env:VAR}
+var secretReferenceRegex = regexp.MustCompile(`\$\{(\w+):([^}]+)\}`)
+
+// isSecretReference tells whether s is only a reference, it can be printed
+func isSecretReference(s string) bool {
+	match := secretReferenceRegex.FindStringIndex(s)
+	return match != nil && match[0] == 0 && match[1] == len(s)
+}
+
+// secretProvider resolves the references of a kind, like ${file:/path}
+type secretProvider interface {
+	resolve(reference string) (string, error)
+}
+
+type secretProviderFunc func(reference string) (string, error)
+
+func (f secretProviderFunc) resolve(reference string) (string, error) {
+	return f(reference)
+}
+
+// secretProviders are the providers of the references by name, the others are added with registerSecretProvider
+var secretProviders = map[string]secretProvider{
+	"file": secretProviderFunc(readFileSecret),
+	"env":  secretProviderFunc(lookupSecretEnv),
+}
+
+// registerSecretProvider adds the provider of the references ${name:...}, it must be called before the references are
+// resolved
+func registerSecretProvider(name string, p secretProvider) {
+	secretProviders[name] = p
+}
+
+func readFileSecret(path string) (string, error) {
+	b, err := readSecretFile(path)
+	return string(b), err
+}
+
+func lookupSecretEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("env var '%v' is not set", name)
+	}
+	return value, nil
+}
+
+// resolveSecretReferences replaces the references of s by their secrets
+func resolveSecretReferences(s string) (string, error) {
+	var err error
+	resolved := secretReferenceRegex.ReplaceAllStringFunc(s, func(reference string) string {
+		match := secretReferenceRegex.FindStringSubmatch(reference)
+		p, ok := secretProviders[match[1]]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("unknown secret provider '%v'", match[1])
+			}
+			return reference
+		}
+		value, e := p.resolve(match[2])
+		if e != nil && err == nil {
+			err = fmt.Errorf("can't resolve ${%v:%v}: %v", match[1], match[2], e)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// vaultSecretProvider reads the secrets ${vault:path#key} from a KV v2 engine of HashiCorp Vault
+type vaultSecretProvider struct {
+	config *types.VaultSecretsConfig
+	client *http.Client
+}
+
+func newVaultSecretProvider(c *types.VaultSecretsConfig) *vaultSecretProvider {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !c.CheckCert {
+		// #nosec G402 This is only set as a result of explicit configuration
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &vaultSecretProvider{config: c, client: &http.Client{Transport: transport, Timeout: 10 * time.Second}}
+}
+
+func (p *vaultSecretProvider) resolve(reference string) (string, error) {
+	path, key, found := strings.Cut(reference, "#")
+	if !found || path == "" || key == "" {
+		return "", fmt.Errorf("invalid reference '%v', it should be path#key", reference)
+	}
+	u, err := url.JoinPath(p.config.Address, "v1", p.config.Mount, "data", path)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.config.Token)
+	if p.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.config.Namespace)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault answered '%v' for '%v'", resp.Status, path)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("no key '%v' in '%v'", key, path)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// secretReference is a setting with references to secrets
+type secretReference struct {
+	// key is the key of the setting, like datadog.apikey
+	key     string
+	section string
+	// path locates the setting in types.Configuration, with the indexes of the fields and of the elements of the
+	// slices, mapKey is its key if it's in a map
+	path   []int
+	mapKey reflect.Value
+	// template is the value of the setting with the references, value is the resolved one
+	template string
+	value    string
+}
+
+// set sets the setting of c to value
+func (r *secretReference) set(c *types.Configuration, value string) {
+	v := reflect.ValueOf(c).Elem()
+	for _, i := range r.path {
+		if v.Kind() == reflect.Slice {
+			v = v.Index(i)
+		} else {
+			v = v.Field(i)
+		}
+	}
+	if r.mapKey.IsValid() {
+		v.SetMapIndex(r.mapKey, reflect.ValueOf(value).Convert(v.Type().Elem()))
+		return
+	}
+	v.SetString(value)
+}
+
+// secretReferences are the settings with references, they're resolved again every refresh interval to follow the
+// rotations of the secrets
+type secretReferences struct {
+	list []*secretReference
+	sync.Mutex
+}
+
+var configSecrets = new(secretReferences)
+
+// resolveSecrets resolves the references of the settings of c, the ones of the secrets section first, as they
+// configure the other providers.
+func resolveSecrets(c *types.Configuration) (*secretReferences, []error) {
+	r := new(secretReferences)
+	v := reflect.ValueOf(c).Elem()
+	secrets, _ := v.Type().FieldByName("Secrets")
+	findSecretReferences(v.FieldByIndex(secrets.Index), "secrets", "Secrets", secrets.Index, &r.list)
+	if errs := resolveSettings(c, r.list); len(errs) != 0 {
+		return r, errs
+	}
+	registerSecretProviders(&c.Secrets)
+
+	var list []*secretReference
+	for i := 0; i < v.NumField(); i++ {
+		if name := v.Type().Field(i).Name; name != "Secrets" {
+			findSecretReferences(v.Field(i), strings.ToLower(name), name, []int{i}, &list)
+		}
+	}
+	var errs []error
+	resolved := len(r.list)
+	for _, i := range list {
+		if !reloadableSection(i.section) {
+			errs = append(errs, fmt.Errorf("%v - the settings of '%v' aren't reloaded, they can't reference secrets", i.key, strings.ToLower(i.section)))
+			continue
+		}
+		r.list = append(r.list, i)
+	}
+	return r, append(errs, resolveSettings(c, r.list[resolved:])...)
+}
+
+// reloadableSection tells whether the settings of the section follow the rotations of the secrets: the ones of the
+// outputs, which are created again, and the ones of the secrets section. The other settings, like the ones of the
+// inputs and of the authentication, are read once at the start.
+func reloadableSection(section string) bool {
+	if section == "Secrets" {
+		return true
+	}
+	for _, i := range adminOutputs {
+		if i.topLevel() == section {
+			return true
+		}
+	}
+	return false
+}
+
+// registerSecretProviders registers the providers configured by the secrets section
+func registerSecretProviders(c *types.SecretsConfig) {
+	if c.Vault.Address != "" {
+		registerSecretProvider("vault", newVaultSecretProvider(&c.Vault))
+	}
+}
+
+// resolveSettings sets the settings of c with the resolved values of their references
+func resolveSettings(c *types.Configuration, list []*secretReference) []error {
+	var errs []error
+	for _, i := range list {
+		value, err := resolveSecretReferences(i.template)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v - %v", i.key, err))
+			continue
+		}
+		i.value = value
+		i.set(c, value)
+	}
+	return errs
+}
+
+// findSecretReferences adds the settings of v with references to list, v is a field of the section at path
+func findSecretReferences(v reflect.Value, key, section string, path []int, list *[]*secretReference) {
+	add := func(key, template string, mapKey reflect.Value) {
+		if secretReferenceRegex.MatchString(template) {
+			*list = append(*list, &secretReference{key: key, section: section, path: path, mapKey: mapKey, template: template})
+		}
+	}
+	// the paths of the settings don't share their arrays
+	path = path[:len(path):len(path)]
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.IsExported() {
+				findSecretReferences(v.Field(i), key+"."+strings.ToLower(f.Name), section, append(path, i), list)
+			}
+		}
+	case reflect.String:
+		add(key, v.String(), reflect.Value{})
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, k := range v.MapKeys() {
+			add(key+"."+k.String(), v.MapIndex(k).String(), k)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			findSecretReferences(v.Index(i), fmt.Sprintf("%v[%v]", key, i), section, append(path, i), list)
+		}
+	}
+}
+
+// deepCopy returns a copy of v which doesn't share its maps and slices
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	}
+	return v
+}
+
+// unresolve sets the settings of c back to their references, to print the configuration
+func (r *secretReferences) unresolve(c *types.Configuration) {
+	r.Lock()
+	defer r.Unlock()
+	for _, i := range r.list {
+		i.set(c, i.template)
+	}
+}
+
+// apply sets the settings of the section of c to the current values of their references, c must not share the maps
+// and the slices of the section with the configuration read by the clients
+func (r *secretReferences) apply(c *types.Configuration, section string) {
+	r.Lock()
+	defer r.Unlock()
+	r.applyLocked(c, section)
+}
+
+func (r *secretReferences) applyLocked(c *types.Configuration, section string) {
+	for _, i := range r.list {
+		if i.section == section {
+			i.set(c, i.value)
+		}
+	}
+}
+
+// values returns the resolved secrets, they're redacted from the logs
+func (r *secretReferences) values() []string {
+	r.Lock()
+	defer r.Unlock()
+	values := make([]string, 0, len(r.list))
+	for _, i := range r.list {
+		values = append(values, i.value)
+	}
+	return values
+}
+
+// contains tells whether s is the resolved value of a setting with references
+func (r *secretReferences) contains(s string) bool {
+	r.Lock()
+	defer r.Unlock()
+	for _, i := range r.list {
+		if s != "" && i.value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh resolves the references again and recreates the outputs of the sections where a setting has changed. The
+// settings read by the clients are never changed: the new clients get a copy of their section with the new values.
+func (r *secretReferences) refresh() {
+	outputsLock.Lock()
+	defer outputsLock.Unlock()
+
+	sections := make(map[string]bool)
+	r.Lock()
+	// the references of the secrets section are resolved first, the other ones use the providers they configure
+	for _, secrets := range []bool{true, false} {
+		for _, i := range r.list {
+			if (i.section == "Secrets") != secrets {
+				continue
+			}
+			value, err := resolveSecretReferences(i.template)
+			if err != nil {
+				log.Printf("[ERROR] : Secrets - Can't refresh '%v': %v\n", i.key, err)
+				continue
+			}
+			if value == i.value {
+				continue
+			}
+			i.value = value
+			sections[i.section] = true
+			log.Printf("[INFO]  : Secrets - '%v' has changed\n", i.key)
+		}
+		if secrets && sections["Secrets"] {
+			c := types.Configuration{Secrets: deepCopy(reflect.ValueOf(config.Secrets)).Interface().(types.SecretsConfig)}
+			r.applyLocked(&c, "Secrets")
+			registerSecretProviders(&c.Secrets)
+		}
+	}
+	r.Unlock()
+	if len(sections) == 0 {
+		return
+	}
+
+	configureLogs(config)
+	for i := range sections {
+		recreateOutputs(i)
+	}
+}
+
+// run refreshes the references every interval until ctx is done
+func (r *secretReferences) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.refresh()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// recreateOutputs creates again the enabled outputs of the section with its current settings, their clients may
+// have used the previous secrets. outputsLock must be held.
+func recreateOutputs(section string) {
+	var destinations []string
+	for destination, s := range adminOutputs {
+		if s.topLevel() == section && isEnabled(destination) {
+			destinations = append(destinations, destination)
+		}
+	}
+	if len(destinations) == 0 {
+		return
+	}
+
+	for _, i := range destinations {
+		removeOutput(i)
+	}
+	c := sectionConfig(config, section)
+	created := createOutputs(&c, destinations)
+	for _, i := range destinations {
+		if !contains(created, i) {
+			log.Printf("[ERROR] : Secrets - The output '%v' can't be created with the new secrets, it's removed\n", i)
+			continue
+		}
+		outputStatuses.update(i, func(s *outputStatus) { s.removed = false })
+		log.Printf("[INFO]  : Secrets - The output '%v' is created with the new secrets\n", i)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	apiKeyFile := filepath.Join(t.TempDir(), "apikey")
+	require.Nil(t, os.WriteFile(apiKeyFile, []byte("my-api-key\n"), 0600))
+	t.Setenv("FALCOSIDEKICK_TEST_PASSWORD", "my-password")
+
+	c := &types.Configuration{}
+	c.Datadog.APIKey = "${file:" + apiKeyFile + "}"
+	c.Kafka.Password = "${env:FALCOSIDEKICK_TEST_PASSWORD}"
+	c.Slack.WebhookURL = "https://hooks.slack.com/services/${env:FALCOSIDEKICK_TEST_PASSWORD}"
+	c.Webhook.CustomHeaders = map[string]string{"Authorization": "Bearer ${file:" + apiKeyFile + "}"}
+	c.Auth.Clients = []types.AuthClientConfig{{Name: "falco", TokenFile: "/etc/falco/token"}}
+	refs, errs := resolveSecrets(c)
+	require.Empty(t, errs)
+	require.Len(t, refs.list, 4)
+	require.Equal(t, "my-api-key", c.Datadog.APIKey)
+	require.Equal(t, "my-password", c.Kafka.Password)
+	require.Equal(t, "https://hooks.slack.com/services/my-password", c.Slack.WebhookURL)
+	require.Equal(t, "Bearer my-api-key", c.Webhook.CustomHeaders["Authorization"])
+	require.Equal(t, "/etc/falco/token", c.Auth.Clients[0].TokenFile)
+	require.ElementsMatch(t, []string{"my-api-key", "my-password", "https://hooks.slack.com/services/my-password", "Bearer my-api-key"}, refs.values())
+
+	refs.unresolve(c)
+	require.Equal(t, "${file:"+apiKeyFile+"}", c.Datadog.APIKey)
+
+	c = &types.Configuration{}
+	c.Datadog.APIKey = "${file:/nonexistent/apikey}"
+	c.Kafka.Password = "${unknown:password}"
+	_, errs = resolveSecrets(c)
+	require.Len(t, errs, 2)
+	require.Equal(t, "kafka.password - unknown secret provider 'unknown'", errs[1].Error())
+
+	// the settings which aren't reloaded can't reference secrets
+	c = &types.Configuration{}
+	c.Auth.Clients = []types.AuthClientConfig{{Name: "falco", TokenFile: "/etc/falco/${env:FALCOSIDEKICK_TEST_PASSWORD}"}}
+	c.Inputs.Kafka.Password = "${env:FALCOSIDEKICK_TEST_PASSWORD}"
+	refs, errs = resolveSecrets(c)
+	require.Len(t, errs, 2)
+	require.Equal(t, "auth.clients[0].tokenfile - the settings of 'auth' aren't reloaded, they can't reference secrets", errs[0].Error())
+	require.Empty(t, refs.list)
+	require.Equal(t, "/etc/falco/${env:FALCOSIDEKICK_TEST_PASSWORD}", c.Auth.Clients[0].TokenFile)
+}
+
+func TestIsSecretReference(t *testing.T) {
+	require.True(t, isSecretReference("${env:VAR}"))
+	require.False(t, isSecretReference("https://hooks.slack.com/${env:VAR}"))
+	require.False(t, isSecretReference("my-secret"))
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "my-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/falcosidekick/slack" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"webhookurl": "https://hooks.slack.com/services/XXX"}, "metadata": {"version": 2}}}`))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.Nil(t, os.WriteFile(tokenFile, []byte("my-token"), 0600))
+	defer delete(secretProviders, "vault")
+
+	c := &types.Configuration{}
+	c.Secrets.Vault = types.VaultSecretsConfig{Address: server.URL, Token: "${file:" + tokenFile + "}", Mount: "kv"}
+	c.Slack.WebhookURL = "${vault:falcosidekick/slack#webhookurl}"
+	c.Discord.WebhookURL = "${vault:falcosidekick/discord#webhookurl}"
+	_, errs := resolveSecrets(c)
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "404 Not Found")
+	require.Equal(t, "my-token", c.Secrets.Vault.Token)
+	require.Equal(t, "https://hooks.slack.com/services/XXX", c.Slack.WebhookURL)
+
+	_, err := secretProviders["vault"].resolve("falcosidekick/slack")
+	require.NotNil(t, err)
+	_, err = secretProviders["vault"].resolve("falcosidekick/slack#unknown")
+	require.NotNil(t, err)
+}
+
+func TestSecretReferencesRefresh(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	require.Nil(t, os.WriteFile(passwordFile, []byte("first"), 0600))
+	addressFile := filepath.Join(dir, "address")
+	require.Nil(t, os.WriteFile(addressFile, []byte("http://localhost:2801"), 0600))
+
+	config = &types.Configuration{}
+	config.Elasticsearch.Password = "${file:" + passwordFile + "}"
+	config.Webhook.Address = "${file:" + addressFile + "}"
+	refs, errs := resolveSecrets(config)
+	require.Empty(t, errs)
+	outputs.EnabledOutputs = nil
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	defer func() {
+		configSecrets = new(secretReferences)
+		outputs.EnabledOutputs = nil
+	}()
+	configSecrets = refs
+	initOutputs(config)
+	require.Equal(t, "first", config.Elasticsearch.Password)
+	require.Equal(t, "http://localhost:2801", webhookClient.EndpointURL.String())
+
+	require.Nil(t, os.WriteFile(passwordFile, []byte("second"), 0600))
+	require.Nil(t, os.WriteFile(addressFile, []byte("http://localhost:2802"), 0600))
+	refs.refresh()
+	// the clients are created again with a copy of their section with the new settings
+	require.Equal(t, "first", config.Elasticsearch.Password)
+	require.Equal(t, "http://localhost:2802", webhookClient.EndpointURL.String())
+	require.Equal(t, "http://localhost:2802", webhookClient.Config.Webhook.Address)
+	c := sectionConfig(config, "Elasticsearch")
+	require.Equal(t, "second", c.Elasticsearch.Password)
+	require.True(t, outputStatuses.allow("webhook"))
+	require.Equal(t, []string{"Webhook"}, outputs.EnabledOutputs)
+	require.True(t, refs.contains("second"))
+	require.False(t, refs.contains("first"))
+
+	// the previous value is kept if the secret can't be read
+	require.Nil(t, os.Remove(passwordFile))
+	refs.refresh()
+	c = sectionConfig(config, "Elasticsearch")
+	require.Equal(t, "second", c.Elasticsearch.Password)
+}
+
+func TestSecretReferencesRefreshDuringPosts(t *testing.T) {
+	var authorizations sync.Map
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations.Store(r.Header.Get("Authorization"), true)
+	}))
+	defer ts.Close()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.Nil(t, os.WriteFile(tokenFile, []byte("token-0"), 0600))
+
+	config = &types.Configuration{}
+	config.Webhook.Address = ts.URL
+	config.Webhook.CheckCert = true
+	config.Webhook.CustomHeaders = map[string]string{"Authorization": "Bearer ${file:" + tokenFile + "}", "X-Source": "falco"}
+	stats = &types.Statistics{Webhook: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{Outputs: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_outputs"}, []string{"destination", "status"})}
+	refs, errs := resolveSecrets(config)
+	require.Empty(t, errs)
+	outputs.EnabledOutputs = nil
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	defer func() {
+		configSecrets = new(secretReferences)
+		outputs.EnabledOutputs = nil
+	}()
+	configSecrets = refs
+	initOutputs(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				outputsLock.RLock()
+				client := webhookClient
+				outputsLock.RUnlock()
+				_ = client.WebhookPost(ctx, types.FalcoPayload{Rule: "Test rule"})
+			}
+		}()
+	}
+	for i := 1; i <= 10; i++ {
+		require.Nil(t, os.WriteFile(tokenFile, []byte(fmt.Sprintf("token-%v", i)), 0600))
+		refs.refresh()
+	}
+	cancel()
+	wg.Wait()
+
+	require.Equal(t, "Bearer token-0", config.Webhook.CustomHeaders["Authorization"])
+	require.Nil(t, webhookClient.WebhookPost(context.Background(), types.FalcoPayload{Rule: "Test rule"}))
+	_, ok := authorizations.Load("Bearer token-10")
+	require.True(t, ok)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+)
+
+var (
+	// outputsContext is the parent of the contexts of the posts to the outputs, it's canceled at the end of the
+	// shutdown
+	outputsContext, cancelOutputs = context.WithCancel(context.Background())
+	// outputsInFlight counts the posts to the outputs not finished yet
+	outputsInFlight sync.WaitGroup
+)
+
+// detachedContext keeps the values of a context, like the tracing data, but its deadline and cancellation are the
+// ones of the outputs.
+type detachedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// detachContext returns a context for the posts of an event which outlives the request or the message it comes
+// from, the posts are canceled only at the end of the shutdown.
+func detachContext(ctx context.Context) context.Context {
+	return detachedContext{Context: outputsContext, values: ctx}
+}
+
+// shutdown stops the servers, then waits for the posts in flight and the spool of the forward output before canceling
+// them, the whole shutdown lasts at most shutdowntimeout seconds.
+func shutdown(servers ...*http.Server) {
+	log.Printf("[INFO]  : Shutting down, waiting up to %vs for the events in flight\n", config.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	for _, i := range servers {
+		if err := i.Shutdown(ctx); err != nil {
+			log.Printf("[ERROR] : Shutdown - %v\n", err)
+		}
+	}
+	if natsInput != nil {
+		if err := natsInput.Drain(); err != nil {
+			log.Printf("[ERROR] : NATS Input - %v\n", err)
+		}
+	}
+	// the last events don't wait for the flush interval of their batch
+	outputs.FlushBatches()
+
+	done := make(chan struct{})
+	go func() {
+		// the Kafka input commits the offsets of its last messages once they're sent
+		if kafkaInputDone != nil {
+			<-kafkaInputDone
+		}
+		outputsInFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("[WARN] : Shutdown - Timeout reached, the posts in flight are canceled\n")
+	}
+	// the events queued by the forward output are sent before the end of the timeout
+	outputsLock.RLock()
+	if forwardClient != nil {
+		forwardClient.Close(ctx)
+	}
+	outputsLock.RUnlock()
+	cancelOutputs()
+	if kafkaInputDone != nil {
+		<-kafkaInputDone
+	}
+
+	// the spans and the metrics of the last events are sent, even if the timeout is reached
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("[ERROR] : Tracing - %v\n", err)
+	}
+	if err := shutdownOTLPMetrics(ctx); err != nil {
+		log.Printf("[ERROR] : OTLP Metrics - %v\n", err)
+	}
+}
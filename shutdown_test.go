@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testContextKey struct{}
+
+func TestDetachContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), testContextKey{}, "value"))
+	ctx := detachContext(parent)
+	cancel()
+
+	// the values are kept, the cancellation of the request isn't
+	require.Equal(t, "value", ctx.Value(testContextKey{}))
+	require.Nil(t, ctx.Err())
+	_, ok := ctx.Deadline()
+	require.False(t, ok)
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"encoding/json"
 	"expvar"
 	"fmt"
+	"log"
+	"net/http"
 	"runtime"
+	"strings"
+	"time"
 
+	"github.com/falcosecurity/falcosidekick/logger"
 	"github.com/falcosecurity/falcosidekick/outputs"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// startTime is the start of falcosidekick, the stats are counted since then
+var startTime = time.Now()
+
 func getInitStats() *types.Statistics {
 	expvar.Publish("goroutines", expvar.Func(func() interface{} {
 		return fmt.Sprintf("%d", runtime.NumGoroutine())
@@ -19,8 +28,16 @@ func getInitStats() *types.Statistics {
 
 	stats = &types.Statistics{
 		Requests:          getInputNewMap("requests"),
+		Clients:           expvar.NewMap("inputs.clients"),
 		FIFO:              getInputNewMap("fifo"),
 		GRPC:              getInputNewMap("grpc"),
+		KafkaInput:        getInputNewMap("kafka"),
+		NatsInput:         getInputNewMap("nats"),
+		CloudEventsInput:  getInputNewMap("cloudevents"),
+		ForwardInput:      getInputNewMap("forward"),
+		ReplayInput:       getInputNewMap("replay"),
+		Stream:            expvar.NewMap("stream"),
+		Batches:           expvar.NewMap("outputs.batches"),
 		Falco:             expvar.NewMap("falco.priority"),
 		Slack:             getOutputNewMap("slack"),
 		Cliq:              getOutputNewMap("cliq"),
@@ -81,6 +98,7 @@ func getInitStats() *types.Statistics {
 		N8N:               getOutputNewMap("n8n"),
 		OpenObserve:       getOutputNewMap("openobserve"),
 		Dynatrace:         getOutputNewMap("dynatrace"),
+		Forward:           getOutputNewMap("forward"),
 	}
 	stats.Falco.Add(outputs.Emergency, 0)
 	stats.Falco.Add(outputs.Alert, 0)
@@ -110,3 +128,101 @@ func getOutputNewMap(s string) *expvar.Map {
 	e.Add(outputs.OK, 0)
 	return e
 }
+
+// statsResponse summarises the stats since the start, the rates are per second
+type statsResponse struct {
+	Uptime     int64                       `json:"uptime_seconds"`
+	Events     eventsStats                 `json:"events"`
+	Inputs     map[string]map[string]int64 `json:"inputs"`
+	Priorities map[string]int64            `json:"priorities"`
+	Outputs    map[string]outputStats      `json:"outputs"`
+}
+
+// eventsStats counts the events received by all the inputs
+type eventsStats struct {
+	Total int64              `json:"total"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// outputStats counts the posts to an output
+type outputStats struct {
+	Total      int64              `json:"total"`
+	OK         int64              `json:"ok"`
+	Errors     int64              `json:"errors"`
+	Rates      map[string]float64 `json:"rates"`
+	ErrorRates map[string]float64 `json:"error_rates"`
+	LastError  *outputError       `json:"last_error,omitempty"`
+}
+
+// outputError is the last error of an output, the secrets of the configuration are redacted
+type outputError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// expvarCounters returns the counters of an expvar map
+func expvarCounters(m *expvar.Map) map[string]int64 {
+	counters := make(map[string]int64)
+	m.Do(func(kv expvar.KeyValue) {
+		if i, ok := kv.Value.(*expvar.Int); ok {
+			counters[kv.Key] = i.Value()
+		}
+	})
+	return counters
+}
+
+// getStats summarises the stats of the inputs, the priorities of the events and the enabled outputs
+func getStats() statsResponse {
+	response := statsResponse{
+		Uptime:     int64(time.Since(startTime).Seconds()),
+		Inputs:     make(map[string]map[string]int64),
+		Priorities: make(map[string]int64),
+		Outputs:    make(map[string]outputStats),
+	}
+
+	expvar.Do(func(kv expvar.KeyValue) {
+		if m, ok := kv.Value.(*expvar.Map); ok && strings.HasPrefix(kv.Key, "inputs.") {
+			response.Inputs[strings.TrimPrefix(kv.Key, "inputs.")] = expvarCounters(m)
+		}
+	})
+	if stats != nil && stats.Falco != nil {
+		response.Priorities = expvarCounters(stats.Falco)
+	}
+	for _, i := range response.Priorities {
+		response.Events.Total += i
+	}
+	response.Events.Rates = rates.get(eventsRate)
+
+	outputsLock.RLock()
+	defer outputsLock.RUnlock()
+	for destination := range adminOutputs {
+		if !isEnabled(destination) {
+			continue
+		}
+		status := outputStatuses.get(destination)
+		s := outputStats{
+			Total:      status.ok + status.errors,
+			OK:         status.ok,
+			Errors:     status.errors,
+			Rates:      rates.get(outputRate(destination)),
+			ErrorRates: rates.get(outputErrorRate(destination)),
+		}
+		if !status.lastError.IsZero() {
+			s.LastError = &outputError{Time: status.lastError, Message: logger.Redact(status.lastErrorMessage)}
+		}
+		response.Outputs[destination] = s
+	}
+	return response
+}
+
+// statsHandler answers with the stats since the start
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Please send with GET http method", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set(outputs.ContentTypeHeaderKey, "application/json")
+	if err := json.NewEncoder(w).Encode(getStats()); err != nil {
+		log.Printf("[ERROR] : Stats - %v\n", err)
+	}
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 
+	"github.com/falcosecurity/falcosidekick/outputs"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -16,6 +21,21 @@ func getInitPromStats(config *types.Configuration) *types.PromStatistics {
 		Falco:   getFalcoNewCounterVec(config),
 		Inputs:  getInputNewCounterVec(),
 		Outputs: getOutputNewCounterVec(),
+		Clients: getClientNewCounterVec(),
+
+		KafkaInputLag:     getKafkaInputLagNewGaugeVec(),
+		StreamSubscribers: getStreamSubscribersNewGauge(),
+		StreamDropped:     getStreamDroppedNewCounter(),
+		OutputBatches:     getOutputBatchesNewCounterVec(),
+		OutputBatchSize:   getOutputBatchSizeNewHistogramVec(),
+
+		OutputLatency:      getOutputLatencyNewHistogramVec(),
+		OutputSendDuration: getOutputSendDurationNewHistogramVec(),
+		OutputsInFlight:    getOutputsInFlightNewGaugeVec(),
+		OutputQueueDepth:   getOutputQueueDepthNewGaugeVec(),
+		OutputErrors:       getOutputErrorsNewCounterVec(),
+
+		FalcoCollapsedLabels: getFalcoCollapsedLabelsNewCounterVec(),
 	}
 	return promStats
 }
@@ -38,6 +58,136 @@ func getOutputNewCounterVec() *prometheus.CounterVec {
 	)
 }
 
+func getClientNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_inputs_clients",
+		},
+		[]string{"client", "status"},
+	)
+}
+
+func getKafkaInputLagNewGaugeVec() *prometheus.GaugeVec {
+	return promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "falcosidekick_inputs_kafka_lag",
+		},
+		[]string{"topic", "partition"},
+	)
+}
+
+func getStreamSubscribersNewGauge() prometheus.Gauge {
+	return promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "falcosidekick_stream_subscribers",
+		},
+	)
+}
+
+func getStreamDroppedNewCounter() prometheus.Counter {
+	return promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_stream_dropped_events",
+		},
+	)
+}
+
+func getOutputBatchesNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_outputs_batches",
+		},
+		[]string{"destination", "status"},
+	)
+}
+
+func getOutputBatchSizeNewHistogramVec() *prometheus.HistogramVec {
+	return promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "falcosidekick_outputs_batch_size",
+			Buckets: []float64{1, 5, 10, 50, 100, 500, 1000, 5000},
+		},
+		[]string{"destination"},
+	)
+}
+
+func getOutputLatencyNewHistogramVec() *prometheus.HistogramVec {
+	return promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "falcosidekick_outputs_latency_seconds",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300},
+		},
+		[]string{"destination"},
+	)
+}
+
+func getOutputSendDurationNewHistogramVec() *prometheus.HistogramVec {
+	return promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "falcosidekick_outputs_send_duration_seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"destination", "status"},
+	)
+}
+
+func getOutputsInFlightNewGaugeVec() *prometheus.GaugeVec {
+	return promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "falcosidekick_outputs_in_flight",
+		},
+		[]string{"destination"},
+	)
+}
+
+func getOutputQueueDepthNewGaugeVec() *prometheus.GaugeVec {
+	return promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "falcosidekick_outputs_queue_depth",
+		},
+		[]string{"destination"},
+	)
+}
+
+func getOutputErrorsNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_outputs_errors",
+		},
+		[]string{"destination", "class"},
+	)
+}
+
+func getFalcoCollapsedLabelsNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_falco_events_collapsed_labels",
+		},
+		[]string{"label", "reason"},
+	)
+}
+
+// observeDelivery updates the metrics of a post of an event to an output, started at start, they're exported to
+// Prometheus and to the OTLP collector.
+func observeDelivery(destination string, falcopayload types.FalcoPayload, start time.Time, err error) {
+	ctx := context.Background()
+	status := outputs.OK
+	if err != nil {
+		status = outputs.Error
+		class := outputs.ClassifyError(err)
+		promStats.OutputErrors.With(map[string]string{"destination": destination, "class": class}).Inc()
+		otlpOutputErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("destination", destination), attribute.String("class", class)))
+	}
+	duration := time.Since(start).Seconds()
+	promStats.OutputSendDuration.With(map[string]string{"destination": destination, "status": status}).Observe(duration)
+	otlpOutputSendDuration.Record(ctx, duration, metric.WithAttributes(attribute.String("destination", destination), attribute.String("status", status)))
+	if err == nil && !falcopayload.Time.IsZero() {
+		latency := time.Since(falcopayload.Time).Seconds()
+		promStats.OutputLatency.With(map[string]string{"destination": destination}).Observe(latency)
+		otlpOutputLatency.Record(ctx, latency, metric.WithAttributes(attribute.String("destination", destination)))
+	}
+}
+
 func getFalcoNewCounterVec(config *types.Configuration) *prometheus.CounterVec {
 	regPromLabels, _ := regexp.Compile("^[a-zA-Z_:][a-zA-Z0-9_:]*$")
 	labelnames := []string{
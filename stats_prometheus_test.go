@@ -2,9 +2,14 @@ package main
 
 import (
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 
+	"github.com/falcosecurity/falcosidekick/outputs"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -24,3 +29,26 @@ func TestFalcoNewCounterVec(t *testing.T) {
 	metricDescString := mm.Desc().String()
 	require.Equal(t, metricDescString, "Desc{fqName: \"falco_events\", help: \"\", constLabels: {}, variableLabels: [{hostname <nil>} {rule <nil>} {priority <nil>} {k8s_ns_name <nil>} {k8s_pod_name <nil>} {test <nil>}]}")
 }
+
+func TestObserveDelivery(t *testing.T) {
+	promStats = &types.PromStatistics{
+		OutputLatency:      prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_latency"}, []string{"destination"}),
+		OutputSendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_send_duration"}, []string{"destination", "status"}),
+		OutputErrors:       prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors"}, []string{"destination", "class"}),
+	}
+	falcopayload := types.FalcoPayload{Time: time.Now().Add(-2 * time.Second)}
+
+	observeDelivery("slack", falcopayload, time.Now(), nil)
+	observeDelivery("slack", falcopayload, time.Now(), outputs.ErrBadGateway)
+	observeDelivery("slack", types.FalcoPayload{}, time.Now(), nil)
+
+	require.Equal(t, 1, testutil.CollectAndCount(promStats.OutputLatency))
+	require.Equal(t, 2, testutil.CollectAndCount(promStats.OutputSendDuration))
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.OutputErrors.WithLabelValues("slack", outputs.ServerErrorClass)))
+
+	// the events without time aren't observed in the latency
+	var m dto.Metric
+	require.Nil(t, promStats.OutputLatency.WithLabelValues("slack").(prometheus.Histogram).Write(&m))
+	require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	require.GreaterOrEqual(t, m.GetHistogram().GetSampleSum(), 2.0)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestStatsHandler(t *testing.T) {
+	stats = &types.Statistics{Falco: new(expvar.Map).Init()}
+	stats.Falco.Add("critical", 2)
+	stats.Falco.Add("warning", 1)
+	outputs.EnabledOutputs = []string{"Webhook"}
+	outputStatuses = &outputRegistry{statuses: make(map[string]*outputStatus)}
+	defer func() { outputs.EnabledOutputs = nil }()
+
+	outputStatuses.observe("webhook", nil)
+	outputStatuses.observe("webhook", errors.New("refused"))
+
+	w := httptest.NewRecorder()
+	statsHandler(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response statsResponse
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, int64(3), response.Events.Total)
+	require.Equal(t, int64(2), response.Priorities["critical"])
+	require.Len(t, response.Outputs, 1)
+	webhook := response.Outputs["webhook"]
+	require.Equal(t, int64(2), webhook.Total)
+	require.Equal(t, int64(1), webhook.Errors)
+	require.Equal(t, "refused", webhook.LastError.Message)
+	require.Contains(t, webhook.Rates, "15m")
+
+	w = httptest.NewRecorder()
+	statsHandler(w, httptest.NewRequest(http.MethodPost, "/stats", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
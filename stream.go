@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+const streamKeepAliveInterval = 15 * time.Second
+
+// ErrTooManySubscribers is returned when the maximum number of subscribers to the stream is reached
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+var streamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// streamFilter selects the events sent to a subscriber, the empty criteria match everything
+type streamFilter struct {
+	priority  types.PriorityType
+	rule      *regexp.Regexp
+	namespace string
+	tags      []string
+}
+
+// streamSubscriber receives the events through a bounded channel, the events are dropped when it's full
+type streamSubscriber struct {
+	events chan []byte
+	filter streamFilter
+}
+
+// streamBroker publishes the events to the subscribers of the /stream endpoint, without ever blocking
+type streamBroker struct {
+	bufferSize     int
+	maxSubscribers int
+	subscribers    map[*streamSubscriber]struct{}
+	sync.RWMutex
+}
+
+func newStreamBroker(config types.StreamConfig) *streamBroker {
+	return &streamBroker{
+		bufferSize:     config.BufferSize,
+		maxSubscribers: config.MaxSubscribers,
+		subscribers:    make(map[*streamSubscriber]struct{}),
+	}
+}
+
+func (b *streamBroker) subscribe(filter streamFilter) (*streamSubscriber, error) {
+	b.Lock()
+	defer b.Unlock()
+	if len(b.subscribers) >= b.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	s := &streamSubscriber{events: make(chan []byte, b.bufferSize), filter: filter}
+	b.subscribers[s] = struct{}{}
+	promStats.StreamSubscribers.Set(float64(len(b.subscribers)))
+	return s, nil
+}
+
+func (b *streamBroker) unsubscribe(s *streamSubscriber) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.subscribers, s)
+	promStats.StreamSubscribers.Set(float64(len(b.subscribers)))
+}
+
+// publish sends the event to the matching subscribers, the event is dropped for the subscribers too slow to read it.
+func (b *streamBroker) publish(falcopayload types.FalcoPayload) {
+	if b == nil {
+		return
+	}
+	b.RLock()
+	defer b.RUnlock()
+	if len(b.subscribers) == 0 {
+		return
+	}
+
+	var event []byte
+	for s := range b.subscribers {
+		if !s.filter.match(falcopayload) {
+			continue
+		}
+		if event == nil {
+			var err error
+			if event, err = json.Marshal(falcopayload); err != nil {
+				log.Printf("[ERROR] : Stream - %v\n", err)
+				return
+			}
+		}
+		select {
+		case s.events <- event:
+		default:
+			stats.Stream.Add("dropped", 1)
+			promStats.StreamDropped.Inc()
+		}
+	}
+}
+
+// newStreamFilter parses the filters of the query: priority (minimum), rule (regular expression), namespace and
+// tags (comma separated, all must be present).
+func newStreamFilter(r *http.Request) (streamFilter, error) {
+	var filter streamFilter
+	query := r.URL.Query()
+	if p := query.Get("priority"); p != "" {
+		if checkPriority(p) == "" {
+			return streamFilter{}, fmt.Errorf("unknown priority '%v'", p)
+		}
+		filter.priority = types.Priority(p)
+	}
+	if rule := query.Get("rule"); rule != "" {
+		reg, err := regexp.Compile(rule)
+		if err != nil {
+			return streamFilter{}, fmt.Errorf("invalid rule regular expression: %v", err)
+		}
+		filter.rule = reg
+	}
+	filter.namespace = query.Get("namespace")
+	if tags := query.Get("tags"); tags != "" {
+		filter.tags = strings.Split(strings.ReplaceAll(tags, " ", ""), ",")
+	}
+	return filter, nil
+}
+
+func (f streamFilter) match(falcopayload types.FalcoPayload) bool {
+	if falcopayload.Priority < f.priority {
+		return false
+	}
+	if f.rule != nil && !f.rule.MatchString(falcopayload.Rule) {
+		return false
+	}
+	if f.namespace != "" {
+		if ns, _ := falcopayload.OutputFields["k8s.ns.name"].(string); ns != f.namespace {
+			return false
+		}
+	}
+	for _, i := range f.tags {
+		found := false
+		for _, j := range falcopayload.Tags {
+			if i == j {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// streamHandler sends the events live, through a WebSocket if the client asks for an upgrade, with Server-Sent
+// Events otherwise.
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := newStreamFilter(r)
+	if err != nil {
+		http.Error(w, "Please send valid filters: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s, err := eventStream.subscribe(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer eventStream.unsubscribe(s)
+	stats.Stream.Add("subscriptions", 1)
+
+	if websocket.IsWebSocketUpgrade(r) {
+		streamWebSocket(w, r, s)
+		return
+	}
+	streamServerSentEvents(w, r, s)
+}
+
+func streamServerSentEvents(w http.ResponseWriter, r *http.Request, s *streamSubscriber) {
+	rc := http.NewResponseController(w)
+	// the stream lasts longer than the write timeout of the server
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(outputs.ContentTypeHeaderKey, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-s.events:
+			fmt.Fprintf(w, "event: falco\ndata: %s\n\n", event)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func streamWebSocket(w http.ResponseWriter, r *http.Request, s *streamSubscriber) {
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered with an error
+		return
+	}
+	defer conn.Close()
+
+	// the messages of the client are ignored, reading is required to handle the close and pong messages
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Time{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			// the connection is hijacked, the server doesn't close it at the shutdown
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		case <-keepAlive.C:
+			conn.SetWriteDeadline(time.Now().Add(streamKeepAliveInterval))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		case event := <-s.events:
+			conn.SetWriteDeadline(time.Now().Add(streamKeepAliveInterval))
+			err = conn.WriteMessage(websocket.TextMessage, event)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestStreamFilter(t *testing.T) {
+	falcopayload := types.FalcoPayload{
+		Rule:         "Write below etc",
+		Priority:     types.Error,
+		OutputFields: map[string]interface{}{"k8s.ns.name": "default"},
+		Tags:         []string{"filesystem", "mitre_persistence"},
+	}
+
+	for query, expected := range map[string]bool{
+		"":                             true,
+		"priority=warning":             true,
+		"priority=critical":            false,
+		"rule=^Write":                  true,
+		"rule=shell":                   false,
+		"namespace=default":            true,
+		"namespace=kube-system":        false,
+		"tags=filesystem":              true,
+		"tags=filesystem,network":      false,
+		"priority=error&rule=etc$":     true,
+		"namespace=default&tags=other": false,
+	} {
+		filter, err := newStreamFilter(httptest.NewRequest(http.MethodGet, "/stream?"+query, nil))
+		require.Nil(t, err, query)
+		require.Equal(t, expected, filter.match(falcopayload), query)
+	}
+
+	for _, query := range []string{"priority=foo", "rule=("} {
+		_, err := newStreamFilter(httptest.NewRequest(http.MethodGet, "/stream?"+query, nil))
+		require.NotNil(t, err, query)
+	}
+}
+
+func TestStreamHandler(t *testing.T) {
+	config = &types.Configuration{}
+	stats = &types.Statistics{Stream: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		StreamSubscribers: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_subscribers"}),
+		StreamDropped:     prometheus.NewCounter(prometheus.CounterOpts{Name: "test_dropped"}),
+	}
+	eventStream = newStreamBroker(types.StreamConfig{BufferSize: 1, MaxSubscribers: 2})
+	defer func() { eventStream = nil }()
+
+	ts := httptest.NewServer(http.HandlerFunc(streamHandler))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?rule=Test")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.Nil(t, err)
+	defer ws.Close()
+
+	// no room for a third subscriber
+	third, err := http.Get(ts.URL)
+	require.Nil(t, err)
+	third.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, third.StatusCode)
+
+	require.Eventually(t, func() bool {
+		eventStream.RLock()
+		defer eventStream.RUnlock()
+		return len(eventStream.subscribers) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	eventStream.publish(types.FalcoPayload{Rule: "Other rule", Priority: types.Debug})
+	eventStream.publish(types.FalcoPayload{Rule: "Test rule", Priority: types.Debug})
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.Nil(t, err)
+	require.Equal(t, "event: falco\n", line)
+	line, err = reader.ReadString('\n')
+	require.Nil(t, err)
+	require.Contains(t, line, `"rule":"Test rule"`)
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := ws.ReadMessage()
+	require.Nil(t, err)
+	require.Contains(t, string(message), `"rule":"Other rule"`)
+}
+
+func TestStreamWebSocketShutdown(t *testing.T) {
+	config = &types.Configuration{}
+	stats = &types.Statistics{Stream: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		StreamSubscribers: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_subscribers"}),
+		StreamDropped:     prometheus.NewCounter(prometheus.CounterOpts{Name: "test_dropped"}),
+	}
+	eventStream = newStreamBroker(types.StreamConfig{BufferSize: 1, MaxSubscribers: 1})
+	defer func() { eventStream = nil }()
+
+	// the requests are canceled at the shutdown, like with the servers of main
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(streamHandler))
+	ts.Config.BaseContext = func(net.Listener) context.Context { return ctx }
+	ts.Start()
+	defer ts.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.Nil(t, err)
+	defer ws.Close()
+
+	cancel()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = ws.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
+
+func TestStreamBrokerDoesNotBlock(t *testing.T) {
+	stats = &types.Statistics{Stream: new(expvar.Map).Init()}
+	promStats = &types.PromStatistics{
+		StreamSubscribers: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_subscribers"}),
+		StreamDropped:     prometheus.NewCounter(prometheus.CounterOpts{Name: "test_dropped"}),
+	}
+	b := newStreamBroker(types.StreamConfig{BufferSize: 2, MaxSubscribers: 1})
+	s, err := b.subscribe(streamFilter{})
+	require.Nil(t, err)
+
+	// nobody reads the events, the ones beyond the buffer are dropped
+	for i := 0; i < 5; i++ {
+		b.publish(types.FalcoPayload{Rule: "Test rule"})
+	}
+	require.Len(t, s.events, 2)
+	require.Equal(t, "3", stats.Stream.Get("dropped").String())
+
+	b.unsubscribe(s)
+	_, err = b.subscribe(streamFilter{})
+	require.Nil(t, err)
+}
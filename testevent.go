@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// testEventRequest sets the fields of a test event and selects its outputs, all the enabled ones if none is set
+type testEventRequest struct {
+	Outputs      []string               `json:"outputs"`
+	Priority     string                 `json:"priority"`
+	Rule         string                 `json:"rule"`
+	Output       string                 `json:"output"`
+	Tags         []string               `json:"tags"`
+	OutputFields map[string]interface{} `json:"output_fields"`
+}
+
+// newTestEventRequest reads the settings of the test event from the JSON body, if any, then from the query: outputs
+// and tags (comma separated lists), priority, rule, output and output_field (key:value, repeated).
+func newTestEventRequest(r *http.Request, body io.Reader) (testEventRequest, error) {
+	req := testEventRequest{
+		Priority: "Debug",
+		Rule:     testRule,
+		Output:   "This is a test from falcosidekick",
+		Tags:     []string{"test", "example"},
+	}
+	d := json.NewDecoder(body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return testEventRequest{}, fmt.Errorf("invalid body: %v", describeDecodingError(err))
+	}
+
+	query := r.URL.Query()
+	for key, value := range map[string]*string{"priority": &req.Priority, "rule": &req.Rule, "output": &req.Output} {
+		if v := query.Get(key); v != "" {
+			*value = v
+		}
+	}
+	for key, list := range map[string]*[]string{"outputs": &req.Outputs, "tags": &req.Tags} {
+		if v := query.Get(key); v != "" {
+			*list = strings.Split(strings.ReplaceAll(v, " ", ""), ",")
+		}
+	}
+	for _, i := range query["output_field"] {
+		key, value, found := strings.Cut(i, ":")
+		if !found || key == "" {
+			return testEventRequest{}, fmt.Errorf("invalid output field '%v', it should be key:value", i)
+		}
+		if req.OutputFields == nil {
+			req.OutputFields = make(map[string]interface{})
+		}
+		req.OutputFields[key] = value
+	}
+	if req.OutputFields == nil {
+		req.OutputFields = map[string]interface{}{"proc.name": "falcosidekick", "user.name": "falcosidekick"}
+	}
+
+	if checkPriority(req.Priority) == "" {
+		return testEventRequest{}, fmt.Errorf("unknown priority '%v'", req.Priority)
+	}
+	for i := range req.Outputs {
+		req.Outputs[i] = strings.ToLower(req.Outputs[i])
+	}
+	return req, nil
+}
+
+// testResult is the result of the post of a test event to an output
+type testResult struct {
+	Output     string  `json:"output"`
+	Status     string  `json:"status"`
+	StatusCode int     `json:"status_code,omitempty"`
+	Latency    float64 `json:"latency_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// testResponse is the answer to a test event, with a result for each selected output
+type testResponse struct {
+	UUID    string       `json:"uuid"`
+	Results []testResult `json:"results"`
+}
+
+// skippedStatus is the status of the outputs which haven't received the test event, because they're paused or
+// because of their minimum priority
+const skippedStatus = "skipped"
+
+// testDelivery selects the outputs of a test event and collects the results of its posts
+type testDelivery struct {
+	// outputs are the selected outputs, all the enabled ones if empty
+	outputs map[string]bool
+	results map[string]testResult
+	sync.Mutex
+}
+
+type testDeliveryKey struct{}
+
+func newTestDeliveryContext(ctx context.Context, delivery *testDelivery) context.Context {
+	return context.WithValue(ctx, testDeliveryKey{}, delivery)
+}
+
+// getTestDelivery returns the test delivery of the context, nil for the other events
+func getTestDelivery(ctx context.Context) *testDelivery {
+	delivery, _ := ctx.Value(testDeliveryKey{}).(*testDelivery)
+	return delivery
+}
+
+// selects tells whether the event is sent to the output, a nil delivery selects all the outputs
+func (d *testDelivery) selects(destination string) bool {
+	return d == nil || len(d.outputs) == 0 || d.outputs[destination]
+}
+
+// add records the result of a post, a nil delivery records nothing
+func (d *testDelivery) add(destination string, code int, start time.Time, err error) {
+	if d == nil {
+		return
+	}
+	result := testResult{Output: destination, Status: outputs.OK, StatusCode: code, Latency: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = outputs.Error
+		result.Error = err.Error()
+	}
+	d.Lock()
+	defer d.Unlock()
+	d.results[destination] = result
+}
+
+// testHandler sends a test event to the selected outputs, or to all the enabled ones, and answers with the result
+// of each post once they're done. The minimum priorities of the outputs are ignored for the default rule only.
+func testHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Please send with GET or POST http method", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := newRequestBodyReader(w, r)
+	if err != nil {
+		http.Error(w, "Please send a valid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+	req, err := newTestEventRequest(r, body)
+	if err != nil {
+		http.Error(w, "Please send a valid test event: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	delivery := &testDelivery{outputs: make(map[string]bool), results: make(map[string]testResult)}
+	var destinations []string
+	outputsLock.RLock()
+	for destination := range adminOutputs {
+		if isEnabled(destination) && (len(req.Outputs) == 0 || contains(req.Outputs, destination)) {
+			destinations = append(destinations, destination)
+		}
+	}
+	outputsLock.RUnlock()
+	for _, i := range req.Outputs {
+		if !contains(destinations, i) {
+			http.Error(w, fmt.Sprintf("Unknown output '%v'", i), http.StatusBadRequest)
+			return
+		}
+		delivery.outputs[i] = true
+	}
+
+	falcopayload := processFalcoPayload(types.FalcoPayload{
+		Output:       req.Output,
+		Priority:     types.Priority(req.Priority),
+		Rule:         req.Rule,
+		Time:         time.Now().UTC(),
+		OutputFields: req.OutputFields,
+		Tags:         req.Tags,
+		Hostname:     "falcosidekick",
+	})
+	forwardEvent(newTestDeliveryContext(r.Context(), delivery), falcopayload).Wait()
+
+	results := make([]testResult, 0, len(destinations))
+	for _, i := range destinations {
+		result, ok := delivery.results[i]
+		if !ok {
+			result = testResult{Output: i, Status: skippedStatus}
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Output < results[j].Output })
+
+	w.Header().Set(outputs.ContentTypeHeaderKey, "application/json")
+	if err := json.NewEncoder(w).Encode(testResponse{UUID: falcopayload.UUID, Results: results}); err != nil {
+		log.Printf("[ERROR] : Test - %v\n", err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, i := range list {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTestEventRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req, err := newTestEventRequest(r, r.Body)
+	require.Nil(t, err)
+	require.Equal(t, testRule, req.Rule)
+	require.Equal(t, "Debug", req.Priority)
+	require.Empty(t, req.Outputs)
+	require.Equal(t, "falcosidekick", req.OutputFields["proc.name"])
+
+	body := `{"outputs": ["Webhook"], "priority": "Critical", "output_fields": {"k8s.ns.name": "prod"}}`
+	r = httptest.NewRequest(http.MethodPost, "/test?rule=My+rule&tags=a,b&output_field=proc.name:nginx", strings.NewReader(body))
+	req, err = newTestEventRequest(r, r.Body)
+	require.Nil(t, err)
+	require.Equal(t, []string{"webhook"}, req.Outputs)
+	require.Equal(t, "Critical", req.Priority)
+	require.Equal(t, "My rule", req.Rule)
+	require.Equal(t, []string{"a", "b"}, req.Tags)
+	require.Equal(t, map[string]interface{}{"k8s.ns.name": "prod", "proc.name": "nginx"}, req.OutputFields)
+
+	for _, i := range []string{"/test?priority=unknown", "/test?output_field=nokey"} {
+		r = httptest.NewRequest(http.MethodGet, i, nil)
+		_, err = newTestEventRequest(r, r.Body)
+		require.NotNil(t, err, i)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"unknown": true}`))
+	_, err = newTestEventRequest(r, r.Body)
+	require.NotNil(t, err)
+}
+
+func TestTestDelivery(t *testing.T) {
+	var none *testDelivery
+	require.True(t, none.selects("slack"))
+	none.add("slack", 200, time.Now(), nil)
+
+	delivery := &testDelivery{outputs: map[string]bool{"webhook": true}, results: make(map[string]testResult)}
+	require.True(t, delivery.selects("webhook"))
+	require.False(t, delivery.selects("slack"))
+	delivery.add("webhook", 500, time.Now(), errors.New("internal server error"))
+	require.Equal(t, "error", delivery.results["webhook"].Status)
+	require.Equal(t, 500, delivery.results["webhook"].StatusCode)
+}
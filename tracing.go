@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// tracer creates the spans of falcosidekick, they're dropped unless tracing is enabled
+var tracer = otel.Tracer("github.com/falcosecurity/falcosidekick")
+
+// tracerProvider sends the spans to the collector, it's nil if tracing is disabled
+var tracerProvider *sdktrace.TracerProvider
+
+// initTracing sends the spans to the OTLP/HTTP collector of tracing.endpoint, the W3C trace context of the requests
+// is propagated from the callers to the outputs.
+func initTracing(config *types.Configuration) error {
+	if config.Tracing.Endpoint == "" {
+		return nil
+	}
+
+	endpoint, err := url.Parse(config.Tracing.Endpoint)
+	if err != nil {
+		return err
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint.Host)}
+	switch endpoint.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return fmt.Errorf("unsupported endpoint scheme '%v'", endpoint.Scheme)
+	}
+	if p := strings.TrimSuffix(endpoint.Path, "/"); p != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(p+"/v1/traces"))
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(config.Tracing.ServiceName),
+		semconv.ServiceVersion(GetVersionInfo().GitVersion),
+	))
+	if err != nil {
+		return err
+	}
+
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return nil
+}
+
+// shutdownTracing sends the last spans to the collector
+func shutdownTracing(ctx context.Context) error {
+	if tracerProvider == nil {
+		return nil
+	}
+	return tracerProvider.Shutdown(ctx)
+}
+
+// statusRecorder keeps the status code of a response for the span of its request
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the original ResponseWriter
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withTracing starts the span of a request to route, as a child of the span of the caller if the request has a
+// traceparent header.
+func withTracing(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethod(r.Method), semconv.HTTPRoute(route)),
+		)
+		defer span.End()
+
+		rw := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPStatusCode(rw.status))
+		// a rejected event never reaches the outputs, it's an error for whoever looks for it
+		if rw.status >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(rw.status))
+		}
+	})
+}
+
+// startStage starts the span of a stage of the processing of an event, like its decoding or its enrichment
+func startStage(ctx context.Context, stage string) trace.Span {
+	_, span := tracer.Start(ctx, stage)
+	return span
+}
+
+// endSpan ends a span, with an error status if err isn't nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// eventAttributes returns the attributes of the spans about an event
+func eventAttributes(falcopayload types.FalcoPayload) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("falco.uuid", falcopayload.UUID),
+		attribute.String("falco.rule", falcopayload.Rule),
+		attribute.String("falco.priority", falcopayload.Priority.String()),
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// otlpCollector is a stand-in of an OTLP/HTTP collector, it keeps the spans it receives
+type otlpCollector struct {
+	spans []*tracepb.Span
+	sync.Mutex
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.Lock()
+	for _, i := range req.ResourceSpans {
+		for _, j := range i.ScopeSpans {
+			c.spans = append(c.spans, j.Spans...)
+		}
+	}
+	c.Unlock()
+	w.Header().Set("Content-Type", "application/x-protobuf")
+}
+
+func TestTracing(t *testing.T) {
+	collector := new(otlpCollector)
+	ts := httptest.NewServer(collector)
+	defer ts.Close()
+
+	config = &types.Configuration{Tracing: types.TracingConfig{Endpoint: ts.URL, ServiceName: "falcosidekick", SampleRatio: 1}}
+	require.Nil(t, initTracing(config))
+	defer func() {
+		tracerProvider = nil
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	}()
+
+	handler := withTracing("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endSpan(startStage(r.Context(), "decode"), nil)
+		http.Error(w, "invalid", http.StatusBadRequest)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Nil(t, shutdownTracing(context.Background()))
+
+	collector.Lock()
+	defer collector.Unlock()
+	require.Len(t, collector.spans, 2)
+	spans := make(map[string]*tracepb.Span)
+	for _, i := range collector.spans {
+		require.Equal(t, "0af7651916cd43dd8448eb211c80319c", trace.TraceID(i.TraceId).String())
+		spans[i.Name] = i
+	}
+	// the span of the request is a child of the one of the caller
+	require.Equal(t, "b7ad6b7169203331", trace.SpanID(spans["POST /"].ParentSpanId).String())
+	require.Equal(t, tracepb.Status_STATUS_CODE_ERROR, spans["POST /"].Status.Code)
+	require.Equal(t, spans["POST /"].SpanId, spans["decode"].ParentSpanId)
+}
@@ -49,10 +49,23 @@ func (f FalcoPayload) Check() bool {
 type Configuration struct {
 	MutualTLSFilesPath string
 	MutualTLSClient    MutualTLSClient
+	HTTPClient         HTTPClientConfig
 	TLSServer          TLSServer
+	Auth               AuthConfig
+	Inputs             InputsConfig
+	Stream             StreamConfig
+	Journal            JournalConfig
+	Capture            CaptureConfig
+	Admin              AdminConfig
+	Secrets            SecretsConfig
+	Logs               LogsConfig
+	Tracing            TracingConfig
 	Debug              bool
 	ListenAddress      string
 	ListenPort         int
+	MaxRequestSize     int64
+	StrictDecoding     bool
+	ShutdownTimeout    int
 	BracketReplacer    string
 	Customfields       map[string]string
 	Templatedfields    map[string]string
@@ -75,6 +88,7 @@ type Configuration struct {
 	Opsgenie           opsgenieOutputConfig
 	Statsd             statsdOutputConfig
 	Dogstatsd          statsdOutputConfig
+	OTLPMetrics        OTLPMetricsConfig
 	Webhook            WebhookOutputConfig
 	CloudEvents        CloudEventsOutputConfig
 	Azure              azureConfig
@@ -106,6 +120,7 @@ type Configuration struct {
 	N8N                N8NConfig
 	OpenObserve        OpenObserveConfig
 	Dynatrace          DynatraceOutputConfig
+	Forward            ForwardOutputConfig
 }
 
 // MutualTLSClient represents parameters for mutual TLS as client
@@ -115,6 +130,67 @@ type MutualTLSClient struct {
 	CaCertFile string
 }
 
+// LogsConfig represents parameters for the logs of falcosidekick
+// Format: text or json.
+// Level: minimum level of the logs, debug, info, warning or error, the debug mode sets it to debug.
+// SuccessInterval: minimum delay in seconds between two logs of success of an output, 0 logs all of them.
+type LogsConfig struct {
+	Format          string
+	Level           string
+	SuccessInterval int
+}
+
+// TracingConfig represents parameters for the OpenTelemetry traces
+// Endpoint: http(s)://host:port of an OTLP/HTTP collector, the spans are sent to its /v1/traces path.
+// ServiceName: name of the service of the spans.
+// SampleRatio: ratio of the traces started by falcosidekick which are sampled, the ones started by the callers follow
+// their sampling decision.
+type TracingConfig struct {
+	Endpoint    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// OTLPMetricsConfig represents parameters for the export of the metrics to an OpenTelemetry collector
+// Endpoint: http(s)://host:port of the collector, for OTLP/HTTP the metrics are sent to its /v1/metrics path.
+// Protocol: http/protobuf or grpc.
+// Temporality: cumulative or delta, the temporality of the counters and the histograms.
+// Interval: delay in seconds between two exports.
+// ServiceName: name of the service of the metrics.
+// ResourceAttributes: comma separated list of key=value added to the resource of the metrics.
+type OTLPMetricsConfig struct {
+	Endpoint               string
+	Protocol               string
+	Temporality            string
+	Interval               int
+	ServiceName            string
+	ResourceAttributes     string
+	ResourceAttributesList map[string]string
+}
+
+// HTTPClientConfig represents parameters for the HTTP clients of the outputs, the timeouts are in seconds
+// Proxy: http(s):// or socks5:// url of the proxy, "none" to not use one, if empty, the HTTP_PROXY env vars are used.
+// Outputs: settings of the outputs, by lower case name, overriding the global ones.
+type HTTPClientConfig struct {
+	ConnectTimeout        int
+	TLSHandshakeTimeout   int
+	ResponseHeaderTimeout int
+	Timeout               int
+	IdleConnTimeout       int
+	MaxIdleConnsPerHost   int
+	Proxy                 string
+	Outputs               map[string]HTTPClientOutputConfig
+}
+
+// HTTPClientOutputConfig represents the HTTP client settings of an output, the empty ones are inherited
+type HTTPClientOutputConfig struct {
+	ConnectTimeout        int
+	TLSHandshakeTimeout   int
+	ResponseHeaderTimeout int
+	Timeout               int
+	Proxy                 string
+}
+
 // TLSServer represents parameters for TLS Server
 type TLSServer struct {
 	Deploy     bool
@@ -126,6 +202,127 @@ type TLSServer struct {
 	NoTLSPaths []string
 }
 
+// AuthConfig represents parameters for the authentication of the clients sending events
+type AuthConfig struct {
+	Clients     []AuthClientConfig
+	HMACMaxSkew int
+}
+
+// AuthClientConfig represents a client identity and its permissions
+// TokenFile: file containing the static bearer token of the client.
+// HMACSecretFile: file containing the secret used by the client to sign the bodies.
+// Permissions: list of allowed actions, ingest, test or admin.
+type AuthClientConfig struct {
+	Name           string
+	TokenFile      string
+	HMACSecretFile string
+	Permissions    []string
+}
+
+// InputsConfig represents parameters for the inputs, in addition of the http server
+type InputsConfig struct {
+	Kafka KafkaInputConfig
+	Nats  NatsInputConfig
+}
+
+// KafkaInputConfig represents parameters for the Kafka consumer input
+// Topics: comma separated list of topics to consume from.
+// StartOffset: offset to start from when the group has no committed offset, first or last.
+// MaxInFlight: maximum number of messages handled by the outputs before their offsets are committed.
+// MaxRetries: maximum number of new attempts for the outputs which failed to send an event, it's then dropped.
+type KafkaInputConfig struct {
+	HostPort    string
+	Topics      string
+	TopicsList  []string
+	GroupID     string
+	SASL        string
+	TLS         bool
+	Username    string
+	Password    string
+	ClientID    string
+	StartOffset string
+	MaxInFlight int
+	MaxRetries  int
+}
+
+// NatsInputConfig represents parameters for the NATS subscription input
+// Subjects: comma separated list of subjects to subscribe to, wildcards are allowed.
+// QueueGroup: queue group shared by the sidekicks, each event is handled by only one of them.
+// JetStream: if true, the subjects are consumed through JetStream durable consumers, with explicit acks.
+// Stream: stream to bind the consumers to, if empty, it is looked up from the subjects.
+// AckWait: delay in seconds before an unacknowledged message is redelivered.
+// MaxAckPending: maximum number of messages handled by the outputs before being acknowledged.
+// MaxRetries: maximum number of new attempts for the outputs which failed to send an event, it's then dropped.
+type NatsInputConfig struct {
+	HostPort      string
+	Subjects      string
+	SubjectsList  []string
+	QueueGroup    string
+	JetStream     bool
+	Stream        string
+	Durable       string
+	AckWait       int
+	MaxAckPending int
+	MaxRetries    int
+}
+
+// StreamConfig represents parameters for the /stream endpoint
+// BufferSize: number of events kept for a subscriber too slow to read them, the next ones are dropped.
+// MaxSubscribers: maximum number of subscribers at the same time.
+type StreamConfig struct {
+	BufferSize     int
+	MaxSubscribers int
+}
+
+// JournalConfig represents parameters for the journal of the deliveries of the events
+// MaxEvents: maximum number of events kept in the journal, the oldest ones are removed, 0 disables the journal.
+// File: path of a JSON lines file where the deliveries are appended, the journal is reloaded from it at the start.
+type JournalConfig struct {
+	MaxEvents int
+	File      string
+}
+
+// CaptureConfig represents parameters for the capture of the requests of the outputs, they're recorded instead of
+// being sent
+// Outputs: comma separated list of the captured outputs, "all" for all the outputs which can be captured, empty
+// disables the capture.
+// File: path of a JSON lines file where the captured requests are appended.
+// MaxRequests: number of the last captured requests returned by the /captures endpoint.
+type CaptureConfig struct {
+	Outputs     string
+	OutputsList []string
+	File        string
+	MaxRequests int
+}
+
+// AdminConfig represents parameters for the admin API of the outputs
+// AuditLog: path of a JSON lines file where the changes made with the admin API are appended.
+type AdminConfig struct {
+	AuditLog string
+}
+
+// SecretsConfig represents parameters for the references to secrets in the settings, like ${file:/path} or ${env:VAR}
+// RefreshInterval: interval in seconds between two resolutions of the references, to follow the rotations, 0 disables it.
+// Vault: HashiCorp Vault provider of the references ${vault:path#key}.
+type SecretsConfig struct {
+	RefreshInterval int
+	Vault           VaultSecretsConfig
+}
+
+// VaultSecretsConfig represents parameters for the secrets read from a KV v2 engine of HashiCorp Vault
+// Address: address of Vault, the provider is enabled if not empty.
+// Token: token of Vault, it can be a reference too, like ${file:/path}.
+// Mount: path where the KV v2 engine is mounted.
+// Namespace: namespace of Vault Enterprise, if any.
+// CheckCert: check if the ssl certificate of Vault is valid.
+type VaultSecretsConfig struct {
+	Address   string
+	Token     string
+	Mount     string
+	Namespace string
+	CheckCert bool
+}
+
 // SlackOutputConfig represents parameters for Slack
 type SlackOutputConfig struct {
 	WebhookURL            string
@@ -250,6 +447,7 @@ type ElasticsearchOutputConfig struct {
 	CheckCert       bool
 	MutualTLS       bool
 	CustomHeaders   map[string]string
+	Batch           BatchConfig
 }
 
 type influxdbOutputConfig struct {
@@ -264,6 +462,7 @@ type influxdbOutputConfig struct {
 	MinimumPriority string
 	CheckCert       bool
 	MutualTLS       bool
+	Batch           BatchConfig
 }
 
 type LokiOutputConfig struct {
@@ -278,11 +477,30 @@ type LokiOutputConfig struct {
 	ExtraLabels     string
 	ExtraLabelsList []string
 	CustomHeaders   map[string]string
+	Batch           BatchConfig
+}
+
+// BatchConfig represents the batching of the events of an output, a batch is sent with a single request
+// MaxSize: maximum number of events in a batch, 0 or 1 disables the batching.
+// MaxBytes: maximum size in bytes of a batch, computed from the JSON size of its events, 0 for no limit.
+// FlushInterval: maximum delay in seconds before sending an incomplete batch.
+type BatchConfig struct {
+	MaxSize       int
+	MaxBytes      int
+	FlushInterval int
 }
 
 type prometheusOutputConfig struct {
 	ExtraLabels     string
 	ExtraLabelsList []string
+	// MaxLabelValues is the maximum number of distinct values of each label of falco_events, 0 is unlimited
+	MaxLabelValues int
+	// AllowedLabelValues and DeniedLabelValues are comma separated lists of label:value
+	AllowedLabelValues     string
+	AllowedLabelValuesList map[string][]string
+	DeniedLabelValues      string
+	DeniedLabelValuesList  map[string][]string
+	DropPodNames           bool
 }
 
 type natsOutputConfig struct {
@@ -729,11 +947,44 @@ type OpenObserveConfig struct {
 	CustomHeaders    map[string]string
 }
 
+// ForwardOutputConfig represents parameters for the forwarding of the events to another falcosidekick
+// Address: url of the falcosidekick receiving the events, they are sent to its /forward endpoint.
+// TokenFile: file containing the bearer token to authenticate to the other falcosidekick.
+// ClusterName: name of the cluster, added to the output fields of the events.
+// BatchSize: maximum number of events sent in a request.
+// MaxBytes: maximum size in bytes of the events of a request before their compression, 0 means no limit.
+// FlushInterval: maximum delay in seconds before sending an incomplete batch.
+// SpoolDir: directory where the batches are kept until they are acknowledged, if empty, they are kept in memory.
+// MaxSpooledBatches: maximum number of batches waiting to be sent, the oldest ones are dropped beyond.
+// RetryInterval: delay in seconds between two attempts to send a batch.
+type ForwardOutputConfig struct {
+	Address           string
+	TokenFile         string
+	ClusterName       string
+	BatchSize         int
+	MaxBytes          int
+	FlushInterval     int
+	SpoolDir          string
+	MaxSpooledBatches int
+	RetryInterval     int
+	CheckCert         bool
+	MutualTLS         bool
+	MinimumPriority   string
+}
+
 // Statistics is a struct to store stastics
 type Statistics struct {
 	Requests          *expvar.Map
+	Clients           *expvar.Map
 	FIFO              *expvar.Map
 	GRPC              *expvar.Map
+	KafkaInput        *expvar.Map
+	NatsInput         *expvar.Map
+	CloudEventsInput  *expvar.Map
+	ForwardInput      *expvar.Map
+	ReplayInput       *expvar.Map
+	Stream            *expvar.Map
+	Batches           *expvar.Map
 	Falco             *expvar.Map
 	Slack             *expvar.Map
 	Mattermost        *expvar.Map
@@ -794,6 +1045,7 @@ type Statistics struct {
 	N8N               *expvar.Map
 	OpenObserve       *expvar.Map
 	Dynatrace         *expvar.Map
+	Forward           *expvar.Map
 }
 
 // PromStatistics is a struct to store prometheus metrics
@@ -801,4 +1053,27 @@ type PromStatistics struct {
 	Falco   *prometheus.CounterVec
 	Inputs  *prometheus.CounterVec
 	Outputs *prometheus.CounterVec
+	Clients *prometheus.CounterVec
+	// KafkaInputLag is the lag of the Kafka consumer input per topic and partition
+	KafkaInputLag *prometheus.GaugeVec
+	// StreamSubscribers is the number of subscribers of the /stream endpoint
+	StreamSubscribers prometheus.Gauge
+	// StreamDropped counts the events dropped for the subscribers too slow to read them
+	StreamDropped prometheus.Counter
+	// OutputBatches counts the batches sent by the outputs, per status
+	OutputBatches *prometheus.CounterVec
+	// OutputBatchSize is the number of events of the batches sent by the outputs
+	OutputBatchSize *prometheus.HistogramVec
+	// OutputLatency is the delay between the time of the events and their delivery to the outputs
+	OutputLatency *prometheus.HistogramVec
+	// OutputSendDuration is the duration of the posts to the outputs, per status
+	OutputSendDuration *prometheus.HistogramVec
+	// OutputsInFlight is the number of posts to the outputs not finished yet
+	OutputsInFlight *prometheus.GaugeVec
+	// OutputQueueDepth is the number of events waiting in the batches and the spools of the outputs
+	OutputQueueDepth *prometheus.GaugeVec
+	// OutputErrors counts the errors of the posts to the outputs, per class
+	OutputErrors *prometheus.CounterVec
+	// FalcoCollapsedLabels counts the label values of falco_events replaced by __other__, per label and reason
+	FalcoCollapsedLabels *prometheus.CounterVec
 }
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+const (
+	serveCommand      = "serve"
+	validateCommand   = "validate"
+	configDumpCommand = "config dump"
+	replayCommand     = "replay"
+)
+
+// configProblems collects the problems of the configuration. The errors stop falcosidekick and the warnings are only
+// logged, unless the configuration is validated, then they're all listed.
+type configProblems struct {
+	validating bool
+	list       []string
+}
+
+func (p *configProblems) errorf(format string, args ...interface{}) {
+	p.list = append(p.list, fmt.Sprintf(format, args...))
+}
+
+func (p *configProblems) warnf(format string, args ...interface{}) {
+	if p.validating {
+		p.errorf(format, args...)
+		return
+	}
+	log.Printf("[ERROR] : "+format+"\n", args...)
+}
+
+// promLabelNameRegex matches the valid names of the labels of Prometheus, Alertmanager and Loki
+var promLabelNameRegex = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// endpointSettings are the settings holding the endpoints of the outputs, they must be http(s) or nats urls
+var endpointSettings = []string{
+	"Alertmanager.HostPort",
+	"Cliq.WebhookURL",
+	"CloudEvents.Address",
+	"Datadog.Host",
+	"Discord.WebhookURL",
+	"Dynatrace.APIUrl",
+	"Elasticsearch.HostPort",
+	"Forward.Address",
+	"GCP.CloudRun.Endpoint",
+	"Googlechat.WebhookURL",
+	"Gotify.HostPort",
+	"Grafana.HostPort",
+	"GrafanaOnCall.WebhookURL",
+	"Influxdb.HostPort",
+	"KafkaRest.Address",
+	"Loki.HostPort",
+	"Mattermost.WebhookURL",
+	"N8N.Address",
+	"Nats.HostPort",
+	"NodeRed.Address",
+	"OpenObserve.HostPort",
+	"Rocketchat.WebhookURL",
+	"Slack.WebhookURL",
+	"Stan.HostPort",
+	"Teams.WebhookURL",
+	"Tekton.EventListener",
+	"Webhook.Address",
+	"WebUI.URL",
+	"Zincsearch.HostPort",
+}
+
+var endpointRegex = regexp.MustCompile(`^(http|nats)(s?)://`)
+
+// createdFileSettings are the settings holding the paths of files created by falcosidekick, only their directories
+// must exist
+var createdFileSettings = []string{"Admin.AuditLog", "Journal.File", "Capture.File"}
+
+// fileSettingRegex matches the names of the settings holding the paths of files read by falcosidekick
+var fileSettingRegex = regexp.MustCompile(`^(CertFile|KeyFile|CaCertFile|TokenFile|HMACSecretFile|Kubeconfig|KubeConfig)$`)
+
+// validateConfig checks the settings which are silently ignored, or fixed, when falcosidekick starts: the unknown
+// keys, the priorities, the endpoints of the outputs, the templates, the names of the labels and the paths of the
+// files. The errors of reading the files are reported by getConfig. It must be called before the priorities are
+// normalized by checkOutputsConfig.
+func validateConfig(files []string, c *types.Configuration) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		// the files are read alone, the defaults would hide their unknown keys
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			continue
+		}
+		if err := v.UnmarshalExact(new(types.Configuration)); err != nil {
+			for _, i := range strings.Split(err.Error(), "\n") {
+				if i = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(i), "*")); i != "" && !strings.HasSuffix(i, "error(s) decoding:") {
+					add("%v - %v", file, i)
+				}
+			}
+		}
+	}
+
+	walkSettings(reflect.ValueOf(c).Elem(), "", func(key, name string, value reflect.Value) {
+		switch {
+		case name == "MinimumPriority" || name == "DropEventDefaultPriority":
+			if p := value.String(); p != "" && !isValidPriority(p) {
+				add("%v - Unknown priority '%v'", key, p)
+			}
+		case fileSettingRegex.MatchString(name):
+			if value.String() == "" || (strings.HasPrefix(key, "tlsserver.") && !c.TLSServer.Deploy) {
+				return
+			}
+			if _, err := os.Stat(value.String()); err != nil {
+				add("%v - %v", key, err)
+			}
+		}
+	})
+
+	for _, i := range endpointSettings {
+		value := sectionValue(c, i).String()
+		if value == "" {
+			continue
+		}
+		if u, err := url.ParseRequestURI(value); err == nil && endpointRegex.MatchString(value) && u.Host != "" {
+			continue
+		}
+		// the webhook urls embed their tokens
+		if secretFieldRegex.MatchString(i) {
+			add("%v - Invalid url, it must be http(s)://host[:port]/path", strings.ToLower(i))
+		} else {
+			add("%v - Invalid url '%v', it must be http(s)://host[:port]/path", strings.ToLower(i), value)
+		}
+	}
+
+	for _, i := range createdFileSettings {
+		value := sectionValue(c, i).String()
+		if value == "" {
+			continue
+		}
+		if info, err := os.Stat(filepath.Dir(value)); err != nil || !info.IsDir() {
+			add("%v - The directory of '%v' doesn't exist", strings.ToLower(i), value)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Templatedfields) {
+		if _, err := template.New("").Parse(c.Templatedfields[name]); err != nil {
+			add("templatedfields - Invalid template for '%v': %v", name, err)
+		}
+	}
+
+	if c.Prometheus.ExtraLabels != "" {
+		for _, i := range strings.Split(strings.ReplaceAll(c.Prometheus.ExtraLabels, " ", ""), ",") {
+			if !promLabelNameRegex.MatchString(strings.ReplaceAll(i, ".", "_")) {
+				add("prometheus.extralabels - Invalid label name '%v'", i)
+			}
+		}
+	}
+	for key, list := range map[string]string{"prometheus.allowedlabelvalues": c.Prometheus.AllowedLabelValues, "prometheus.deniedlabelvalues": c.Prometheus.DeniedLabelValues} {
+		if strings.TrimSpace(list) == "" {
+			continue
+		}
+		for _, i := range strings.Split(list, ",") {
+			if label, _, found := strings.Cut(strings.TrimSpace(i), ":"); !found || strings.TrimSpace(label) == "" {
+				add("%v - Invalid label value '%v', it should be label:value", key, i)
+			}
+		}
+	}
+	if c.Loki.ExtraLabels != "" {
+		for _, i := range strings.Split(strings.ReplaceAll(c.Loki.ExtraLabels, " ", ""), ",") {
+			if !promLabelNameRegex.MatchString(strings.ReplaceAll(i, ".", "_")) {
+				add("loki.extralabels - Invalid label name '%v'", i)
+			}
+		}
+	}
+	for key, labels := range map[string]map[string]string{"alertmanager.extralabels": c.Alertmanager.ExtraLabels, "alertmanager.extraannotations": c.Alertmanager.ExtraAnnotations} {
+		for _, name := range sortedKeys(labels) {
+			if !promLabelNameRegex.MatchString(name) {
+				add("%v - Invalid name '%v'", key, name)
+			}
+		}
+	}
+	if c.Alertmanager.DropEventThresholds != "" {
+		for _, i := range strings.Split(strings.ReplaceAll(c.Alertmanager.DropEventThresholds, " ", ""), ",") {
+			value, priority, found := strings.Cut(i, ":")
+			if _, err := strconv.ParseInt(value, 10, 64); !found || err != nil || !isValidPriority(priority) {
+				add("alertmanager.dropeventthresholds - Invalid threshold '%v', it should be value:priority", i)
+			}
+		}
+	}
+	for _, i := range strings.Split(c.OTLPMetrics.ResourceAttributes, ",") {
+		if key, _, found := strings.Cut(i, "="); strings.TrimSpace(i) != "" && (!found || strings.TrimSpace(key) == "") {
+			add("otlpmetrics.resourceattributes - Invalid attribute '%v', it should be key=value", i)
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// walkSettings calls f for each string setting of v, with its key in the configuration and its field name, the
+// structs in the lists, like the clients of the authentication, are walked too.
+func walkSettings(v reflect.Value, prefix string, f func(key, name string, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field, sf := v.Field(i), v.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := prefix + strings.ToLower(sf.Name)
+		switch field.Kind() {
+		case reflect.Struct:
+			walkSettings(field, key+".", f)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.Struct {
+				continue
+			}
+			for j := 0; j < field.Len(); j++ {
+				walkSettings(field.Index(j), fmt.Sprintf("%v[%v].", key, j), f)
+			}
+		case reflect.String:
+			f(key, sf.Name, field)
+		}
+	}
+}
+
+// isValidPriority tells whether p is the name of a falco priority, checkPriority also accepts the names containing one
+func isValidPriority(p string) bool {
+	return checkPriority(p) != "" && types.Priority(p) != types.Default
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for i := range m {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportProblems writes the problems of the configuration, and the settings set by several files, and returns the
+// exit status of the validate command, the overrides aren't problems
+func reportProblems(w io.Writer, problems, overrides []string) int {
+	if len(overrides) != 0 {
+		fmt.Fprintf(w, "The configuration has %v overridden setting(s):\n", len(overrides))
+		for _, i := range overrides {
+			fmt.Fprintf(w, "  - %v\n", i)
+		}
+	}
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "The configuration is valid")
+		return 0
+	}
+	fmt.Fprintf(w, "The configuration has %v problem(s):\n", len(problems))
+	for _, i := range problems {
+		fmt.Fprintf(w, "  - %v\n", i)
+	}
+	return 1
+}
+
+// dumpConfig writes the effective configuration, merged from the files, the env vars and the defaults, as YAML with
+// the secrets redacted. If sources is set, the source of each setting is added as a comment.
+func dumpConfig(w io.Writer, c *types.Configuration, sources func(key string) string) error {
+	var node yaml.Node
+	if err := node.Encode(redactedConfig(reflect.ValueOf(c).Elem())); err != nil {
+		return err
+	}
+	if sources != nil {
+		commentSources(&node, "", sources)
+	}
+	e := yaml.NewEncoder(w)
+	e.SetIndent(2)
+	if err := e.Encode(&node); err != nil {
+		return err
+	}
+	return e.Close()
+}
+
+// commentSources adds the sources of the settings of the mapping node as comments
+func commentSources(node *yaml.Node, prefix string, sources func(key string) string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := prefix+node.Content[i].Value, node.Content[i+1]
+		if value.Kind == yaml.MappingNode && len(value.Content) != 0 {
+			commentSources(value, key+".", sources)
+			continue
+		}
+		if source := sources(key); source != "" {
+			node.Content[i].LineComment = source
+		}
+	}
+}